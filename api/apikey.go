@@ -0,0 +1,102 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+type APIKeyHandler struct {
+	controller *controllers.APIKeyController
+}
+
+func NewAPIKeyHandler(controller *controllers.APIKeyController) *APIKeyHandler {
+	return &APIKeyHandler{
+		controller: controller,
+	}
+}
+
+// Used as jwt middleware filter, so requests with API key skip JWT check
+func HasAPIKey(ctx *fiber.Ctx) bool {
+	return len(ctx.Get(APIKeyHeader)) != 0
+}
+
+func (self *APIKeyHandler) Authenticate(ctx *fiber.Ctx) error {
+	if !HasAPIKey(ctx) {
+		return ctx.Next()
+	}
+	apiKey, err := self.controller.Authenticate(ctx.Context(), ctx.Get(APIKeyHeader))
+	if err != nil {
+		return err
+	}
+	ctx.Locals(controllers.APIKeyContextKey, apiKey)
+	return ctx.Next()
+}
+
+// Requests authenticated by JWT pass through, API keys need the permission
+func RequirePermission(permission types.APIKeyPermission) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		apiKey := controllers.GetAPIKeyFromContext(ctx.Context())
+		if apiKey != nil && !apiKey.HasPermission(permission) {
+			return controllers.ErrPermissionDenied
+		}
+		return ctx.Next()
+	}
+}
+
+// For endpoints managing user's own account, which API keys can't access
+func DenyAPIKey(ctx *fiber.Ctx) error {
+	if controllers.GetAPIKeyFromContext(ctx.Context()) != nil {
+		return controllers.ErrPermissionDenied
+	}
+	return ctx.Next()
+}
+
+func (self *APIKeyHandler) HandleListAPIKeys(ctx *fiber.Ctx) error {
+	apiKeys, err := self.controller.Get(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(apiKeys)
+}
+
+func (self *APIKeyHandler) HandleCreateAPIKey(ctx *fiber.Ctx) error {
+	var params types.CreateAPIKeyParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := types.NewAPIKeyFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdAPIKey, err := self.controller.Create(ctx.Context(), apiKey)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdAPIKey)
+}
+
+func (self *APIKeyHandler) HandleRevokeAPIKey(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	apiKey, err := self.controller.RevokeByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if apiKey == nil {
+		return controllers.NotFoundError{Entity: "API key"}
+	}
+
+	return ctx.JSON(apiKey)
+}
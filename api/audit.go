@@ -0,0 +1,32 @@
+package api
+
+import (
+	"hotel/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AuditHandler struct {
+	controller *controllers.AuditController
+}
+
+func NewAuditHandler(controller *controllers.AuditController) *AuditHandler {
+	return &AuditHandler{
+		controller: controller,
+	}
+}
+
+func (self *AuditHandler) HandleListAudit(ctx *fiber.Ctx) error {
+	var query controllers.AuditGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	records, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(records)
+}
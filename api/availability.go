@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hotel/controllers"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	availabilityStreamSuffix = "/availability/stream"
+	// Sent as SSE comment, so proxies don't close idle connection
+	heartbeatInterval = 15 * time.Second
+	// Delay before EventSource reconnects
+	reconnectDelay = 3 * time.Second
+)
+
+type AvailabilityHandler struct {
+	controller *controllers.AvailabilityController
+}
+
+func NewAvailabilityHandler(
+	controller *controllers.AvailabilityController,
+) *AvailabilityHandler {
+	return &AvailabilityHandler{
+		controller: controller,
+	}
+}
+
+// Moves access_token query param of availability streams into
+// Authorization header. Must be registered before JWT middleware
+func StreamTokenFromQuery(ctx *fiber.Ctx) error {
+	token := ctx.Query("access_token")
+	if len(token) != 0 && len(ctx.Get(fiber.HeaderAuthorization)) == 0 &&
+		strings.HasSuffix(ctx.Path(), availabilityStreamSuffix) {
+		ctx.Request().Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+	return ctx.Next()
+}
+
+func (self *AvailabilityHandler) parseLastEventID(ctx *fiber.Ctx) (string, error) {
+	var query controllers.AvailabilityStreamQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return "", err
+	}
+	lastEventID := ctx.Get("Last-Event-ID")
+	if len(lastEventID) == 0 {
+		lastEventID = query.LastEventID
+	}
+	return lastEventID, nil
+}
+
+func (self *AvailabilityHandler) HandleStreamRoom(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	lastEventID, err := self.parseLastEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := self.controller.WatchRoom(ctx.Context(), id, lastEventID)
+	if err != nil {
+		return err
+	}
+	return sendAvailabilityStream(ctx, stream)
+}
+
+func (self *AvailabilityHandler) HandleStreamHotel(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	lastEventID, err := self.parseLastEventID(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := self.controller.WatchHotel(ctx.Context(), id, lastEventID)
+	if err != nil {
+		return err
+	}
+	return sendAvailabilityStream(ctx, stream)
+}
+
+func (self *AvailabilityHandler) HandleSearchHotel(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	var query controllers.AvailabilitySearchQueryParams
+	err = ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	availability, err := self.controller.Search(ctx.Context(), id, &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(availability)
+}
+
+// Writes changes as server-sent events until client disconnects
+func sendAvailabilityStream(
+	ctx *fiber.Ctx, stream *controllers.AvailabilityStream,
+) error {
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	// Disables response buffering of nginx
+	ctx.Set("X-Accel-Buffering", "no")
+
+	// Writer runs after handler returns, so it isn't bound to request
+	ctx.Context().SetBodyStreamWriter(func(writer *bufio.Writer) {
+		streamCtx := context.Background()
+		defer stream.Close(streamCtx)
+
+		fmt.Fprintf(writer, "retry: %d\n\n", reconnectDelay.Milliseconds())
+		if writer.Flush() != nil {
+			return
+		}
+		lastWrite := time.Now()
+		for {
+			changes, eventID, err := stream.Next(streamCtx)
+			if err != nil {
+				log.Printf("Availability stream failed: %s\n", err.Error())
+				return
+			}
+			for _, change := range changes {
+				data, err := json.Marshal(change)
+				if err != nil {
+					log.Printf("Failed to encode availability change: %s\n", err.Error())
+					return
+				}
+				fmt.Fprintf(writer, "id: %s\nevent: availability\ndata: %s\n\n", eventID, data)
+			}
+			if len(changes) == 0 && time.Since(lastWrite) < heartbeatInterval {
+				continue
+			}
+			if len(changes) == 0 {
+				fmt.Fprint(writer, ": heartbeat\n\n")
+			}
+			if writer.Flush() != nil {
+				return
+			}
+			lastWrite = time.Now()
+		}
+	})
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	"hotel/types"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type BookingHandler struct {
@@ -19,34 +18,69 @@ func NewBookingHandler(controller *controllers.BookingController) *BookingHandle
 }
 
 func (self *BookingHandler) HandleListBookings(ctx *fiber.Ctx) error {
-	rooms, err := self.controller.Get(ctx.Context(), nil)
+	var query controllers.BookingGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
+	}
+	bookings, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
 	}
 
-	return ctx.JSON(rooms)
+	return ctx.JSON(bookings)
 }
 
 func (self *BookingHandler) HandleGetBooking(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	var query controllers.DisplayCurrencyQueryParams
+	err = ParseQuery(ctx, self.controller.Store, &query)
 	if err != nil {
 		return err
 	}
 
 	room, err := self.controller.GetUnfoldedByID(ctx.Context(), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 	if room == nil {
-		return fiber.NewError(fiber.StatusNotFound, EntityNotFoundMessage)
+		return controllers.NotFoundError{Entity: "Booking"}
+	}
+	err = self.controller.WithDisplayTotalCost(room, query.Currency)
+	if err != nil {
+		return err
 	}
 
-	return ctx.JSON(room)
+	return SendWithETag(ctx, room.Version, room)
+}
+
+func (self *BookingHandler) HandleQuoteBooking(ctx *fiber.Ctx) error {
+	var params types.CreateBookingParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	booking, err := types.NewBookingFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	quote, err := self.controller.Quote(ctx.Context(), booking)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(quote)
 }
 
 func (self *BookingHandler) HandleCreateBooking(ctx *fiber.Ctx) error {
 	var params types.CreateBookingParams
-	err := ctx.BodyParser(&params)
+	err := ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -58,10 +92,6 @@ func (self *BookingHandler) HandleCreateBooking(ctx *fiber.Ctx) error {
 
 	createdBooking, err := self.controller.Create(ctx.Context(), room)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 
@@ -69,13 +99,17 @@ func (self *BookingHandler) HandleCreateBooking(ctx *fiber.Ctx) error {
 }
 
 func (self *BookingHandler) HandleUpdateBooking(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
 	if err != nil {
 		return err
 	}
 
 	var params types.UpdateBookingParams
-	err = ctx.BodyParser(&params)
+	err = ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -84,31 +118,125 @@ func (self *BookingHandler) HandleUpdateBooking(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	data.Version = version
 
 	updatedBooking, err := self.controller.UpdateByID(ctx.Context(), id, data)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 	if updatedBooking == nil {
-		return fiber.NewError(fiber.StatusNotFound, EntityNotFoundMessage)
+		return controllers.NotFoundError{Entity: "Booking"}
+	}
+
+	return SendWithETag(ctx, updatedBooking.Version, updatedBooking)
+}
+
+func (self *BookingHandler) HandlePatchBooking(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := self.controller.GetUnfoldedByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return controllers.NotFoundError{Entity: "Booking"}
+	}
+
+	var params types.UpdateBookingParams
+	err = ParsePatch(ctx, self.controller.Store, current, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewBookingFromUpdateParams(params)
+	if err != nil {
+		return err
 	}
+	data.Version = version
 
-	return ctx.JSON(updatedBooking)
+	updatedBooking, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedBooking.Version, updatedBooking)
 }
 
 func (self *BookingHandler) HandleDeleteBooking(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = self.controller.DeleteByID(ctx.Context(), id)
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
 	if err != nil {
 		return err
 	}
 	return ctx.Status(fiber.StatusNoContent).Send(nil)
 }
+
+func (self *BookingHandler) HandleRestoreBooking(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	booking, err := self.controller.RestoreByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, booking.Version, booking)
+}
+
+func (self *BookingHandler) HandlePurgeBooking(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.PurgeByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *BookingHandler) HandleCheckInBooking(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	booking, err := self.controller.CheckInByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, booking.Version, booking)
+}
+
+func (self *BookingHandler) HandleCheckOutBooking(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	booking, err := self.controller.CheckOutByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, booking.Version, booking)
+}
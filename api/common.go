@@ -1,5 +0,0 @@
-package api
-
-const (
-	EntityNotFoundMessage = "Entity not found"
-)
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"hotel/controllers"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ETag of entity is its version
+func ETag(version int64) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// Returns entity version from If-Match header. Header is mandatory
+// for writes, so clients can't overwrite changes they haven't seen.
+// "*" matches any version and returns zero
+func ParseIfMatch(ctx *fiber.Ctx) (int64, error) {
+	header := strings.TrimSpace(ctx.Get(fiber.HeaderIfMatch))
+	if len(header) == 0 {
+		return 0, controllers.ErrPreconditionRequired
+	}
+	if header == "*" {
+		return 0, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), "\"")
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fiber.NewError(
+			fiber.StatusBadRequest, fmt.Sprintf("Invalid If-Match header %s", header),
+		)
+	}
+	return version, nil
+}
+
+// Sends entity with its ETag. Replies 304 to GET,
+// if client already has this version
+func SendWithETag(ctx *fiber.Ctx, version int64, body interface{}) error {
+	etag := ETag(version)
+	ctx.Set(fiber.HeaderETag, etag)
+	if ctx.Method() == fiber.MethodGet && ctx.Get(fiber.HeaderIfNoneMatch) == etag {
+		return ctx.SendStatus(fiber.StatusNotModified)
+	}
+	return ctx.JSON(body)
+}
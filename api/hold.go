@@ -0,0 +1,82 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type HoldHandler struct {
+	controller *controllers.HoldController
+}
+
+func NewHoldHandler(controller *controllers.HoldController) *HoldHandler {
+	return &HoldHandler{
+		controller: controller,
+	}
+}
+
+func (self *HoldHandler) HandleGetHold(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	hold, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return controllers.NotFoundError{Entity: "Hold"}
+	}
+
+	return ctx.JSON(hold)
+}
+
+func (self *HoldHandler) HandleCreateHold(ctx *fiber.Ctx) error {
+	var params types.CreateHoldParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	hold, err := types.NewHoldFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdHold, err := self.controller.Create(ctx.Context(), hold, params.Minutes)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdHold)
+}
+
+func (self *HoldHandler) HandleReleaseHold(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.ReleaseByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *HoldHandler) HandleConvertHold(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	booking, err := self.controller.ConvertByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(booking)
+}
@@ -5,7 +5,6 @@ import (
 	"hotel/types"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type HotelHandler struct {
@@ -19,34 +18,39 @@ func NewHotelHandler(controller *controllers.HotelController) *HotelHandler {
 }
 
 func (self *HotelHandler) HandleListHotels(ctx *fiber.Ctx) error {
-	hotels, err := self.controller.Get(ctx.Context())
+	var query controllers.HotelGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
+	}
+	hotels, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
 	}
 
 	return ctx.JSON(hotels)
 }
 
 func (self *HotelHandler) HandleGetHotel(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
 	if err != nil {
 		return err
 	}
 
 	hotel, err := self.controller.GetWithRoomsByID(ctx.Context(), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 	if hotel == nil {
-		return fiber.NewError(fiber.StatusNotFound, EntityNotFoundMessage)
+		return controllers.NotFoundError{Entity: "Hotel"}
 	}
 
-	return ctx.JSON(hotel)
+	return SendWithETag(ctx, hotel.Version, hotel)
 }
 
 func (self *HotelHandler) HandleCreateHotel(ctx *fiber.Ctx) error {
 	var params types.CreateHotelParams
-	err := ctx.BodyParser(&params)
+	err := ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -58,10 +62,6 @@ func (self *HotelHandler) HandleCreateHotel(ctx *fiber.Ctx) error {
 
 	createdHotel, err := self.controller.Create(ctx.Context(), hotel)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 
@@ -69,13 +69,17 @@ func (self *HotelHandler) HandleCreateHotel(ctx *fiber.Ctx) error {
 }
 
 func (self *HotelHandler) HandleUpdateHotel(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
 	if err != nil {
 		return err
 	}
 
 	var params types.UpdateHotelParams
-	err = ctx.BodyParser(&params)
+	err = ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -84,31 +88,123 @@ func (self *HotelHandler) HandleUpdateHotel(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	data.Version = version
 
 	updatedHotel, err := self.controller.UpdateByID(ctx.Context(), id, data)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 	if updatedHotel == nil {
-		return fiber.NewError(fiber.StatusNotFound, EntityNotFoundMessage)
+		return controllers.NotFoundError{Entity: "Hotel"}
 	}
 
-	return ctx.JSON(updatedHotel)
+	return SendWithETag(ctx, updatedHotel.Version, updatedHotel)
+}
+
+func (self *HotelHandler) HandlePatchHotel(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := self.controller.GetWithRoomsByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return controllers.NotFoundError{Entity: "Hotel"}
+	}
+
+	var params types.UpdateHotelParams
+	err = ParsePatch(ctx, self.controller.Store, current, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewHotelFromUpdateParams(params)
+	if err != nil {
+		return err
+	}
+	data.Version = version
+
+	updatedHotel, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedHotel.Version, updatedHotel)
 }
 
 func (self *HotelHandler) HandleDeleteHotel(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *HotelHandler) HandleRestoreHotel(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	hotel, err := self.controller.RestoreByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, hotel.Version, hotel)
+}
+
+func (self *HotelHandler) HandlePurgeHotel(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
 	if err != nil {
 		return err
 	}
 
-	err = self.controller.DeleteByID(ctx.Context(), id)
+	err = self.controller.PurgeByID(ctx.Context(), id)
 	if err != nil {
 		return err
 	}
 	return ctx.Status(fiber.StatusNoContent).Send(nil)
 }
+
+func (self *HotelHandler) HandleSetTaxRules(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var params types.SetTaxRulesParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	updatedHotel, err := self.controller.SetTaxRulesByID(
+		ctx.Context(), id, version, types.NewTaxRulesFromParams(params),
+	)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedHotel.Version, updatedHotel)
+}
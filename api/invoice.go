@@ -0,0 +1,206 @@
+package api
+
+import (
+	"fmt"
+	"hotel/controllers"
+	"hotel/pdf"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type InvoiceHandler struct {
+	controller *controllers.InvoiceController
+}
+
+func NewInvoiceHandler(controller *controllers.InvoiceController) *InvoiceHandler {
+	return &InvoiceHandler{
+		controller: controller,
+	}
+}
+
+func (self *InvoiceHandler) getInvoice(ctx *fiber.Ctx) (*types.Invoice, error) {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	invoice, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil {
+		return nil, controllers.NotFoundError{Entity: "Invoice"}
+	}
+	return invoice, nil
+}
+
+func (self *InvoiceHandler) HandleGetInvoice(ctx *fiber.Ctx) error {
+	invoice, err := self.getInvoice(ctx)
+	if err != nil {
+		return err
+	}
+	return ctx.JSON(invoice)
+}
+
+func (self *InvoiceHandler) HandleGetInvoicePDF(ctx *fiber.Ctx) error {
+	invoice, err := self.getInvoice(ctx)
+	if err != nil {
+		return err
+	}
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	ctx.Set(
+		fiber.HeaderContentDisposition,
+		fmt.Sprintf("inline; filename=\"%s.pdf\"", invoice.Number),
+	)
+	return ctx.Send(InvoicePDF(invoice))
+}
+
+func (self *InvoiceHandler) HandleListInvoices(ctx *fiber.Ctx) error {
+	var query controllers.InvoiceGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	invoices, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(invoices)
+}
+
+func (self *InvoiceHandler) HandleIssueInvoice(ctx *fiber.Ctx) error {
+	bookingID, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	var params types.IssueInvoiceParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	invoice, err := self.controller.IssueForBooking(
+		ctx.Context(), bookingID, types.NewInvoiceLinesFromParams(params.Lines),
+	)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(invoice)
+}
+
+func (self *InvoiceHandler) HandleIssueCreditNote(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	var params types.IssueCreditNoteParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	creditNote, err := self.controller.IssueCreditNote(
+		ctx.Context(), id, types.NewInvoiceLinesFromParams(params.Lines),
+	)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(creditNote)
+}
+
+const (
+	invoiceMargin    = 50.0
+	invoiceLineStep  = 16.0
+	invoiceFontSize  = 10.0
+	invoicePageLimit = pdf.PageHeight - 80
+)
+
+var invoiceTitles = map[types.InvoiceKind]string{
+	types.InvoiceInvoiceKind:    "Invoice",
+	types.CreditNoteInvoiceKind: "Credit note",
+}
+
+// Renders invoice as single column A4 document, lines continue
+// on the next page when they don't fit
+func InvoicePDF(invoice *types.Invoice) []byte {
+	document := pdf.New()
+	right := pdf.PageWidth - invoiceMargin
+	rightAligned := func(x float64, y float64, bold bool, str string) {
+		document.Text(x-pdf.TextWidth(str, invoiceFontSize), y, invoiceFontSize, bold, str)
+	}
+
+	y := 70.0
+	document.Text(invoiceMargin, y, 20, true, invoiceTitles[invoice.Kind])
+	rightAligned(right, y, true, invoice.Number)
+	y += invoiceLineStep * 1.5
+	rightAligned(right, y, false, "Issued "+invoice.IssuedAt.Format("2006-01-02"))
+	y += invoiceLineStep * 2
+
+	parties := []struct {
+		x     float64
+		title string
+		party types.InvoiceParty
+	}{
+		{invoiceMargin, "From", invoice.Seller},
+		{pdf.PageWidth / 2, "Billed to", invoice.BilledTo},
+	}
+	partiesY := y
+	for _, column := range parties {
+		y = partiesY
+		document.Text(column.x, y, invoiceFontSize, true, column.title)
+		for _, str := range []string{
+			column.party.Name, column.party.Address, column.party.Email,
+		} {
+			if len(str) == 0 {
+				continue
+			}
+			y += invoiceLineStep
+			document.Text(column.x, y, invoiceFontSize, false, str)
+		}
+	}
+	y = partiesY + invoiceLineStep*5
+
+	header := func() {
+		document.Text(invoiceMargin, y, invoiceFontSize, true, "Description")
+		rightAligned(right-170, y, true, "Qty")
+		rightAligned(right-80, y, true, "Unit price")
+		rightAligned(right, y, true, "Amount, "+string(invoice.Currency))
+		y += invoiceLineStep * 1.5
+	}
+	header()
+	for _, line := range invoice.Lines {
+		if y > invoicePageLimit {
+			document.AddPage()
+			y = 70
+			header()
+		}
+		document.Text(invoiceMargin, y, invoiceFontSize, false, line.Description)
+		rightAligned(right-170, y, false, fmt.Sprint(line.Quantity))
+		rightAligned(right-80, y, false, line.UnitPrice.Amount())
+		rightAligned(right, y, false, line.Amount.Amount())
+		y += invoiceLineStep
+	}
+
+	y += invoiceLineStep
+	for _, total := range []struct {
+		title  string
+		amount types.Money
+		bold   bool
+	}{
+		{"Subtotal", invoice.Subtotal, false},
+		{"Taxes", invoice.TaxTotal, false},
+		{"Total", invoice.Total, true},
+	} {
+		rightAligned(right-80, y, total.bold, total.title)
+		rightAligned(right, y, total.bold, total.amount.Amount())
+		y += invoiceLineStep
+	}
+	return document.Bytes()
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"hotel/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LoyaltyHandler struct {
+	controller *controllers.LoyaltyController
+}
+
+func NewLoyaltyHandler(controller *controllers.LoyaltyController) *LoyaltyHandler {
+	return &LoyaltyHandler{
+		controller: controller,
+	}
+}
+
+func (self *LoyaltyHandler) HandleGetLoyaltyAccount(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	account, err := self.controller.GetAccount(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(account)
+}
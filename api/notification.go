@@ -0,0 +1,34 @@
+package api
+
+import (
+	"hotel/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationHandler struct {
+	controller *controllers.NotificationController
+}
+
+func NewNotificationHandler(
+	controller *controllers.NotificationController,
+) *NotificationHandler {
+	return &NotificationHandler{
+		controller: controller,
+	}
+}
+
+func (self *NotificationHandler) HandleListNotifications(ctx *fiber.Ctx) error {
+	var query controllers.NotificationGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	notifications, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(notifications)
+}
@@ -0,0 +1,397 @@
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"hotel/types"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	APIPrefix = "/api/v1"
+
+	openAPIVersion = "3.0.3"
+	apiVersion     = "1.0.0"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// Describes single endpoint for OpenAPI spec.
+// Request, Query and Response are zero values of the types used by handler
+type RouteDoc struct {
+	Method   string
+	Path     string
+	Tag      string
+	Summary  string
+	Public   bool
+	Request  interface{}
+	Query    interface{}
+	Response interface{}
+	// Defaults to 200 or 204 when Response is nil
+	Status int
+	// Defaults to application/json
+	ContentType string
+	// Write requires entity version in If-Match header
+	IfMatch bool
+}
+
+type OpenAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       map[string]string                       `json:"info"`
+	Servers    []map[string]string                     `json:"servers"`
+	Security   []map[string][]string                   `json:"security"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+type OpenAPIComponents struct {
+	Schemas         map[string]*OpenAPISchema    `json:"schemas"`
+	SecuritySchemes map[string]map[string]string `json:"securitySchemes"`
+}
+
+type OpenAPIOperation struct {
+	Tags        []string                    `json:"tags,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	OperationID string                      `json:"operationId"`
+	Security    *[]map[string][]string      `json:"security,omitempty"`
+	Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+type OpenAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required"`
+	Schema   *OpenAPISchema `json:"schema"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                                 `json:"required"`
+	Content  map[string]map[string]*OpenAPISchema `json:"content"`
+}
+
+type OpenAPIResponse struct {
+	Description string                               `json:"description"`
+	Content     map[string]map[string]*OpenAPISchema `json:"content,omitempty"`
+}
+
+type OpenAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	MinLength            *int                      `json:"minLength,omitempty"`
+	MaxLength            *int                      `json:"maxLength,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	Items                *OpenAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// Values of enum-like types, which can't be discovered by reflection
+var schemaEnums = map[reflect.Type][]interface{}{}
+
+func RegisterSchemaEnum[T any](values ...T) {
+	enum := []interface{}{}
+	for _, value := range values {
+		enum = append(enum, value)
+	}
+	schemaEnums[reflect.TypeOf(*new(T))] = enum
+}
+
+var (
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	dateType     = reflect.TypeOf(civil.Date{})
+	timeType     = reflect.TypeOf(time.Time{})
+	moneyType    = reflect.TypeOf(types.Money{})
+)
+
+type schemaBuilder struct {
+	schemas map[string]*OpenAPISchema
+}
+
+func (self *schemaBuilder) schemaFor(t reflect.Type) *OpenAPISchema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var schema *OpenAPISchema
+	switch {
+	case t == objectIDType:
+		schema = &OpenAPISchema{Type: "string", Pattern: "^[0-9a-f]{24}$"}
+	case t == dateType:
+		schema = &OpenAPISchema{Type: "string", Format: "date"}
+	case t == timeType:
+		schema = &OpenAPISchema{Type: "string", Format: "date-time"}
+	case t == moneyType:
+		return self.moneyRef(nullable)
+	case t.Kind() == reflect.Struct:
+		return self.refFor(t, nullable)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = &OpenAPISchema{Type: "array", Items: self.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		schema = &OpenAPISchema{
+			Type: "object", AdditionalProperties: self.schemaFor(t.Elem()),
+		}
+	case t.Kind() == reflect.Bool:
+		schema = &OpenAPISchema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = &OpenAPISchema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = &OpenAPISchema{Type: "number"}
+	case t.Kind() == reflect.String:
+		schema = &OpenAPISchema{Type: "string"}
+	default:
+		schema = &OpenAPISchema{}
+	}
+	schema.Enum = schemaEnums[t]
+	schema.Nullable = nullable
+	return schema
+}
+
+// Money is encoded with amount as decimal string, see types.Money
+func (self *schemaBuilder) moneyRef(nullable bool) *OpenAPISchema {
+	if _, ok := self.schemas["Money"]; !ok {
+		self.schemas["Money"] = &OpenAPISchema{
+			Type: "object",
+			Properties: map[string]*OpenAPISchema{
+				"amount":   {Type: "string", Pattern: `^-?\d+(\.\d+)?$`},
+				"currency": self.schemaFor(reflect.TypeOf(types.Currency(""))),
+			},
+			Required: []string{"amount", "currency"},
+		}
+	}
+	return &OpenAPISchema{Ref: "#/components/schemas/Money", Nullable: nullable}
+}
+
+// Named structs go to components, so they are described only once
+func (self *schemaBuilder) refFor(t reflect.Type, nullable bool) *OpenAPISchema {
+	name := t.Name()
+	if len(name) == 0 {
+		return self.structSchema(t)
+	}
+	if _, ok := self.schemas[name]; !ok {
+		// Placeholder guards against infinite recursion
+		self.schemas[name] = &OpenAPISchema{}
+		*self.schemas[name] = *self.structSchema(t)
+	}
+	return &OpenAPISchema{Ref: "#/components/schemas/" + name, Nullable: nullable}
+}
+
+func (self *schemaBuilder) structSchema(t reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: map[string]*OpenAPISchema{}}
+	self.addProperties(schema, t)
+	return schema
+}
+
+// Mirrors encoding/json rules: embedded structs without tag are flattened
+func (self *schemaBuilder) addProperties(schema *OpenAPISchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && len(name) == 0 && fieldType.Kind() == reflect.Struct {
+			self.addProperties(schema, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		property := self.schemaFor(field.Type)
+		if applyValidateTag(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// Describes validation rules of params in schema, reports if field is required
+func applyValidateTag(schema *OpenAPISchema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		limit, _ := strconv.Atoi(arg)
+		switch {
+		case name == "required":
+			required = true
+		case name == "email":
+			schema.Format = "email"
+		case name == "url":
+			schema.Format = "uri"
+		case name == "min" && schema.Type == "string":
+			schema.MinLength = &limit
+		case name == "max" && schema.Type == "string":
+			schema.MaxLength = &limit
+		}
+	}
+	return required
+}
+
+func (self *schemaBuilder) queryParameters(query interface{}) []*OpenAPIParameter {
+	params := []*OpenAPIParameter{}
+	schema := self.structSchema(reflect.TypeOf(query))
+	for name, property := range schema.Properties {
+		params = append(params, &OpenAPIParameter{Name: name, In: "query", Schema: property})
+	}
+	return params
+}
+
+func content(contentType string, schema *OpenAPISchema) map[string]map[string]*OpenAPISchema {
+	if len(contentType) == 0 {
+		contentType = fiber.MIMEApplicationJSON
+	}
+	return map[string]map[string]*OpenAPISchema{
+		contentType: {"schema": schema},
+	}
+}
+
+// Converts fiber path to OpenAPI one, "/user/:id" becomes "/user/{id}"
+func OpenAPIPath(path string) (string, []string) {
+	parts := strings.Split(path, "/")
+	params := []string{}
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			params = append(params, part[1:])
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/"), params
+}
+
+func BuildOpenAPIDocument(routes []RouteDoc) *OpenAPIDocument {
+	builder := &schemaBuilder{schemas: map[string]*OpenAPISchema{}}
+	errorSchema := builder.schemaFor(reflect.TypeOf(ErrorResponse{}))
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: map[string]string{
+			"title":   "gohotel API",
+			"version": apiVersion,
+		},
+		Servers:  []map[string]string{{"url": APIPrefix}},
+		Security: []map[string][]string{{"bearerAuth": {}}, {"apiKeyAuth": {}}},
+		Paths:    map[string]map[string]*OpenAPIOperation{},
+		Components: OpenAPIComponents{
+			Schemas: builder.schemas,
+			SecuritySchemes: map[string]map[string]string{
+				"bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKeyAuth": {"type": "apiKey", "in": "header", "name": APIKeyHeader},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		path, pathParams := OpenAPIPath(route.Path)
+		method := strings.ToLower(route.Method)
+		op := &OpenAPIOperation{
+			Summary:     route.Summary,
+			OperationID: method + strings.NewReplacer("/", "_", "{", "", "}", "").Replace(path),
+			Responses: map[string]*OpenAPIResponse{
+				"default": {Description: "Error", Content: content("", errorSchema)},
+			},
+		}
+		if len(route.Tag) != 0 {
+			op.Tags = []string{route.Tag}
+		}
+		if route.Public {
+			op.Security = &[]map[string][]string{}
+		}
+		for _, param := range pathParams {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: param, In: "path", Required: true,
+				Schema: builder.schemaFor(objectIDType),
+			})
+		}
+		if route.IfMatch {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: fiber.HeaderIfMatch, In: "header", Required: true,
+				Schema: &OpenAPISchema{Type: "string"},
+			})
+		}
+		if route.Query != nil {
+			op.Parameters = append(op.Parameters, builder.queryParameters(route.Query)...)
+		}
+		if route.Request != nil {
+			requestType := ""
+			if method == "patch" {
+				requestType = MIMEMergePatchJSON
+			}
+			op.RequestBody = &OpenAPIRequestBody{
+				Required: true,
+				Content: content(
+					requestType, builder.schemaFor(reflect.TypeOf(route.Request)),
+				),
+			}
+		}
+		status := route.Status
+		if route.Response != nil {
+			if status == 0 {
+				status = fiber.StatusOK
+			}
+			op.Responses[fmt.Sprint(status)] = &OpenAPIResponse{
+				Description: http.StatusText(status),
+				Content: content(
+					route.ContentType, builder.schemaFor(reflect.TypeOf(route.Response)),
+				),
+			}
+		} else {
+			if status == 0 {
+				status = fiber.StatusNoContent
+			}
+			op.Responses[fmt.Sprint(status)] = &OpenAPIResponse{
+				Description: http.StatusText(status),
+			}
+		}
+
+		if _, ok := doc.Paths[path]; !ok {
+			doc.Paths[path] = map[string]*OpenAPIOperation{}
+		}
+		doc.Paths[path][method] = op
+	}
+	return doc
+}
+
+var (
+	openAPIDocument     *OpenAPIDocument
+	openAPIDocumentOnce sync.Once
+)
+
+func GetOpenAPIDocument() *OpenAPIDocument {
+	openAPIDocumentOnce.Do(func() {
+		openAPIDocument = BuildOpenAPIDocument(Routes)
+	})
+	return openAPIDocument
+}
+
+func HandleOpenAPISpec(ctx *fiber.Ctx) error {
+	return ctx.JSON(GetOpenAPIDocument())
+}
+
+func HandleDocs(ctx *fiber.Ctx) error {
+	ctx.Type("html")
+	return ctx.Send(docsPage)
+}
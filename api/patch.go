@@ -0,0 +1,76 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"hotel/controllers"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const MIMEMergePatchJSON = "application/merge-patch+json"
+
+// Applies JSON Merge Patch (RFC 7386) to target document.
+// null in patch removes the field, objects are merged recursively
+// and any other value replaces the field as a whole
+func MergePatch(target []byte, patch []byte) ([]byte, error) {
+	var targetValue interface{}
+	if len(bytes.TrimSpace(target)) != 0 {
+		err := json.Unmarshal(target, &targetValue)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var patchValue interface{}
+	err := json.Unmarshal(patch, &patchValue)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergeValue(targetValue, patchValue))
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = mergeValue(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
+
+// Applies request body as merge patch to current entity and parses
+// result into params. Params are validated after merge, so removing
+// required field fails the same way as omitting it in PUT
+func ParsePatch(
+	ctx *fiber.Ctx, store *controllers.Store, current interface{}, params interface{},
+) error {
+	contentType := strings.ToLower(ctx.Get(fiber.HeaderContentType))
+	if !strings.HasPrefix(contentType, MIMEMergePatchJSON) &&
+		!strings.HasPrefix(contentType, fiber.MIMEApplicationJSON) {
+		return fiber.ErrUnsupportedMediaType
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	merged, err := MergePatch(currentJSON, ctx.Body())
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid merge patch: "+err.Error())
+	}
+	err = json.Unmarshal(merged, params)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid merge patch: "+err.Error())
+	}
+	return store.ValidateParams(ctx.Context(), params)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type PromotionHandler struct {
+	controller *controllers.PromotionController
+}
+
+func NewPromotionHandler(controller *controllers.PromotionController) *PromotionHandler {
+	return &PromotionHandler{
+		controller: controller,
+	}
+}
+
+func (self *PromotionHandler) HandleListPromotions(ctx *fiber.Ctx) error {
+	var query controllers.PromotionGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	promotions, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(promotions)
+}
+
+func (self *PromotionHandler) HandleGetPromotion(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	promotion, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, promotion.Version, promotion)
+}
+
+func (self *PromotionHandler) HandleCreatePromotion(ctx *fiber.Ctx) error {
+	var params types.CreatePromotionParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	promotion, err := types.NewPromotionFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdPromotion, err := self.controller.Create(ctx.Context(), promotion)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdPromotion)
+}
+
+func (self *PromotionHandler) HandleUpdatePromotion(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var params types.UpdatePromotionParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewPromotionFromUpdateParams(params)
+	if err != nil {
+		return err
+	}
+	data.Version = version
+
+	updatedPromotion, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedPromotion.Version, updatedPromotion)
+}
+
+func (self *PromotionHandler) HandleDeletePromotion(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RatePlanHandler struct {
+	controller *controllers.RatePlanController
+}
+
+func NewRatePlanHandler(controller *controllers.RatePlanController) *RatePlanHandler {
+	return &RatePlanHandler{
+		controller: controller,
+	}
+}
+
+func (self *RatePlanHandler) HandleListRatePlans(ctx *fiber.Ctx) error {
+	var query controllers.RatePlanGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	ratePlans, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(ratePlans)
+}
+
+func (self *RatePlanHandler) HandleGetRatePlan(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	ratePlan, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, ratePlan.Version, ratePlan)
+}
+
+func (self *RatePlanHandler) HandleCreateRatePlan(ctx *fiber.Ctx) error {
+	var params types.CreateRatePlanParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	ratePlan, err := types.NewRatePlanFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdRatePlan, err := self.controller.Create(ctx.Context(), ratePlan)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdRatePlan)
+}
+
+func (self *RatePlanHandler) HandleUpdateRatePlan(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var params types.UpdateRatePlanParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewRatePlanFromUpdateParams(params)
+	if err != nil {
+		return err
+	}
+	data.Version = version
+
+	updatedRatePlan, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedRatePlan.Version, updatedRatePlan)
+}
+
+func (self *RatePlanHandler) HandleDeleteRatePlan(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
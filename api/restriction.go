@@ -0,0 +1,114 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type StayRestrictionHandler struct {
+	controller *controllers.StayRestrictionController
+}
+
+func NewStayRestrictionHandler(controller *controllers.StayRestrictionController) *StayRestrictionHandler {
+	return &StayRestrictionHandler{
+		controller: controller,
+	}
+}
+
+func (self *StayRestrictionHandler) HandleListStayRestrictions(ctx *fiber.Ctx) error {
+	var query controllers.StayRestrictionGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	restrictions, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(restrictions)
+}
+
+func (self *StayRestrictionHandler) HandleGetStayRestriction(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	restriction, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, restriction.Version, restriction)
+}
+
+func (self *StayRestrictionHandler) HandleCreateStayRestriction(ctx *fiber.Ctx) error {
+	var params types.CreateStayRestrictionParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	restriction, err := types.NewStayRestrictionFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdRestriction, err := self.controller.Create(ctx.Context(), restriction)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdRestriction)
+}
+
+func (self *StayRestrictionHandler) HandleUpdateStayRestriction(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var params types.UpdateStayRestrictionParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewStayRestrictionFromUpdateParams(params)
+	if err != nil {
+		return err
+	}
+	data.Version = version
+
+	updatedRestriction, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedRestriction.Version, updatedRestriction)
+}
+
+func (self *StayRestrictionHandler) HandleDeleteStayRestriction(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
@@ -5,7 +5,6 @@ import (
 	"hotel/types"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type RoomHandler struct {
@@ -20,38 +19,48 @@ func NewRoomHandler(controller *controllers.RoomController) *RoomHandler {
 
 func (self *RoomHandler) HandleListRooms(ctx *fiber.Ctx) error {
 	var query controllers.RoomGetQueryParams
-	err := ctx.QueryParser(&query)
+	err := ParseQuery(ctx, self.controller.Store, &query)
 	if err != nil {
 		return err
 	}
 	rooms, err := self.controller.Get(ctx.Context(), &query)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 
 	return ctx.JSON(rooms)
 }
 
 func (self *RoomHandler) HandleGetRoom(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	var query controllers.DisplayCurrencyQueryParams
+	err = ParseQuery(ctx, self.controller.Store, &query)
 	if err != nil {
 		return err
 	}
 
 	room, err := self.controller.GetUnfoldedByID(ctx.Context(), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 	if room == nil {
-		return fiber.NewError(fiber.StatusNotFound, EntityNotFoundMessage)
+		return controllers.NotFoundError{Entity: "Room"}
+	}
+	err = self.controller.WithDisplayPrice(room, query.Currency)
+	if err != nil {
+		return err
 	}
 
-	return ctx.JSON(room)
+	return SendWithETag(ctx, room.Version, room)
 }
 
 func (self *RoomHandler) HandleCreateRoom(ctx *fiber.Ctx) error {
 	var params types.CreateRoomParams
-	err := ctx.BodyParser(&params)
+	err := ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -63,10 +72,6 @@ func (self *RoomHandler) HandleCreateRoom(ctx *fiber.Ctx) error {
 
 	createdRoom, err := self.controller.Create(ctx.Context(), room)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 
@@ -74,13 +79,17 @@ func (self *RoomHandler) HandleCreateRoom(ctx *fiber.Ctx) error {
 }
 
 func (self *RoomHandler) HandleUpdateRoom(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
 	if err != nil {
 		return err
 	}
 
 	var params types.UpdateRoomParams
-	err = ctx.BodyParser(&params)
+	err = ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -89,29 +98,95 @@ func (self *RoomHandler) HandleUpdateRoom(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	data.Version = version
 
 	updatedRoom, err := self.controller.UpdateByID(ctx.Context(), id, data)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 	if updatedRoom == nil {
-		return fiber.NewError(fiber.StatusNotFound, EntityNotFoundMessage)
+		return controllers.NotFoundError{Entity: "Room"}
+	}
+
+	return SendWithETag(ctx, updatedRoom.Version, updatedRoom)
+}
+
+func (self *RoomHandler) HandlePatchRoom(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := self.controller.GetUnfoldedByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return controllers.NotFoundError{Entity: "Room"}
+	}
+
+	var params types.UpdateRoomParams
+	err = ParsePatch(ctx, self.controller.Store, current, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewRoomFromUpdateParams(params)
+	if err != nil {
+		return err
+	}
+	data.Version = version
+
+	updatedRoom, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
 	}
 
-	return ctx.JSON(updatedRoom)
+	return SendWithETag(ctx, updatedRoom.Version, updatedRoom)
 }
 
 func (self *RoomHandler) HandleDeleteRoom(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *RoomHandler) HandleRestoreRoom(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	room, err := self.controller.RestoreByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, room.Version, room)
+}
+
+func (self *RoomHandler) HandlePurgeRoom(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
 	if err != nil {
 		return err
 	}
 
-	err = self.controller.DeleteByID(ctx.Context(), id)
+	err = self.controller.PurgeByID(ctx.Context(), id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+)
+
+func init() {
+	RegisterSchemaEnum(
+		types.SingleRoomType, types.DoubleRoomType,
+		types.SeaSideRoomType, types.DeluxeRoomType,
+	)
+	RegisterSchemaEnum(
+		types.CreateAuditAction, types.UpdateAuditAction, types.DeleteAuditAction,
+		types.RestoreAuditAction, types.PurgeAuditAction,
+	)
+	RegisterSchemaEnum(
+		types.BookingCreatedEventType, types.BookingChangedEventType,
+		types.BookingCancelledEventType, types.BookingRestoredEventType,
+		types.RoomRepricedEventType,
+	)
+	RegisterSchemaEnum(
+		types.PendingWebhookDeliveryStatus, types.SucceededWebhookDeliveryStatus,
+		types.FailedWebhookDeliveryStatus, types.DeadWebhookDeliveryStatus,
+	)
+	RegisterSchemaEnum(
+		types.ConfirmedBookingStatus, types.CheckedInBookingStatus,
+		types.CheckedOutBookingStatus, types.NoShowBookingStatus,
+	)
+	RegisterSchemaEnum(
+		types.RunningJobRunStatus, types.SucceededJobRunStatus, types.FailedJobRunStatus,
+	)
+	RegisterSchemaEnum(
+		types.BookingConfirmedNotificationKind, types.BookingModifiedNotificationKind,
+		types.BookingCancelledNotificationKind, types.BookingReminderNotificationKind,
+		types.BookingReceiptNotificationKind, types.WaitlistOfferNotificationKind,
+	)
+	RegisterSchemaEnum(
+		types.SentNotificationStatus, types.FailedNotificationStatus,
+		types.SkippedNotificationStatus,
+	)
+	RegisterSchemaEnum(types.EnglishLocale, types.GermanLocale)
+	RegisterSchemaEnum(
+		types.ActiveHoldStatus, types.ConvertedHoldStatus,
+		types.ExpiredHoldStatus, types.ReleasedHoldStatus,
+	)
+	RegisterSchemaEnum(types.Currencies()...)
+	RegisterSchemaEnum(
+		types.VATTaxKind, types.CityTaxKind, types.CleaningFeeTaxKind, types.ResortFeeTaxKind,
+	)
+	RegisterSchemaEnum(types.InvoiceInvoiceKind, types.CreditNoteInvoiceKind)
+	RegisterSchemaEnum(
+		types.NightInvoiceLineKind, types.ExtraInvoiceLineKind, types.TaxInvoiceLineKind,
+		types.DiscountInvoiceLineKind,
+	)
+	RegisterSchemaEnum(types.PercentDiscountKind, types.FixedDiscountKind)
+	RegisterSchemaEnum(
+		types.AccrualLoyaltyEntryKind, types.RedemptionLoyaltyEntryKind,
+		types.ReversalLoyaltyEntryKind,
+	)
+	RegisterSchemaEnum(
+		types.WaitingWaitlistStatus, types.OfferedWaitlistStatus, types.BookedWaitlistStatus,
+		types.ExpiredWaitlistStatus, types.DeclinedWaitlistStatus,
+	)
+	RegisterSchemaEnum(
+		types.MinLOSRestrictionKind, types.MaxLOSRestrictionKind,
+		types.ClosedToArrivalRestrictionKind, types.ClosedToDepartureRestrictionKind,
+		types.StopSellRestrictionKind,
+	)
+	RegisterSchemaEnum(
+		types.FlexibleRatePlanKind, types.NonRefundableRatePlanKind,
+		types.BreakfastIncludedRatePlanKind, types.MemberOnlyRatePlanKind,
+	)
+	RegisterSchemaEnum(
+		types.MemberLoyaltyTier, types.SilverLoyaltyTier, types.GoldLoyaltyTier,
+		types.PlatinumLoyaltyTier,
+	)
+	RegisterSchemaEnum(
+		types.HotelsReadPermission, types.HotelsWritePermission,
+		types.RoomsReadPermission, types.RoomsWritePermission,
+		types.BookingsReadPermission, types.BookingsWritePermission,
+		types.UsersReadPermission, types.UsersWritePermission,
+	)
+}
+
+// Documentation of every route registered in main.go, used to build
+// OpenAPI spec. Paths are relative to APIPrefix
+var Routes = []RouteDoc{
+	{Method: "GET", Path: "/openapi.json", Tag: "docs", Summary: "OpenAPI specification", Public: true, Response: map[string]interface{}{}},
+	{Method: "GET", Path: "/docs", Tag: "docs", Summary: "API documentation page", Public: true, Response: "", ContentType: "text/html"},
+
+	{Method: "POST", Path: "/login", Tag: "auth", Summary: "Log in with email and password", Public: true, Request: types.LoginUserParams{}, Response: types.LoginResult{}},
+	{Method: "POST", Path: "/login/otp", Tag: "auth", Summary: "Complete login with second factor", Public: true, Request: types.LoginOTPParams{}, Response: types.LoginResult{}},
+	{Method: "POST", Path: "/login/otp/enrol", Tag: "auth", Summary: "Enrol into TOTP during login", Public: true, Request: types.OTPChallengeParams{}, Response: types.OTPEnrolment{}},
+	{Method: "POST", Path: "/register", Tag: "auth", Summary: "Sign up", Public: true, Request: types.CreateUserParams{}, Response: types.User{}, Status: 201},
+	{Method: "POST", Path: "/verify-email", Tag: "auth", Summary: "Confirm email with token from email", Public: true, Request: types.VerifyEmailParams{}, Response: types.User{}},
+	{Method: "POST", Path: "/resend-verification", Tag: "auth", Summary: "Send email verification again", Public: true, Request: types.ResendVerificationParams{}, Status: 202},
+	{Method: "POST", Path: "/forgot-password", Tag: "auth", Summary: "Send password reset email", Public: true, Request: types.ForgotPasswordParams{}, Status: 202},
+	{Method: "POST", Path: "/reset-password", Tag: "auth", Summary: "Set new password with token from email", Public: true, Request: types.ResetPasswordParams{}},
+
+	{Method: "POST", Path: "/change-password", Tag: "account", Summary: "Change own password", Request: types.ChangePasswordParams{}},
+	{Method: "POST", Path: "/otp/enrol", Tag: "account", Summary: "Start TOTP enrolment", Response: types.OTPEnrolment{}},
+	{Method: "POST", Path: "/otp/confirm", Tag: "account", Summary: "Complete TOTP enrolment", Request: types.OTPCodeParams{}, Response: types.RecoveryCodes{}},
+	{Method: "POST", Path: "/otp/disable", Tag: "account", Summary: "Disable TOTP", Request: types.DisableOTPParams{}},
+	{Method: "POST", Path: "/otp/recovery-codes", Tag: "account", Summary: "Regenerate recovery codes", Request: types.OTPCodeParams{}, Response: types.RecoveryCodes{}},
+
+	{Method: "POST", Path: "/user", Tag: "users", Summary: "Create user", Request: types.CreateUserParams{}, Response: types.User{}, Status: 201},
+	{Method: "GET", Path: "/user", Tag: "users", Summary: "List users", Response: []types.User{}},
+	{Method: "GET", Path: "/user/:id", Tag: "users", Summary: "Get user", Response: types.User{}},
+	{Method: "PUT", Path: "/user/:id", Tag: "users", Summary: "Update user", Request: types.UpdateUserParams{}, Response: types.User{}, IfMatch: true},
+	{Method: "PATCH", Path: "/user/:id", Tag: "users", Summary: "Partially update user with JSON Merge Patch", Request: types.UpdateUserParams{}, Response: types.User{}, IfMatch: true},
+	{Method: "DELETE", Path: "/user/:id", Tag: "users", Summary: "Delete user", IfMatch: true},
+	{Method: "GET", Path: "/user/:id/loyalty", Tag: "users", Summary: "Loyalty points balance, tier and history", Response: types.LoyaltyAccount{}},
+	{Method: "POST", Path: "/user/:id/unlock", Tag: "admin", Summary: "Unlock account after failed logins", Response: types.User{}},
+	{Method: "GET", Path: "/lockout", Tag: "admin", Summary: "List locked accounts and IPs", Response: []types.LoginAttempts{}},
+	{Method: "POST", Path: "/lockout/unlock-ip", Tag: "admin", Summary: "Unlock IP after failed logins", Request: types.UnlockIPParams{}},
+
+	{Method: "POST", Path: "/apikey", Tag: "admin", Summary: "Create API key", Request: types.CreateAPIKeyParams{}, Response: types.APIKeyWithSecret{}, Status: 201},
+	{Method: "GET", Path: "/apikey", Tag: "admin", Summary: "List API keys", Response: []types.APIKey{}},
+	{Method: "DELETE", Path: "/apikey/:id", Tag: "admin", Summary: "Revoke API key", Response: types.APIKey{}},
+	{Method: "GET", Path: "/audit", Tag: "admin", Summary: "List audit records, newest first", Query: controllers.AuditGetQueryParams{}, Response: []types.AuditRecord{}},
+	{Method: "GET", Path: "/scheduler/schedule", Tag: "admin", Summary: "List schedules of background jobs", Response: []types.Schedule{}},
+	{Method: "GET", Path: "/scheduler/run", Tag: "admin", Summary: "List runs of background jobs, newest first", Query: controllers.JobRunGetQueryParams{}, Response: []types.JobRun{}},
+
+	{Method: "POST", Path: "/hotel", Tag: "hotels", Summary: "Create hotel", Request: types.CreateHotelParams{}, Response: types.HotelWithRooms{}, Status: 201},
+	{Method: "GET", Path: "/hotel", Tag: "hotels", Summary: "List hotels", Query: controllers.HotelGetQueryParams{}, Response: []types.Hotel{}},
+	{Method: "GET", Path: "/hotel/:id", Tag: "hotels", Summary: "Get hotel with rooms", Response: types.HotelWithRooms{}},
+	{Method: "PUT", Path: "/hotel/:id", Tag: "hotels", Summary: "Update hotel", Request: types.UpdateHotelParams{}, Response: types.HotelWithRooms{}, IfMatch: true},
+	{Method: "PATCH", Path: "/hotel/:id", Tag: "hotels", Summary: "Partially update hotel with JSON Merge Patch", Request: types.UpdateHotelParams{}, Response: types.HotelWithRooms{}, IfMatch: true},
+	{Method: "DELETE", Path: "/hotel/:id", Tag: "hotels", Summary: "Delete hotel and archive its rooms", IfMatch: true},
+	{Method: "POST", Path: "/hotel/:id/restore", Tag: "hotels", Summary: "Restore deleted hotel with its archived rooms", Response: types.HotelWithRooms{}},
+	{Method: "DELETE", Path: "/hotel/:id/purge", Tag: "hotels", Summary: "Permanently remove deleted hotel, admin only"},
+	{Method: "PUT", Path: "/hotel/:id/tax-rules", Tag: "hotels", Summary: "Replace taxes and fees applied to new bookings", Request: types.SetTaxRulesParams{}, Response: types.HotelWithRooms{}, IfMatch: true},
+
+	{Method: "POST", Path: "/webhook", Tag: "webhooks", Summary: "Subscribe to hotel events, secret is returned only once. Admin only", Request: types.CreateWebhookParams{}, Response: types.WebhookWithSecret{}, Status: 201},
+	{Method: "GET", Path: "/webhook", Tag: "webhooks", Summary: "List webhooks, admin only", Query: controllers.WebhookGetQueryParams{}, Response: []types.Webhook{}},
+	{Method: "GET", Path: "/webhook/:id", Tag: "webhooks", Summary: "Get webhook", Response: types.Webhook{}},
+	{Method: "PUT", Path: "/webhook/:id", Tag: "webhooks", Summary: "Update webhook", Request: types.UpdateWebhookParams{}, Response: types.Webhook{}, IfMatch: true},
+	{Method: "DELETE", Path: "/webhook/:id", Tag: "webhooks", Summary: "Delete webhook", IfMatch: true},
+	{Method: "GET", Path: "/webhook/:id/delivery", Tag: "webhooks", Summary: "Delivery log, newest first", Query: controllers.WebhookDeliveryGetQueryParams{}, Response: []types.WebhookDelivery{}},
+	{Method: "POST", Path: "/webhook/:id/delivery/:deliveryID/redeliver", Tag: "webhooks", Summary: "Send delivery again", Response: types.WebhookDelivery{}, Status: 202},
+
+	{Method: "POST", Path: "/room", Tag: "rooms", Summary: "Create room", Request: types.CreateRoomParams{}, Response: types.RoomUnfolded{}, Status: 201},
+	{Method: "GET", Path: "/room", Tag: "rooms", Summary: "List rooms", Query: controllers.RoomGetQueryParams{}, Response: []types.Room{}},
+	{Method: "GET", Path: "/room/:id", Tag: "rooms", Summary: "Get room with booked dates", Query: controllers.DisplayCurrencyQueryParams{}, Response: types.RoomUnfolded{}},
+	{Method: "PUT", Path: "/room/:id", Tag: "rooms", Summary: "Update room", Request: types.UpdateRoomParams{}, Response: types.RoomUnfolded{}, IfMatch: true},
+	{Method: "PATCH", Path: "/room/:id", Tag: "rooms", Summary: "Partially update room with JSON Merge Patch", Request: types.UpdateRoomParams{}, Response: types.RoomUnfolded{}, IfMatch: true},
+	{Method: "DELETE", Path: "/room/:id", Tag: "rooms", Summary: "Delete room", IfMatch: true},
+	{Method: "POST", Path: "/room/:id/restore", Tag: "rooms", Summary: "Restore deleted room", Response: types.RoomUnfolded{}},
+	{Method: "DELETE", Path: "/room/:id/purge", Tag: "rooms", Summary: "Permanently remove deleted room, admin only"},
+	{Method: "GET", Path: "/room/:id/availability/stream", Tag: "rooms", Summary: "Server-sent events with room's booked dates on every change", Query: controllers.AvailabilityStreamQueryParams{}, Response: types.AvailabilityChange{}, ContentType: "text/event-stream"},
+	{Method: "GET", Path: "/hotel/:id/availability/stream", Tag: "hotels", Summary: "Server-sent events with booked dates of hotel's rooms on every change", Query: controllers.AvailabilityStreamQueryParams{}, Response: types.AvailabilityChange{}, ContentType: "text/event-stream"},
+	{Method: "GET", Path: "/hotel/:id/availability", Tag: "hotels", Summary: "Free rooms for stay with rate plans they can be booked with", Query: controllers.AvailabilitySearchQueryParams{}, Response: []types.RoomAvailability{}},
+
+	{Method: "POST", Path: "/rate-plan", Tag: "rate plans", Summary: "Create rate plan, hotel's rooms are then booked only with its plans. Admin only", Request: types.CreateRatePlanParams{}, Response: types.RatePlan{}, Status: 201},
+	{Method: "GET", Path: "/rate-plan", Tag: "rate plans", Summary: "List rate plans", Query: controllers.RatePlanGetQueryParams{}, Response: []types.RatePlan{}},
+	{Method: "GET", Path: "/rate-plan/:id", Tag: "rate plans", Summary: "Get rate plan", Response: types.RatePlan{}},
+	{Method: "PUT", Path: "/rate-plan/:id", Tag: "rate plans", Summary: "Update rate plan, bookings keep plan as it was. Admin only", Request: types.UpdateRatePlanParams{}, Response: types.RatePlan{}, IfMatch: true},
+	{Method: "DELETE", Path: "/rate-plan/:id", Tag: "rate plans", Summary: "Delete rate plan, admin only", IfMatch: true},
+
+	{Method: "POST", Path: "/stay-restriction", Tag: "stay restrictions", Summary: "Create min/max length of stay, closed to arrival or departure, or stop-sell rule for hotel or its room type. Admin only", Request: types.CreateStayRestrictionParams{}, Response: types.StayRestriction{}, Status: 201},
+	{Method: "GET", Path: "/stay-restriction", Tag: "stay restrictions", Summary: "List stay restrictions by date", Query: controllers.StayRestrictionGetQueryParams{}, Response: []types.StayRestriction{}},
+	{Method: "GET", Path: "/stay-restriction/:id", Tag: "stay restrictions", Summary: "Get stay restriction", Response: types.StayRestriction{}},
+	{Method: "PUT", Path: "/stay-restriction/:id", Tag: "stay restrictions", Summary: "Update stay restriction, bookings made are kept. Admin only", Request: types.UpdateStayRestrictionParams{}, Response: types.StayRestriction{}, IfMatch: true},
+	{Method: "DELETE", Path: "/stay-restriction/:id", Tag: "stay restrictions", Summary: "Delete stay restriction, admin only", IfMatch: true},
+
+	{Method: "POST", Path: "/booking", Tag: "bookings", Summary: "Book room, with promo code or the best automatic promotion and loyalty points", Request: types.CreateBookingParams{}, Response: types.BookingUnfolded{}, Status: 201},
+	{Method: "POST", Path: "/booking/quote", Tag: "bookings", Summary: "Price booking with discount, taxes and fees without making it", Request: types.CreateBookingParams{}, Response: types.BookingUnfolded{}},
+	{Method: "GET", Path: "/booking", Tag: "bookings", Summary: "List bookings", Query: controllers.BookingGetQueryParams{}, Response: []types.Booking{}},
+	{Method: "GET", Path: "/booking/:id", Tag: "bookings", Summary: "Get booking", Query: controllers.DisplayCurrencyQueryParams{}, Response: types.BookingUnfolded{}},
+	{Method: "PUT", Path: "/booking/:id", Tag: "bookings", Summary: "Update booking", Request: types.UpdateBookingParams{}, Response: types.BookingUnfolded{}, IfMatch: true},
+	{Method: "PATCH", Path: "/booking/:id", Tag: "bookings", Summary: "Partially update booking with JSON Merge Patch", Request: types.UpdateBookingParams{}, Response: types.BookingUnfolded{}, IfMatch: true},
+	{Method: "DELETE", Path: "/booking/:id", Tag: "bookings", Summary: "Cancel booking, guests only while its rate plan allows free cancellation", IfMatch: true},
+	{Method: "POST", Path: "/booking/:id/restore", Tag: "bookings", Summary: "Restore cancelled booking", Response: types.BookingUnfolded{}},
+	{Method: "DELETE", Path: "/booking/:id/purge", Tag: "bookings", Summary: "Permanently remove cancelled booking, admin only"},
+	{Method: "POST", Path: "/booking/:id/check-in", Tag: "bookings", Summary: "Register guest's arrival on arrival date, admin only", Response: types.BookingUnfolded{}},
+	{Method: "POST", Path: "/booking/:id/check-out", Tag: "bookings", Summary: "Register guest's departure, admin only", Response: types.BookingUnfolded{}},
+
+	{Method: "POST", Path: "/hold", Tag: "holds", Summary: "Lock room for dates during checkout", Request: types.CreateHoldParams{}, Response: types.Hold{}, Status: 201},
+	{Method: "GET", Path: "/hold/:id", Tag: "holds", Summary: "Get hold", Response: types.Hold{}},
+	{Method: "DELETE", Path: "/hold/:id", Tag: "holds", Summary: "Release hold before it expires"},
+	{Method: "POST", Path: "/hold/:id/book", Tag: "holds", Summary: "Convert hold into booking", Response: types.BookingUnfolded{}, Status: 201},
+
+	{Method: "POST", Path: "/waitlist", Tag: "waitlist", Summary: "Wait for room of type to be freed for dates", Request: types.CreateWaitlistEntryParams{}, Response: types.WaitlistEntry{}, Status: 201},
+	{Method: "GET", Path: "/waitlist", Tag: "waitlist", Summary: "List waitlist entries in order rooms are offered. Users see their own", Query: controllers.WaitlistGetQueryParams{}, Response: []types.WaitlistEntry{}},
+	{Method: "GET", Path: "/waitlist/:id", Tag: "waitlist", Summary: "Get waitlist entry", Response: types.WaitlistEntry{}},
+	{Method: "DELETE", Path: "/waitlist/:id", Tag: "waitlist", Summary: "Leave waitlist, room offered passes to the next guest"},
+
+	{Method: "POST", Path: "/booking/:id/invoice", Tag: "invoices", Summary: "Issue invoice for nights of the stay and extras, admin only", Request: types.IssueInvoiceParams{}, Response: types.Invoice{}, Status: 201},
+	{Method: "GET", Path: "/invoice", Tag: "invoices", Summary: "List invoices and credit notes. Users see their own", Query: controllers.InvoiceGetQueryParams{}, Response: []types.Invoice{}},
+	{Method: "GET", Path: "/invoice/:id", Tag: "invoices", Summary: "Get invoice", Response: types.Invoice{}},
+	{Method: "GET", Path: "/invoice/:id/pdf", Tag: "invoices", Summary: "Get invoice as PDF", Response: "", ContentType: "application/pdf"},
+	{Method: "POST", Path: "/invoice/:id/credit-note", Tag: "invoices", Summary: "Refund lines or the whole invoice with credit note, admin only", Request: types.IssueCreditNoteParams{}, Response: types.Invoice{}, Status: 201},
+
+	{Method: "POST", Path: "/promotion", Tag: "promotions", Summary: "Create promotion, without code it applies automatically. Admin only", Request: types.CreatePromotionParams{}, Response: types.Promotion{}, Status: 201},
+	{Method: "GET", Path: "/promotion", Tag: "promotions", Summary: "List promotions, admin only", Query: controllers.PromotionGetQueryParams{}, Response: []types.Promotion{}},
+	{Method: "GET", Path: "/promotion/:id", Tag: "promotions", Summary: "Get promotion, admin only", Response: types.Promotion{}},
+	{Method: "PUT", Path: "/promotion/:id", Tag: "promotions", Summary: "Update promotion, admin only", Request: types.UpdatePromotionParams{}, Response: types.Promotion{}, IfMatch: true},
+	{Method: "DELETE", Path: "/promotion/:id", Tag: "promotions", Summary: "Delete promotion, bookings keep their discounts. Admin only", IfMatch: true},
+
+	{Method: "GET", Path: "/notification", Tag: "notifications", Summary: "List sent and failed notifications, newest first. Users see their own", Query: controllers.NotificationGetQueryParams{}, Response: []types.Notification{}},
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"hotel/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SchedulerHandler struct {
+	controller *controllers.SchedulerController
+}
+
+func NewSchedulerHandler(controller *controllers.SchedulerController) *SchedulerHandler {
+	return &SchedulerHandler{
+		controller: controller,
+	}
+}
+
+func (self *SchedulerHandler) HandleListSchedules(ctx *fiber.Ctx) error {
+	schedules, err := self.controller.GetSchedules(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(schedules)
+}
+
+func (self *SchedulerHandler) HandleListRuns(ctx *fiber.Ctx) error {
+	var query controllers.JobRunGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	runs, err := self.controller.GetRuns(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(runs)
+}
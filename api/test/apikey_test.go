@@ -0,0 +1,164 @@
+package apiTest
+
+import (
+	"context"
+	"encoding/json"
+	"hotel/api"
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAPIKeyScope(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "keyadmin@gmail.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	partner, err := createTestUser(store, "partner@gmail.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hotels := []*types.HotelWithRooms{}
+	for _, name := range []string{"Partner hotel", "Other hotel"} {
+		hotel, err := store.CT.Hotels.Create(
+			context.Background(), &types.Hotel{Name: name, Location: "Berlin"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hotels = append(hotels, hotel)
+	}
+
+	apiKeyController := &controllers.APIKeyController{Store: store}
+	apiKey, err := apiKeyController.Create(
+		contextWithUser(admin),
+		&types.APIKey{
+			Name:        "Partner",
+			UserID:      partner.ID,
+			HotelIDs:    []primitive.ObjectID{hotels[0].ID},
+			Permissions: []types.APIKeyPermission{types.HotelsReadPermission},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys are managed by admins only
+	_, err = apiKeyController.Get(contextWithUser(partner))
+	if err != controllers.ErrAdminOnly {
+		t.Fatalf("Non-admin listed API keys")
+	}
+
+	app := newTestApp()
+	apiKeyHandler := api.NewAPIKeyHandler(apiKeyController)
+	hotelHandler := api.NewHotelHandler(
+		&controllers.HotelController{Store: store},
+	)
+	app.Use(apiKeyHandler.Authenticate)
+	app.Get(
+		"/hotel", api.RequirePermission(types.HotelsReadPermission),
+		hotelHandler.HandleListHotels,
+	)
+	app.Get(
+		"/hotel/:id", api.RequirePermission(types.HotelsReadPermission),
+		hotelHandler.HandleGetHotel,
+	)
+	app.Delete(
+		"/hotel/:id", api.RequirePermission(types.HotelsWritePermission),
+		hotelHandler.HandleDeleteHotel,
+	)
+
+	resp, err := sendAPIKeyRequest(app, "GET", "/hotel", apiKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect list status %d", resp.StatusCode)
+	}
+	var listed []*types.Hotel
+	err = json.NewDecoder(resp.Body).Decode(&listed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != hotels[0].ID {
+		t.Fatalf("API key sees hotels out of its scope")
+	}
+
+	resp, err = sendAPIKeyRequest(app, "GET", "/hotel/"+hotels[1].ID.Hex(), apiKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("API key accessed hotel out of its scope")
+	}
+
+	resp, err = sendAPIKeyRequest(app, "DELETE", "/hotel/"+hotels[0].ID.Hex(), apiKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("API key deleted hotel without permission")
+	}
+
+	stored, err := apiKeyController.GetByID(context.Background(), apiKey.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Fatalf("Last used time wasn't tracked")
+	}
+	if stored.KeyHash == apiKey.Key {
+		t.Fatalf("API key is stored in plain text")
+	}
+
+	// Bookings are listed for rooms of hotels in scope only
+	roomIDs := []primitive.ObjectID{}
+	for _, hotel := range hotels {
+		roomID, err := store.DB.Rooms.Create(context.Background(), &types.Room{
+			HotelID: hotel.ID, Type: types.SingleRoomType, Version: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = store.DB.Bookings.Create(context.Background(), &types.Booking{
+			UserID: partner.ID, RoomID: roomID, Version: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		roomIDs = append(roomIDs, roomID)
+	}
+	keyCtx := context.WithValue(
+		contextWithUser(partner), controllers.APIKeyContextKey, apiKey.APIKey,
+	)
+	bookings, err := store.CT.Bookings.Get(keyCtx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bookings) != 1 || bookings[0].RoomID != roomIDs[0] {
+		t.Fatalf("API key sees bookings out of its scope: %+v", bookings)
+	}
+	_, err = store.CT.Bookings.Get(keyCtx, &controllers.BookingGetQueryParams{RoomID: roomIDs[1]})
+	if err != controllers.ErrHotelOutOfScope {
+		t.Fatalf("Expected room out of scope to be rejected, got %v", err)
+	}
+
+	_, err = apiKeyController.RevokeByID(contextWithUser(admin), apiKey.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = sendAPIKeyRequest(app, "GET", "/hotel", apiKey.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("Revoked API key was accepted")
+	}
+}
@@ -0,0 +1,85 @@
+package apiTest
+
+import (
+	"errors"
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, err := createTestUser(store, "guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(admin)
+
+	hotel, err := store.CT.Hotels.Create(ctx, &types.Hotel{Name: "Audited", Location: "Rome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Hotels.UpdateByID(ctx, hotel.ID, &types.Hotel{
+		Name: "Renamed", Location: "Rome", Version: hotel.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CT.Hotels.DeleteByID(ctx, hotel.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := store.CT.Audit.Get(ctx, &controllers.AuditGetQueryParams{
+		Entity: "Hotel", EntityID: hotel.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Found %d audit records instead of 3", len(records))
+	}
+	actions := []types.AuditAction{
+		types.DeleteAuditAction, types.UpdateAuditAction, types.CreateAuditAction,
+	}
+	for i, record := range records {
+		if record.Action != actions[i] {
+			t.Fatalf("Record %d has action %s instead of %s", i, record.Action, actions[i])
+		}
+		if record.ActorID != admin.ID {
+			t.Fatalf("Record %d has wrong actor", i)
+		}
+	}
+	change := records[1].Changes["name"]
+	if change.Before != "Audited" || change.After != "Renamed" || len(records[1].Changes) != 1 {
+		t.Fatalf("Incorrect update diff %v", records[1].Changes)
+	}
+	if _, ok := records[0].Changes["deletedAt"]; !ok {
+		t.Fatal("Soft delete isn't recorded as deletedAt change")
+	}
+
+	userRecords, err := store.CT.Audit.Get(ctx, &controllers.AuditGetQueryParams{
+		EntityID: guest.ID, Action: types.CreateAuditAction,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userRecords) != 1 {
+		t.Fatalf("Found %d user creation records instead of 1", len(userRecords))
+	}
+	if userRecords[0].Changes["encryptedPassword"].After != "[redacted]" {
+		t.Fatal("Password hash isn't redacted in audit log")
+	}
+
+	_, err = store.CT.Audit.Get(contextWithUser(guest), nil)
+	var forbiddenErr controllers.ForbiddenError
+	if !errors.As(err, &forbiddenErr) {
+		t.Fatalf("Audit log is accessible by non-admin, got %v", err)
+	}
+}
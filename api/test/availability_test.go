@@ -0,0 +1,92 @@
+package apiTest
+
+import (
+	"context"
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// Waits for the first change of availability stream
+func nextAvailabilityChange(
+	t *testing.T, stream *controllers.AvailabilityStream,
+) (*types.AvailabilityChange, string) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		changes, eventID, err := stream.Next(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 0 {
+			return changes[0], eventID
+		}
+	}
+	t.Fatal("No availability change received")
+	return nil, ""
+}
+
+func TestAvailabilityStream(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	user, err := createTestUser(store, "watcher@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(user)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Watched", Location: "Oslo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := store.CT.Availability.WatchRoom(ctx, roomID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close(ctx)
+
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change, eventID := nextAvailabilityChange(t, stream)
+	if change.RoomID != roomID || change.Reason != types.BookingCreatedEventType {
+		t.Fatalf("Unexpected change %+v", change)
+	}
+	if len(change.BookedDates) != 1 || change.BookedDates[0].DateFrom != booking.DateFrom {
+		t.Fatalf("Expected booked dates of created booking, got %+v", change.BookedDates)
+	}
+
+	err = store.CT.Bookings.DeleteByID(ctx, booking.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reconnected client gets what it missed
+	resumed, err := store.CT.Availability.WatchRoom(ctx, roomID, eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close(ctx)
+	change, _ = nextAvailabilityChange(t, resumed)
+	if change.Reason != types.BookingCancelledEventType || len(change.BookedDates) != 0 {
+		t.Fatalf("Expected cancellation with no booked dates, got %+v", change)
+	}
+
+	_, err = store.CT.Availability.WatchRoom(ctx, roomID, "not-a-token")
+	if err == nil {
+		t.Fatal("Expected invalid event id to be rejected")
+	}
+}
@@ -0,0 +1,89 @@
+package apiTest
+
+import (
+	"encoding/json"
+	"hotel/api"
+	"hotel/controllers"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestErrorResponse(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	app := newTestApp()
+	hotelHandler := api.NewHotelHandler(
+		&controllers.HotelController{Store: store},
+	)
+	app.Get("/hotel/:id", hotelHandler.HandleGetHotel)
+
+	cases := []struct {
+		path   string
+		status int
+		code   controllers.ErrorCode
+	}{
+		{"/hotel/notanid", fiber.StatusBadRequest, controllers.ValidationErrorCode},
+		{"/hotel/64b000000000000000000000", fiber.StatusNotFound, controllers.NotFoundErrorCode},
+	}
+	for _, c := range cases {
+		resp, err := app.Test(httptest.NewRequest("GET", c.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("Expected status %d for %s but got %d", c.status, c.path, resp.StatusCode)
+		}
+		var body api.ErrorResponse
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body.Error.Code != c.code {
+			t.Fatalf("Expected code %s for %s but got %s", c.code, c.path, body.Error.Code)
+		}
+		if len(body.Error.RequestID) == 0 {
+			t.Fatalf("Request ID is missing")
+		}
+		if c.code == controllers.ValidationErrorCode && len(body.Error.Fields["id"]) == 0 {
+			t.Fatalf("Field details are missing")
+		}
+	}
+}
+
+func TestMalformedBody(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	app := newTestApp()
+	roomHandler := api.NewRoomHandler(&controllers.RoomController{Store: store})
+	app.Post("/room", roomHandler.HandleCreateRoom)
+
+	bodies := []string{
+		`{"type": 1,`,
+		`{"type": "single"}`,
+		`{"type": 1, "price": {"amount": "100.00", "currency": "XXX"}}`,
+	}
+	for _, body := range bodies {
+		req := httptest.NewRequest("POST", "/room", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusBadRequest {
+			t.Fatalf("Expected status %d for %s but got %d", fiber.StatusBadRequest, body, resp.StatusCode)
+		}
+		var errResp api.ErrorResponse
+		err = json.NewDecoder(resp.Body).Decode(&errResp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if errResp.Error.Code != controllers.ValidationErrorCode || len(errResp.Error.Fields["body"]) == 0 {
+			t.Fatalf("Expected body validation error for %s, got %+v", body, errResp.Error)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package apiTest
+
+import (
+	"context"
+	"hotel/api"
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestOptimisticConcurrency(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	hotel, err := store.CT.Hotels.Create(
+		context.Background(), &types.Hotel{Name: "Versioned", Location: "Rome"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	hotelHandler := api.NewHotelHandler(
+		&controllers.HotelController{Store: store},
+	)
+	app.Get("/hotel/:id", hotelHandler.HandleGetHotel)
+	app.Put("/hotel/:id", hotelHandler.HandleUpdateHotel)
+	app.Delete("/hotel/:id", hotelHandler.HandleDeleteHotel)
+	path := "/hotel/" + hotel.ID.Hex()
+
+	resp, err := sendStructJSONRequest(app, "GET", path, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag != api.ETag(1) {
+		t.Fatalf("Incorrect ETag %s of new hotel", etag)
+	}
+
+	params := types.UpdateHotelParams{BaseHotelParams: types.BaseHotelParams{
+		Name: "Renamed", Location: "Rome",
+	}}
+	send := func(method string, ifMatch string) int {
+		req := jsonRequest(method, path, params)
+		if len(ifMatch) != 0 {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := send("PUT", ""); status != fiber.StatusPreconditionRequired {
+		t.Fatalf("Update without If-Match returned %d", status)
+	}
+	if status := send("PUT", etag); status != fiber.StatusOK {
+		t.Fatalf("Update with current version returned %d", status)
+	}
+	// Second writer is based on the same, now stale, version
+	if status := send("PUT", etag); status != fiber.StatusPreconditionFailed {
+		t.Fatalf("Stale update returned %d", status)
+	}
+	if status := send("DELETE", etag); status != fiber.StatusPreconditionFailed {
+		t.Fatalf("Stale delete returned %d", status)
+	}
+	if status := send("DELETE", api.ETag(2)); status != fiber.StatusNoContent {
+		t.Fatalf("Delete with current version returned %d", status)
+	}
+}
+
+func TestLegacyVersionBackfill(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	// Written before hotels had versions
+	id, err := store.DB.Hotels.Create(
+		context.Background(), bson.M{"name": "Legacy", "location": "Turin"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DB.Migrate(context.Background(), controllers.DefaultCurrency())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	hotelHandler := api.NewHotelHandler(
+		&controllers.HotelController{Store: store},
+	)
+	app.Get("/hotel/:id", hotelHandler.HandleGetHotel)
+	app.Put("/hotel/:id", hotelHandler.HandleUpdateHotel)
+	path := "/hotel/" + id.Hex()
+
+	resp, err := sendStructJSONRequest(app, "GET", path, struct{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag != api.ETag(1) {
+		t.Fatalf("Expected legacy hotel to start at version 1, got ETag %s", etag)
+	}
+	req := jsonRequest("PUT", path, types.UpdateHotelParams{BaseHotelParams: types.BaseHotelParams{
+		Name: "Modern", Location: "Turin",
+	}})
+	req.Header.Set("If-Match", etag)
+	resp, err = app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Update of legacy hotel returned %d", resp.StatusCode)
+	}
+}
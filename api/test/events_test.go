@@ -0,0 +1,107 @@
+package apiTest
+
+import (
+	"context"
+	"fmt"
+	"hotel/events"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// Fails first delivery of every event
+type flakySink struct {
+	attempts map[string]int
+}
+
+func (self *flakySink) Name() string {
+	return "flaky"
+}
+
+func (self *flakySink) Deliver(ctx context.Context, event *types.Event) error {
+	self.attempts[event.ID.Hex()]++
+	if self.attempts[event.ID.Hex()] == 1 {
+		return fmt.Errorf("Temporary failure")
+	}
+	return nil
+}
+
+func TestEventOutbox(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	user, err := createTestUser(store, "guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(user)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Evented", Location: "Rome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CT.Bookings.DeleteByID(ctx, booking.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	received := []*types.Event{}
+	bus := events.NewBus()
+	for _, eventType := range []types.EventType{
+		types.BookingCreatedEventType, types.BookingCancelledEventType,
+	} {
+		bus.Subscribe(eventType, func(ctx context.Context, event *types.Event) error {
+			received = append(received, event)
+			return nil
+		})
+	}
+	flaky := &flakySink{attempts: map[string]int{}}
+	dispatcher := events.NewDispatcher(store.DB, []events.Sink{bus, flaky})
+	// Failed events become due immediately, so one pass retries them
+	dispatcher.BaseBackoff = 0
+
+	processed, err := dispatcher.DispatchPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 4 {
+		t.Fatalf("Processed %d events instead of 4 (2 events, each retried once)", processed)
+	}
+	if len(received) != 2 {
+		t.Fatalf("Bus received %d events instead of 2", len(received))
+	}
+	if received[0].Type != types.BookingCreatedEventType ||
+		received[1].Type != types.BookingCancelledEventType {
+		t.Fatalf("Unexpected events %s, %s", received[0].Type, received[1].Type)
+	}
+	var cancelled types.BookingCancelled
+	err = received[1].Decode(&cancelled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Booking.ID != booking.ID || cancelled.HotelID != hotelID {
+		t.Fatal("Cancelled event has wrong payload")
+	}
+
+	processed, err = dispatcher.DispatchPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed != 0 {
+		t.Fatalf("Delivered events were processed again")
+	}
+}
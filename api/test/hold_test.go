@@ -0,0 +1,198 @@
+package apiTest
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestBookingHold(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	guest, err := createTestUser(store, "guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := createTestUser(store, "other@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guestCtx := contextWithUser(guest)
+	otherCtx := contextWithUser(other)
+
+	hotelID, err := store.DB.Hotels.Create(guestCtx, &types.Hotel{Name: "Held", Location: "Lisbon"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(guestCtx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	dateFrom, dateTo := today.AddDays(1), today.AddDays(3)
+
+	_, err = store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: dateFrom, DateTo: dateTo,
+	}, 24*60)
+	if err == nil {
+		t.Fatal("Expected hold longer than TTL to be rejected")
+	}
+	hold, err := store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: dateFrom, DateTo: dateTo,
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Bookings.Create(otherCtx, &types.Booking{
+		RoomID: roomID, DateFrom: dateTo, DateTo: dateTo.AddDays(1),
+	})
+	if err == nil {
+		t.Fatal("Expected held room to be unavailable")
+	}
+	_, err = store.CT.Holds.ConvertByID(otherCtx, hold.ID)
+	if err == nil {
+		t.Fatal("Expected hold of other guest to be hidden")
+	}
+
+	booking, err := store.CT.Holds.ConvertByID(guestCtx, hold.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.HoldID != hold.ID || booking.UserID != guest.ID || booking.DateTo != dateTo {
+		t.Fatalf("Booking doesn't match hold: %+v", booking.Booking)
+	}
+	_, err = store.CT.Holds.ConvertByID(guestCtx, hold.ID)
+	requireConflict(t, err, controllers.HoldNotActiveErrorCode)
+
+	// Expired hold stops locking the room before reaper gets to it
+	stale, err := store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: today.AddDays(5), DateTo: today.AddDays(6),
+	}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	isFree, err := store.CT.Bookings.IsRoomFreeForDate(
+		otherCtx, booking.ID, hold.ID, roomID, stale.DateFrom, stale.DateTo,
+	)
+	if err != nil || isFree {
+		t.Fatalf("Expected active hold to lock room, got %v %v", isFree, err)
+	}
+	expired, err := store.CT.Holds.ExpireStale(guestCtx, stale.ExpiresAt)
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected one hold to expire, got %d %v", expired, err)
+	}
+	_, err = store.CT.Bookings.Create(otherCtx, &types.Booking{
+		RoomID: roomID, DateFrom: stale.DateFrom, DateTo: stale.DateTo,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CT.Holds.ReleaseByID(guestCtx, stale.ID)
+	requireConflict(t, err, controllers.HoldNotActiveErrorCode)
+
+	// Hold converted by admin is booked for its holder
+	admin, err := createTestUser(store, "hold-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, err := store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: today.AddDays(10), DateTo: today.AddDays(11),
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	booked, err := store.CT.Holds.ConvertByID(contextWithUser(admin), held.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booked.UserID != guest.ID {
+		t.Fatalf("Expected booking for holder, got %s", booked.UserID.Hex())
+	}
+	converted, err := store.CT.Holds.GetByID(guestCtx, held.ID)
+	if err != nil || converted.Status != types.ConvertedHoldStatus || converted.BookingID != booked.ID {
+		t.Fatalf("Expected hold to be converted, got %+v %v", converted, err)
+	}
+
+	// Held room can't be deleted, nor its hotel
+	heldRoomID, err := store.DB.Rooms.Create(guestCtx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: heldRoomID, DateFrom: today.AddDays(20), DateTo: today.AddDays(21),
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	err = store.CT.Rooms.DeleteByID(adminCtx, heldRoomID, 0)
+	requireConflict(t, err, controllers.HasActiveBookingsErrorCode)
+	err = store.CT.Hotels.DeleteByID(adminCtx, hotelID, 0)
+	requireConflict(t, err, controllers.HasActiveBookingsErrorCode)
+	err = store.CT.Holds.ReleaseByID(guestCtx, pending.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CT.Rooms.DeleteByID(adminCtx, heldRoomID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConcurrentHolds(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	guest, err := createTestUser(store, "racer@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(guest)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Raced", Location: "Faro"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := store.CT.Holds.Create(ctx, &types.Hold{
+				RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(2),
+			}, 0)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+		} else if _, ok := err.(controllers.ValidationError); !ok {
+			t.Fatal(err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("Expected exactly one hold of room, got %d", created)
+	}
+}
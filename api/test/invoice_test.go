@@ -0,0 +1,175 @@
+package apiTest
+
+import (
+	"bytes"
+	"context"
+	"hotel/api"
+	"hotel/controllers"
+	"hotel/types"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestInvoicePDF(t *testing.T) {
+	invoice := &types.Invoice{
+		Kind:     types.InvoiceInvoiceKind,
+		Number:   "INV-000001",
+		Seller:   types.InvoiceParty{Name: "Seaside (Main)", Address: "Lisbon"},
+		BilledTo: types.InvoiceParty{Name: "Anna", Email: "anna@test.com"},
+		IssuedAt: time.Date(2030, 5, 3, 0, 0, 0, 0, time.UTC),
+		Currency: "EUR",
+		Total:    types.NewMoney(25000, "EUR"),
+	}
+	for i := 0; i < 60; i++ {
+		invoice.Lines = append(invoice.Lines, &types.InvoiceLine{
+			Kind: types.ExtraInvoiceLineKind, Description: "Minibar", Quantity: 1,
+		})
+	}
+	document := api.InvoicePDF(invoice)
+	if !bytes.HasPrefix(document, []byte("%PDF-")) ||
+		!bytes.HasSuffix(bytes.TrimSpace(document), []byte("%%EOF")) {
+		t.Fatal("Expected complete PDF document")
+	}
+	if !bytes.Contains(document, []byte(`(Seaside \(Main\))`)) {
+		t.Fatal("Expected escaped seller name in document")
+	}
+	if bytes.Count(document, []byte("/Type /Page ")) < 2 {
+		t.Fatal("Expected lines to continue on the second page")
+	}
+}
+
+func TestInvoices(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, err := createTestUser(store, "guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	guestCtx := contextWithUser(guest)
+
+	hotelID, err := store.DB.Hotels.Create(adminCtx, &types.Hotel{Name: "Billing", Location: "Oslo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(adminCtx, &types.Room{
+		HotelID: hotelID, Type: types.DoubleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eur := func(minor int64) types.Money {
+		return types.NewMoney(minor, "EUR")
+	}
+	extras := []*types.InvoiceLine{
+		{Kind: types.ExtraInvoiceLineKind, Description: "Breakfast", Quantity: 2, UnitPrice: eur(1250)},
+		{Kind: types.TaxInvoiceLineKind, Description: "City tax", Quantity: 1, UnitPrice: eur(400)},
+	}
+	_, err = store.CT.Invoices.IssueForBooking(guestCtx, booking.ID, extras)
+	if err == nil {
+		t.Fatal("Expected guest to be forbidden to issue invoices")
+	}
+	invoice, err := store.CT.Invoices.IssueForBooking(adminCtx, booking.ID, extras)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if invoice.Number != "INV-000001" || len(invoice.Lines) != 3 ||
+		invoice.Lines[0].Quantity != 2 || invoice.TaxTotal != eur(400) ||
+		invoice.Total != booking.TotalCost.Add(eur(2900)) {
+		t.Fatalf("Unexpected invoice: %+v", invoice)
+	}
+	_, err = store.CT.Invoices.IssueForBooking(adminCtx, booking.ID, nil)
+	requireConflict(t, err, controllers.AlreadyInvoicedErrorCode)
+	// Index keeps one open invoice of booking
+	_, err = store.DB.OpenInvoices.Create(context.Background(), &types.OpenInvoice{
+		InvoiceID: primitive.NewObjectID(), BookingID: booking.ID,
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("Expected second open invoice to be rejected by index, got %v", err)
+	}
+
+	// Partial refund, then the rest can't be credited as a whole
+	creditNote, err := store.CT.Invoices.IssueCreditNote(adminCtx, invoice.ID, []*types.InvoiceLine{
+		{Kind: types.ExtraInvoiceLineKind, Description: "Breakfast", Quantity: 1, UnitPrice: eur(1250)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creditNote.Number != "CN-000001" || creditNote.Total != eur(-1250) ||
+		creditNote.CreditedInvoiceID != invoice.ID {
+		t.Fatalf("Unexpected credit note: %+v", creditNote)
+	}
+	_, err = store.CT.Invoices.IssueCreditNote(adminCtx, invoice.ID, nil)
+	if err == nil {
+		t.Fatal("Expected full credit of partially credited invoice to fail")
+	}
+	_, err = store.CT.Invoices.IssueCreditNote(adminCtx, invoice.ID, []*types.InvoiceLine{
+		{Kind: types.ExtraInvoiceLineKind, Description: "Too much", Quantity: 1, UnitPrice: invoice.Total},
+	})
+	if err == nil {
+		t.Fatal("Expected credit note over remaining total to fail")
+	}
+	_, err = store.CT.Invoices.IssueCreditNote(adminCtx, invoice.ID, []*types.InvoiceLine{
+		{Kind: types.ExtraInvoiceLineKind, Description: "Rest", Quantity: 1, UnitPrice: invoice.Total.Sub(eur(1250))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Invoices.IssueCreditNote(adminCtx, invoice.ID, []*types.InvoiceLine{
+		{Kind: types.ExtraInvoiceLineKind, Description: "More", Quantity: 1, UnitPrice: eur(1)},
+	})
+	if err == nil {
+		t.Fatal("Expected fully credited invoice to reject credit notes")
+	}
+	// Issued invoice stays as it was
+	credited, err := store.CT.Invoices.GetByID(adminCtx, invoice.ID)
+	if err != nil || !reflect.DeepEqual(credited, invoice) {
+		t.Fatalf("Expected invoice to stay unchanged, got %+v %v", credited, err)
+	}
+
+	// Fully credited booking can be invoiced again with the next number
+	reissued, err := store.CT.Invoices.IssueForBooking(adminCtx, booking.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reissued.Number != "INV-000002" || reissued.Total != booking.TotalCost {
+		t.Fatalf("Unexpected reissued invoice: %+v", reissued)
+	}
+
+	invoices, err := store.CT.Invoices.Get(guestCtx, &controllers.InvoiceGetQueryParams{
+		Kind: types.InvoiceInvoiceKind,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invoices) != 2 || invoices[0].ID != invoice.ID {
+		t.Fatalf("Expected guest to see both invoices, got %+v", invoices)
+	}
+	other, err := createTestUser(store, "other@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Invoices.GetByID(contextWithUser(other), invoice.ID)
+	if err == nil {
+		t.Fatal("Expected invoice of other guest to be hidden")
+	}
+}
@@ -0,0 +1,130 @@
+package apiTest
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLoyaltyPoints(t *testing.T) {
+	if tier, bonus := controllers.LoyaltyTierFor(9); tier != types.MemberLoyaltyTier || bonus != 0 {
+		t.Fatalf("Unexpected tier %s with bonus %d", tier, bonus)
+	}
+	if tier, _ := controllers.LoyaltyTierFor(25); tier != types.GoldLoyaltyTier {
+		t.Fatalf("Expected gold tier, got %s", tier)
+	}
+	if points := controllers.LoyaltyPointsEarned(types.NewMoney(19999, "EUR"), 10); points != 218 {
+		t.Fatalf("Expected 199 points plus 10%% bonus, rounded down, got %d", points)
+	}
+	if points := controllers.LoyaltyPointsEarned(types.NewMoney(15000, "JPY"), 0); points != 15000 {
+		t.Fatalf("Expected point per yen, got %d", points)
+	}
+	if value := controllers.LoyaltyPointsValue(250, "EUR"); value != types.NewMoney(250, "EUR") {
+		t.Fatalf("Expected 100 points to be worth 1 EUR, got %s", value)
+	}
+
+	now := time.Now()
+	account := controllers.LoyaltyAccountFromEntries(primitive.NilObjectID, []*types.LoyaltyEntry{
+		{Kind: types.ReversalLoyaltyEntryKind, Points: -300, Nights: -2, CreatedAt: now},
+		{Kind: types.AccrualLoyaltyEntryKind, Points: 300, Nights: 2, CreatedAt: now},
+		{Kind: types.AccrualLoyaltyEntryKind, Points: 1200, Nights: 12, CreatedAt: now},
+		{Kind: types.AccrualLoyaltyEntryKind, Points: 500, Nights: 40, CreatedAt: now.AddDate(-1, 0, 0)},
+	}, now)
+	if account.Balance != 1700 || account.NightsThisYear != 12 || account.Tier != types.SilverLoyaltyTier {
+		t.Fatalf("Unexpected account %+v", account)
+	}
+}
+
+func TestLoyalty(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	guest, err := createTestUser(store, "loyal@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(guest)
+	other, err := createTestUser(store, "curious@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hotel, err := store.CT.Hotels.Create(ctx, &types.Hotel{Name: "Loyal", Location: "Oslo", Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotel.ID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := civil.DateOf(time.Now())
+	_, err = store.CT.Bookings.Quote(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(5), DateTo: today.AddDays(6), PointsToRedeem: 100,
+	})
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected points without balance to be rejected, got %v", err)
+	}
+
+	stay, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today, DateTo: today.AddDays(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Bookings.CheckInByID(ctx, stay.ID)
+	if _, ok := err.(controllers.ForbiddenError); !ok {
+		t.Fatalf("Expected guest not to check in themselves, got %v", err)
+	}
+	admin, err := createTestUser(store, "loyal-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	_, err = store.CT.Bookings.CheckInByID(adminCtx, stay.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Bookings.CheckOutByID(adminCtx, stay.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := store.CT.Loyalty.GetAccount(ctx, guest.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 200 || account.NightsThisYear != 2 || len(account.Entries) != 1 {
+		t.Fatalf("Expected 200 points for 2 nights, got %+v", account)
+	}
+	_, err = store.CT.Loyalty.GetAccount(contextWithUser(other), guest.ID)
+	if _, ok := err.(controllers.NotFoundError); !ok {
+		t.Fatalf("Expected other user's account to be hidden, got %v", err)
+	}
+
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(5), DateTo: today.AddDays(6), PointsToRedeem: 150,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.RedeemedPoints == nil || booking.TotalCost != types.NewMoney(9850, "EUR") {
+		t.Fatalf("Expected points to be taken off cost, got %+v", booking.Booking)
+	}
+	err = store.CT.Bookings.DeleteByID(ctx, booking.ID, booking.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err = store.CT.Loyalty.GetAccount(ctx, guest.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance != 200 || len(account.Entries) != 3 ||
+		account.Entries[0].Kind != types.ReversalLoyaltyEntryKind || account.Entries[0].Sequence != 3 {
+		t.Fatalf("Expected cancellation to give points back, got %+v", account)
+	}
+}
@@ -0,0 +1,180 @@
+package apiTest
+
+import (
+	"encoding/json"
+	"hotel/controllers"
+	"hotel/exchange"
+	"hotel/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMoney(t *testing.T) {
+	for amount, expected := range map[string]int64{
+		"12.5": 1250, "-0.07": -7, "100": 10000, "0.10": 10,
+	} {
+		money, err := types.ParseMoney(amount, "EUR")
+		if err != nil || money.Minor != expected {
+			t.Fatalf("Expected %q to be %d cents, got %d %v", amount, expected, money.Minor, err)
+		}
+	}
+	for _, amount := range []string{"1.005", "1.", ".5", "1,5", "", "12a"} {
+		_, err := types.ParseMoney(amount, "EUR")
+		if err == nil {
+			t.Fatalf("Expected %q to be rejected", amount)
+		}
+	}
+	if _, err := types.ParseMoney("1.5", "JPY"); err == nil {
+		t.Fatal("Expected yen with fraction to be rejected")
+	}
+
+	// Float would give 0.30000000000000004
+	sum := types.NewMoney(10, "EUR").Add(types.NewMoney(20, "EUR"))
+	if sum.Amount() != "0.30" || sum.String() != "0.30 EUR" {
+		t.Fatalf("Unexpected sum %s", sum)
+	}
+	if types.CheckSameCurrency(types.NewMoney(1, "EUR"), types.NewMoney(2, "USD")) == nil {
+		t.Fatal("Expected amounts in different currencies to be reported")
+	}
+	if err := types.CheckSameCurrency(types.NewMoney(1, "EUR"), types.Money{}); err != nil {
+		t.Fatalf("Expected money without currency to go with any, got %v", err)
+	}
+	if types.NewMoney(-5, "USD").Amount() != "-0.05" || types.NewMoney(1500, "JPY").Amount() != "1500" {
+		t.Fatal("Unexpected formatting")
+	}
+	if types.NewMoney(10000, "EUR").Div(3).Minor != 3333 ||
+		types.NewMoney(5, "EUR").MulRat(1, 2).Minor != 3 ||
+		types.NewMoney(-5, "EUR").MulRat(1, 2).Minor != -3 {
+		t.Fatal("Expected rounding half away from zero")
+	}
+
+	var decoded struct {
+		Price types.Money `json:"price"`
+	}
+	err := json.Unmarshal([]byte(`{"price": {"amount": 19.99, "currency": "GBP"}}`), &decoded)
+	if err != nil || decoded.Price != types.NewMoney(1999, "GBP") {
+		t.Fatalf("Unexpected decoded price %+v %v", decoded.Price, err)
+	}
+	encoded, _ := json.Marshal(decoded)
+	if string(encoded) != `{"price":{"amount":"19.99","currency":"GBP"}}` {
+		t.Fatalf("Unexpected encoded price %s", encoded)
+	}
+	err = json.Unmarshal([]byte(`{"price": {"amount": "1", "currency": "XXX"}}`), &decoded)
+	if err == nil {
+		t.Fatal("Expected unknown currency to be rejected")
+	}
+}
+
+func writeExchangeRates(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	err := os.WriteFile(
+		path, []byte(`{"base": "EUR", "rates": {"USD": "1.0845", "JPY": "162.3"}}`), 0644,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExchangeRates(t *testing.T) {
+	rates, err := exchange.Load(writeExchangeRates(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		from     types.Money
+		to       types.Currency
+		expected types.Money
+	}{
+		{types.NewMoney(10000, "EUR"), "USD", types.NewMoney(10845, "USD")},
+		{types.NewMoney(10845, "USD"), "EUR", types.NewMoney(10000, "EUR")},
+		{types.NewMoney(1000, "USD"), "JPY", types.NewMoney(1497, "JPY")},
+		{types.NewMoney(-1, "EUR"), "USD", types.NewMoney(-1, "USD")},
+	} {
+		converted, err := rates.Convert(test.from, test.to)
+		if err != nil || converted != test.expected {
+			t.Fatalf("Expected %s in %s to be %s, got %s %v", test.from, test.to, test.expected, converted, err)
+		}
+	}
+	if _, err := rates.Convert(types.NewMoney(100, "EUR"), "GBP"); err == nil {
+		t.Fatal("Expected conversion without rate to fail")
+	}
+}
+
+func TestHotelCurrency(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(admin)
+	hotel, err := store.CT.Hotels.Create(ctx, &types.Hotel{Name: "Priced", Location: "Zurich"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hotel.Currency != controllers.DefaultCurrency() {
+		t.Fatalf("Expected default currency, got %q", hotel.Currency)
+	}
+	hotel, err = store.CT.Hotels.UpdateByID(ctx, hotel.ID, &types.Hotel{
+		Name: "Priced", Location: "Zurich", Currency: "CHF",
+	})
+	if err != nil || hotel.Currency != "CHF" {
+		t.Fatalf("Expected currency of empty hotel to change, got %+v %v", hotel, err)
+	}
+
+	// Room priced before currencies were introduced, in major units
+	result, err := store.DB.Rooms.Coll.InsertOne(ctx, bson.M{
+		"hotelID": hotel.ID, "type": types.SingleRoomType, "price": 1200.0, "version": 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DB.Migrate(ctx, "JPY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	room, err := store.CT.Rooms.GetUnfoldedByID(ctx, result.InsertedID.(primitive.ObjectID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.Price != types.NewMoney(1200, "JPY") {
+		t.Fatalf("Expected legacy price to be converted to yen, got %+v", room.Price)
+	}
+
+	_, err = store.CT.Hotels.UpdateByID(ctx, hotel.ID, &types.Hotel{
+		Name: "Priced", Location: "Zurich", Currency: "EUR",
+	})
+	requireConflict(t, err, controllers.CurrencyInUseErrorCode)
+	// Omitted currency is kept
+	hotel, err = store.CT.Hotels.UpdateByID(ctx, hotel.ID, &types.Hotel{
+		Name: "Priced Inn", Location: "Zurich",
+	})
+	if err != nil || hotel.Currency != "CHF" {
+		t.Fatalf("Expected currency to be kept, got %+v %v", hotel, err)
+	}
+
+	room.Price = types.NewMoney(10000, "CHF")
+	err = store.CT.Rooms.WithDisplayPrice(room, "USD")
+	if err == nil {
+		t.Fatal("Expected display price to require exchange rates")
+	}
+	store.ExchangeRates, err = exchange.Load(writeExchangeRates(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CT.Rooms.WithDisplayPrice(room, "USD")
+	if err == nil {
+		t.Fatal("Expected display price to require rate of hotel's currency")
+	}
+	room.Price = types.NewMoney(10000, "EUR")
+	err = store.CT.Rooms.WithDisplayPrice(room, "USD")
+	if err != nil || *room.DisplayPrice != types.NewMoney(10845, "USD") {
+		t.Fatalf("Unexpected display price %v %v", room.DisplayPrice, err)
+	}
+}
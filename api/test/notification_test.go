@@ -0,0 +1,192 @@
+package apiTest
+
+import (
+	"context"
+	"errors"
+	"hotel/controllers"
+	"hotel/notifications"
+	"hotel/types"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+type recordingChannel struct {
+	name string
+	err  error
+	sent []*notifications.Message
+	mu   sync.Mutex
+}
+
+func (self *recordingChannel) Name() string {
+	return self.name
+}
+
+func (self *recordingChannel) Address(user *types.User) string {
+	if self.name == "sms" {
+		return user.Phone
+	}
+	return user.Email
+}
+
+func (self *recordingChannel) Send(ctx context.Context, msg *notifications.Message) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.sent = append(self.sent, msg)
+	return self.err
+}
+
+func TestNotificationTemplates(t *testing.T) {
+	data := &notifications.BookingData{
+		GuestName: "Anna", HotelName: "Seaside", RoomType: types.DoubleRoomType,
+		DateFrom: civil.Date{Year: 2030, Month: 5, Day: 1},
+		DateTo:   civil.Date{Year: 2030, Month: 5, Day: 3},
+		Nights:   2, TotalCost: types.NewMoney(25000, "EUR"),
+	}
+	kinds := []types.NotificationKind{
+		types.BookingConfirmedNotificationKind, types.BookingModifiedNotificationKind,
+		types.BookingCancelledNotificationKind, types.BookingReminderNotificationKind,
+		types.BookingReceiptNotificationKind,
+	}
+	for _, locale := range []types.Locale{types.EnglishLocale, types.GermanLocale} {
+		for _, kind := range kinds {
+			for _, channel := range []string{"email", "sms", "log"} {
+				subject, body, err := notifications.DefaultTemplates.Render(kind, locale, channel, data)
+				if err != nil {
+					t.Fatalf("Failed to render %s %s for %s: %s", locale, kind, channel, err)
+				}
+				if !strings.Contains(subject, "Seaside") || !strings.Contains(body, "Seaside") {
+					t.Fatalf("Unexpected %s %s for %s: %q %q", locale, kind, channel, subject, body)
+				}
+			}
+		}
+	}
+	subject, _, err := notifications.DefaultTemplates.Render(
+		types.BookingConfirmedNotificationKind, types.Locale("fr"), "email", data,
+	)
+	if err != nil || !strings.Contains(subject, "confirmed") {
+		t.Fatalf("Expected fallback to default locale, got %q %v", subject, err)
+	}
+	_, body, _ := notifications.DefaultTemplates.Render(
+		types.BookingReceiptNotificationKind, types.GermanLocale, "email", data,
+	)
+	if !strings.Contains(body, "Bezahlt: 250.00") {
+		t.Fatalf("Expected German receipt, got %q", body)
+	}
+}
+
+func TestBookingNotifications(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	email := &recordingChannel{name: "email"}
+	sms := &recordingChannel{name: "sms", err: errors.New("provider is down")}
+	store.Notifier.Channels = []notifications.Channel{email, sms}
+
+	guest, err := createTestUser(store, "notified@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(guest)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Notifying", Location: "Vienna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Notifications are sent in background
+	store.Notifier.Wait()
+	err = store.CT.Bookings.DeleteByID(ctx, booking.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.Notifier.Wait()
+	if len(email.sent) != 2 || !strings.Contains(email.sent[0].Subject, "Notifying") {
+		t.Fatalf("Expected confirmation and cancellation emails, got %+v", email.sent)
+	}
+	// Guest has no phone
+	if len(sms.sent) != 0 {
+		t.Fatalf("Expected no SMS, got %+v", sms.sent)
+	}
+	logged, err := store.CT.Notifications.Get(ctx, &controllers.NotificationGetQueryParams{
+		BookingID: booking.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logged) != 4 || logged[0].Kind != types.BookingCancelledNotificationKind {
+		t.Fatalf("Expected 4 notifications, cancellation first, got %+v", logged)
+	}
+	for _, notification := range logged {
+		expected := types.SentNotificationStatus
+		if notification.Channel == "sms" {
+			expected = types.SkippedNotificationStatus
+		}
+		if notification.Status != expected {
+			t.Fatalf("Expected %s via %s, got %s", expected, notification.Channel, notification.Status)
+		}
+	}
+}
+
+func TestUndeliveredReminders(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	sms := &recordingChannel{name: "sms"}
+	store.Notifier.Channels = []notifications.Channel{sms}
+
+	guest, err := createTestUser(store, "unreachable@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(guest)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Reminding", Location: "Graz"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Notifier.Wait()
+
+	// Guest has no phone, so reminder isn't delivered
+	sent, err := store.CT.Bookings.SendReminders(context.Background(), time.Now(), 2)
+	if !errors.Is(err, notifications.ErrNotDelivered) || sent != 0 {
+		t.Fatalf("Expected undelivered reminder, got %d %v", sent, err)
+	}
+	released, err := store.CT.Bookings.GetByID(ctx, booking.ID)
+	if err != nil || released.ReminderSentAt != nil {
+		t.Fatalf("Expected reminder to stay unsent, got %+v %v", released, err)
+	}
+
+	store.Notifier.Channels = []notifications.Channel{&recordingChannel{name: "email"}}
+	sent, err = store.CT.Bookings.SendReminders(context.Background(), time.Now(), 2)
+	if err != nil || sent != 1 {
+		t.Fatalf("Expected reminder by email, got %d %v", sent, err)
+	}
+}
@@ -0,0 +1,72 @@
+package apiTest
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"hotel/api"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var routeMethods = map[string]bool{
+	"Get": true, "Post": true, "Put": true, "Patch": true, "Delete": true,
+}
+
+// Collects routes registered on apiv1 group in main.go
+func registeredRoutes(t *testing.T) map[string]bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "../../main.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := map[string]bool{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !routeMethods[selector.Sel.Name] {
+			return true
+		}
+		group, ok := selector.X.(*ast.Ident)
+		if !ok || group.Name != "apiv1" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		path, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		openAPIPath, _ := api.OpenAPIPath(path)
+		routes[strings.ToLower(selector.Sel.Name)+" "+openAPIPath] = true
+		return true
+	})
+	return routes
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	doc := api.GetOpenAPIDocument()
+	routes := registeredRoutes(t)
+	if len(routes) == 0 {
+		t.Fatal("No routes found in main.go")
+	}
+
+	for route := range routes {
+		parts := strings.SplitN(route, " ", 2)
+		if _, ok := doc.Paths[parts[1]][parts[0]]; !ok {
+			t.Errorf("Route %s is missing in OpenAPI spec", route)
+		}
+	}
+	for path, operations := range doc.Paths {
+		for method := range operations {
+			if !routes[method+" "+path] {
+				t.Errorf("OpenAPI spec describes unregistered route %s %s", method, path)
+			}
+		}
+	}
+}
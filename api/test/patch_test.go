@@ -0,0 +1,127 @@
+package apiTest
+
+import (
+	"context"
+	"encoding/json"
+	"hotel/api"
+	"hotel/controllers"
+	"hotel/types"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7386, appendix A
+	cases := []struct{ target, patch, result string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, c := range cases {
+		result, err := api.MergePatch([]byte(c.target), []byte(c.patch))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != c.result {
+			t.Fatalf(
+				"Patch %s on %s: expected %s, got %s", c.patch, c.target, c.result, result,
+			)
+		}
+	}
+}
+
+func sendPatchRequest(
+	app *fiber.App, path string, contentType string, body string, version int64,
+) (*http.Response, error) {
+	req := httptest.NewRequest("PATCH", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("If-Match", api.ETag(version))
+	return app.Test(req)
+}
+
+func TestPatchUser(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	user, err := createTestUser(store, "patched@gmail.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	userHandler := api.NewUserHandler(
+		&controllers.UserController{Store: store},
+	)
+	app.Patch("/user/:id", userHandler.HandlePatchUser)
+	path := "/user/" + user.ID.Hex()
+
+	resp, err := sendPatchRequest(
+		app, path, api.MIMEMergePatchJSON, `{"firstName": "Patched"}`, user.Version,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect patch status %d", resp.StatusCode)
+	}
+	var patched *types.User
+	err = json.NewDecoder(resp.Body).Decode(&patched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patched.FirstName != "Patched" {
+		t.Fatalf("First name wasn't patched")
+	}
+	if patched.LastName != user.LastName || patched.Email != user.Email {
+		t.Fatalf("Fields missing in patch were changed")
+	}
+
+	actual, err := store.CT.Users.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !actual.IsVerified || actual.EncryptedPassword != user.EncryptedPassword {
+		t.Fatalf("Patch wiped fields which aren't part of params")
+	}
+
+	// Removing required field fails validation of merged result
+	resp, err = sendPatchRequest(
+		app, path, api.MIMEMergePatchJSON, `{"lastName": null}`, patched.Version,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body api.ErrorResponse
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest || len(body.Error.Fields["lastName"]) == 0 {
+		t.Fatalf("Required field was removed by patch")
+	}
+
+	resp, err = sendPatchRequest(
+		app, path, "text/plain", `{"firstName": "Text"}`, patched.Version,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnsupportedMediaType {
+		t.Fatalf("Incorrect status %d for unsupported content type", resp.StatusCode)
+	}
+}
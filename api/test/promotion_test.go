@@ -0,0 +1,164 @@
+package apiTest
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestPromotionDiscount(t *testing.T) {
+	roomCost := types.NewMoney(20000, "EUR")
+	percent := &types.Promotion{DiscountKind: types.PercentDiscountKind, Rate: 1250}
+	if discount := controllers.PromotionDiscount(percent, roomCost); discount != types.NewMoney(2500, "EUR") {
+		t.Fatalf("Expected 12.5%% discount, got %s", discount)
+	}
+	fixed := &types.Promotion{DiscountKind: types.FixedDiscountKind, Amount: types.NewMoney(30000, "EUR")}
+	if discount := controllers.PromotionDiscount(fixed, roomCost); discount != roomCost {
+		t.Fatalf("Expected discount up to room cost, got %s", discount)
+	}
+	fixed.Amount = types.NewMoney(3000, "USD")
+	if discount := controllers.PromotionDiscount(fixed, roomCost); !discount.IsZero() {
+		t.Fatalf("Expected no discount in other currency, got %s", discount)
+	}
+
+	today := civil.DateOf(time.Now())
+	booking := &types.BookingUnfolded{
+		Booking: &types.Booking{DateFrom: today.AddDays(10), DateTo: today.AddDays(12)},
+		Room:    &types.Room{Type: types.DoubleRoomType, Price: types.NewMoney(10000, "EUR")},
+	}
+	percent.StayFrom, percent.StayTo = today.AddDays(10), today.AddDays(11)
+	if mismatch := controllers.PromotionMismatch(percent, booking); len(mismatch) != 0 {
+		t.Fatalf("Expected stay within window to qualify, got %q", mismatch)
+	}
+	percent.MinNights = 3
+	if len(controllers.PromotionMismatch(percent, booking)) == 0 {
+		t.Fatal("Expected short stay not to qualify")
+	}
+	percent.MinNights = 0
+	percent.RoomTypes = []types.RoomType{types.SingleRoomType}
+	if len(controllers.PromotionMismatch(percent, booking)) == 0 {
+		t.Fatal("Expected other room type not to qualify")
+	}
+}
+
+func TestPromotions(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "promo-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	guest, err := createTestUser(store, "promo-guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guestCtx := contextWithUser(guest)
+	other, err := createTestUser(store, "promo-other@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCtx := contextWithUser(other)
+
+	hotel, err := store.CT.Hotels.Create(adminCtx, &types.Hotel{Name: "Promo", Location: "Rome", Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(adminCtx, &types.Room{
+		HotelID: hotel.ID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Promotions.Create(guestCtx, &types.Promotion{
+		Name: "Mine", DiscountKind: types.PercentDiscountKind, Rate: 10000,
+	})
+	if err == nil {
+		t.Fatal("Expected guest not to create promotions")
+	}
+	automatic, err := store.CT.Promotions.Create(adminCtx, &types.Promotion{
+		Name: "Autumn", DiscountKind: types.PercentDiscountKind, Rate: 1000,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	coded, err := store.CT.Promotions.Create(adminCtx, &types.Promotion{
+		Code: "WELCOME", Name: "Welcome", DiscountKind: types.FixedDiscountKind,
+		Amount: types.NewMoney(5000, "EUR"), MaxRedemptions: 1,
+		HotelIDs: []primitive.ObjectID{hotel.ID},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Promotions.Create(adminCtx, &types.Promotion{
+		Code: "WELCOME", Name: "Duplicate", DiscountKind: types.PercentDiscountKind, Rate: 500,
+	})
+	if err == nil {
+		t.Fatal("Expected duplicate code to be rejected")
+	}
+	// Index keeps codes unique, even if check is bypassed
+	_, err = store.DB.Promotions.Create(adminCtx, &types.Promotion{
+		Code: "WELCOME", Name: "Bypassed", DiscountKind: types.PercentDiscountKind, Rate: 500,
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("Expected duplicate code to be rejected by index, got %v", err)
+	}
+
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.Discount == nil || booking.Discount.PromotionID != automatic.ID ||
+		booking.TotalCost != types.NewMoney(18000, "EUR") {
+		t.Fatalf("Expected automatic discount, got %+v", booking.Booking)
+	}
+
+	coupon, err := store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(5), DateTo: today.AddDays(7), PromoCode: "welcome",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coupon.Discount == nil || coupon.Discount.Code != "WELCOME" ||
+		coupon.TotalCost != types.NewMoney(15000, "EUR") {
+		t.Fatalf("Expected code discount, got %+v", coupon.Booking)
+	}
+
+	_, err = store.CT.Bookings.Quote(otherCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(8), DateTo: today.AddDays(9), PromoCode: "WELCOME",
+	})
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected used up code to be rejected, got %v", err)
+	}
+
+	// Cancelled booking gives its redemption back
+	err = store.CT.Bookings.DeleteByID(guestCtx, coupon.ID, coupon.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	promotion, err := store.CT.Promotions.GetByID(adminCtx, coded.ID)
+	if err != nil || promotion.Redemptions != 0 {
+		t.Fatalf("Expected redemption to be released, got %+v %v", promotion, err)
+	}
+	_, err = store.CT.Bookings.Create(otherCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(8), DateTo: today.AddDays(9), PromoCode: "WELCOME",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Bookings.RestoreByID(guestCtx, coupon.ID)
+	if codedErr, ok := err.(controllers.ConflictError); !ok ||
+		codedErr.Code != controllers.PromotionExhaustedErrorCode {
+		t.Fatalf("Expected restore to conflict on used up promotion, got %v", err)
+	}
+}
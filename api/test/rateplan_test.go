@@ -0,0 +1,245 @@
+package apiTest
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestRatePlanRoomCost(t *testing.T) {
+	price := types.NewMoney(10000, "EUR")
+	if cost := controllers.RatePlanRoomCost(nil, price, 3, 2); cost != types.NewMoney(30000, "EUR") {
+		t.Fatalf("Expected plain room price without plan, got %s", cost)
+	}
+	ratePlan := &types.RatePlan{
+		PriceAdjustment:      -1500,
+		GuestNightSupplement: types.NewMoney(1200, "EUR"),
+	}
+	// (85.00 + 2 * 12.00) * 3
+	if cost := controllers.RatePlanRoomCost(ratePlan, price, 3, 2); cost != types.NewMoney(32700, "EUR") {
+		t.Fatalf("Expected adjusted price with supplements, got %s", cost)
+	}
+
+	today := civil.DateOf(time.Now())
+	ratePlan = &types.RatePlan{
+		MinNights: 2, MaxNights: 5, AdvancePurchaseDays: 14,
+		RoomTypes: []types.RoomType{types.DoubleRoomType},
+	}
+	from := today.AddDays(20)
+	if mismatch := controllers.RatePlanMismatch(
+		ratePlan, types.DoubleRoomType, from, from.AddDays(3), today, true,
+	); len(mismatch) != 0 {
+		t.Fatalf("Expected stay to match plan, got %q", mismatch)
+	}
+	if len(controllers.RatePlanMismatch(ratePlan, types.SingleRoomType, from, from.AddDays(3), today, true)) == 0 {
+		t.Fatal("Expected other room type not to match")
+	}
+	if len(controllers.RatePlanMismatch(ratePlan, types.DoubleRoomType, from, from.AddDays(1), today, true)) == 0 {
+		t.Fatal("Expected short stay not to match")
+	}
+	if len(controllers.RatePlanMismatch(ratePlan, types.DoubleRoomType, from, from.AddDays(6), today, true)) == 0 {
+		t.Fatal("Expected long stay not to match")
+	}
+	late := today.AddDays(3)
+	if len(controllers.RatePlanMismatch(ratePlan, types.DoubleRoomType, late, late.AddDays(3), today, true)) == 0 {
+		t.Fatal("Expected late purchase not to match")
+	}
+	if mismatch := controllers.RatePlanMismatch(
+		ratePlan, types.DoubleRoomType, late, late.AddDays(3), today, false,
+	); len(mismatch) != 0 {
+		t.Fatalf("Expected advance purchase to be skipped, got %q", mismatch)
+	}
+}
+
+func TestFreeCancellationAllowed(t *testing.T) {
+	today := civil.DateOf(time.Now())
+	if !controllers.FreeCancellationAllowed(nil, today, today) {
+		t.Fatal("Expected booking without plan to be cancellable")
+	}
+	flexible := &types.BookingRatePlan{
+		Cancellation: types.CancellationPolicy{Refundable: true, FreeCancellationDays: 2},
+	}
+	if !controllers.FreeCancellationAllowed(flexible, today.AddDays(2), today) {
+		t.Fatal("Expected cancellation on deadline to be free")
+	}
+	if controllers.FreeCancellationAllowed(flexible, today.AddDays(1), today) {
+		t.Fatal("Expected cancellation after deadline not to be free")
+	}
+	nonRefundable := &types.BookingRatePlan{Kind: types.NonRefundableRatePlanKind}
+	if controllers.FreeCancellationAllowed(nonRefundable, today.AddDays(30), today) {
+		t.Fatal("Expected non-refundable booking not to be cancellable")
+	}
+}
+
+func TestRatePlans(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "rate-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	guest, err := createTestUser(store, "rate-guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guestCtx := contextWithUser(guest)
+
+	hotel, err := store.CT.Hotels.Create(adminCtx, &types.Hotel{Name: "Rates", Location: "Lyon", Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(adminCtx, &types.Room{
+		HotelID: hotel.ID, Type: types.DoubleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := civil.DateOf(time.Now())
+	search := &controllers.AvailabilitySearchQueryParams{
+		DateFrom: today.AddDays(1), DateTo: today.AddDays(3), Guests: 2,
+	}
+	availability, err := store.CT.Availability.Search(guestCtx, hotel.ID, search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 1 || len(availability[0].Options) != 1 ||
+		availability[0].Options[0].RoomCost != types.NewMoney(20000, "EUR") {
+		t.Fatalf("Expected standard rate without plans, got %+v", availability)
+	}
+
+	_, err = store.CT.RatePlans.Create(adminCtx, &types.RatePlan{
+		HotelID: hotel.ID, Name: "Broken", Kind: types.NonRefundableRatePlanKind,
+		Cancellation: types.CancellationPolicy{Refundable: true},
+	})
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected refundable non-refundable plan to be rejected, got %v", err)
+	}
+	flexible, err := store.CT.RatePlans.Create(adminCtx, &types.RatePlan{
+		HotelID: hotel.ID, Name: "Flexible", Kind: types.FlexibleRatePlanKind,
+		Cancellation: types.CancellationPolicy{Refundable: true, FreeCancellationDays: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	breakfast, err := store.CT.RatePlans.Create(adminCtx, &types.RatePlan{
+		HotelID: hotel.ID, Name: "Bed and breakfast", Kind: types.BreakfastIncludedRatePlanKind,
+		GuestNightSupplement: types.NewMoney(1500, "EUR"), Inclusions: []string{"breakfast"},
+		Cancellation: types.CancellationPolicy{Refundable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Supplement ties hotel to its currency, even before it has rooms
+	unfurnished, err := store.CT.Hotels.Create(adminCtx, &types.Hotel{
+		Name: "Unfurnished", Location: "Rome", Currency: "EUR",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.RatePlans.Create(adminCtx, &types.RatePlan{
+		HotelID: unfurnished.ID, Name: "Half board", Kind: types.BreakfastIncludedRatePlanKind,
+		GuestNightSupplement: types.NewMoney(3000, "EUR"),
+		Cancellation:         types.CancellationPolicy{Refundable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Hotels.UpdateByID(adminCtx, unfurnished.ID, &types.Hotel{
+		Name: "Unfurnished", Location: "Rome", Currency: "USD",
+	})
+	requireConflict(t, err, controllers.CurrencyInUseErrorCode)
+
+	gold, err := store.CT.RatePlans.Create(adminCtx, &types.RatePlan{
+		HotelID: hotel.ID, Name: "Gold members", Kind: types.MemberOnlyRatePlanKind,
+		PriceAdjustment: -2000, MinTier: types.GoldLoyaltyTier,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	availability, err = store.CT.Availability.Search(guestCtx, hotel.ID, search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 1 || len(availability[0].Options) != 2 {
+		t.Fatalf("Expected flexible and breakfast options only, got %+v", availability)
+	}
+	for _, option := range availability[0].Options {
+		if option.RatePlanID == breakfast.ID && option.RoomCost != types.NewMoney(26000, "EUR") {
+			t.Fatalf("Expected breakfast supplement per guest, got %s", option.RoomCost)
+		}
+	}
+
+	_, err = store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	})
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected booking without plan to be rejected, got %v", err)
+	}
+	booking, err := store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3), RatePlanID: flexible.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.RatePlan == nil || booking.RatePlan.Name != "Flexible" ||
+		booking.RatePlan.Cancellation.FreeCancellationDays != 2 {
+		t.Fatalf("Expected booking to record rate plan, got %+v", booking.Booking)
+	}
+
+	availability, err = store.CT.Availability.Search(guestCtx, hotel.ID, search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 0 {
+		t.Fatalf("Expected booked room not to be offered, got %+v", availability)
+	}
+
+	// Other guests can't change or cancel the booking
+	other, err := createTestUser(store, "rate-other@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCtx := contextWithUser(other)
+	_, err = store.CT.Bookings.UpdateByID(otherCtx, booking.ID, booking.Booking)
+	if _, ok := err.(controllers.NotFoundError); !ok {
+		t.Fatalf("Expected booking of another guest not to be found, got %v", err)
+	}
+	err = store.CT.Bookings.DeleteByID(otherCtx, booking.ID, booking.Version)
+	if _, ok := err.(controllers.NotFoundError); !ok {
+		t.Fatalf("Expected booking of another guest not to be found, got %v", err)
+	}
+
+	// Arrival is within free cancellation days
+	err = store.CT.Bookings.DeleteByID(guestCtx, booking.ID, booking.Version)
+	requireConflict(t, err, controllers.CancellationNotAllowedCode)
+	err = store.CT.Bookings.DeleteByID(adminCtx, booking.ID, booking.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	}, 0)
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected hold without plan to be rejected, got %v", err)
+	}
+	_, err = store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3), RatePlanID: gold.ID,
+	}, 0)
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected hold with member only plan to be rejected, got %v", err)
+	}
+	_, err = store.CT.RatePlans.Create(guestCtx, &types.RatePlan{
+		HotelID: hotel.ID, Name: "Own", Kind: types.FlexibleRatePlanKind,
+	})
+	if _, ok := err.(controllers.ForbiddenError); !ok {
+		t.Fatalf("Expected guest not to create rate plans, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package apiTest
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestStayRestrictionErrors(t *testing.T) {
+	// Friday to Sunday of festival weekend
+	friday := civil.Date{Year: 2030, Month: time.June, Day: 7}
+	festival := &types.StayRestriction{
+		Kind: types.MinLOSRestrictionKind, DateFrom: friday, DateTo: friday.AddDays(2), Nights: 3,
+	}
+	noSundayArrivals := &types.StayRestriction{
+		Kind: types.ClosedToArrivalRestrictionKind, DateFrom: friday, DateTo: friday.AddDays(30),
+		Weekdays: []time.Weekday{time.Sunday},
+	}
+	restrictions := []*types.StayRestriction{festival, noSundayArrivals}
+
+	errors := controllers.StayRestrictionErrors(restrictions, types.SingleRoomType, friday, friday.AddDays(2))
+	if _, ok := errors["dateTo"]; !ok || len(errors) != 1 {
+		t.Fatalf("Expected short festival stay to be rejected, got %v", errors)
+	}
+	errors = controllers.StayRestrictionErrors(restrictions, types.SingleRoomType, friday, friday.AddDays(3))
+	if len(errors) != 0 {
+		t.Fatalf("Expected long enough stay to pass, got %v", errors)
+	}
+	sunday := friday.AddDays(2)
+	errors = controllers.StayRestrictionErrors(restrictions, types.SingleRoomType, sunday, sunday.AddDays(3))
+	if _, ok := errors["dateFrom"]; !ok {
+		t.Fatalf("Expected Sunday arrival to be rejected, got %v", errors)
+	}
+	monday := friday.AddDays(3)
+	errors = controllers.StayRestrictionErrors(restrictions, types.SingleRoomType, monday, monday.AddDays(1))
+	if len(errors) != 0 {
+		t.Fatalf("Expected Monday arrival to pass, got %v", errors)
+	}
+
+	stopSell := &types.StayRestriction{
+		Kind: types.StopSellRestrictionKind, RoomType: types.DeluxeRoomType,
+		DateFrom: friday.AddDays(10), DateTo: friday.AddDays(10),
+	}
+	noDepartures := &types.StayRestriction{
+		Kind: types.ClosedToDepartureRestrictionKind, DateFrom: friday.AddDays(10), DateTo: friday.AddDays(10),
+	}
+	restrictions = []*types.StayRestriction{stopSell, noDepartures}
+	errors = controllers.StayRestrictionErrors(restrictions, types.DeluxeRoomType, friday.AddDays(8), friday.AddDays(11))
+	if _, ok := errors["roomID"]; !ok || len(errors) != 1 {
+		t.Fatalf("Expected stop-sell night to be rejected, got %v", errors)
+	}
+	errors = controllers.StayRestrictionErrors(restrictions, types.SingleRoomType, friday.AddDays(8), friday.AddDays(11))
+	if len(errors) != 0 {
+		t.Fatalf("Expected stop-sell of other room type not to apply, got %v", errors)
+	}
+	// Departure day isn't a night sold
+	errors = controllers.StayRestrictionErrors(restrictions, types.DeluxeRoomType, friday.AddDays(8), friday.AddDays(10))
+	if _, ok := errors["roomID"]; ok || len(errors["dateTo"]) == 0 {
+		t.Fatalf("Expected closed departure only, got %v", errors)
+	}
+}
+
+func TestStayRestrictions(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "rules-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	guest, err := createTestUser(store, "rules-guest@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guestCtx := contextWithUser(guest)
+
+	hotel, err := store.CT.Hotels.Create(adminCtx, &types.Hotel{Name: "Rules", Location: "Porto", Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(adminCtx, &types.Room{
+		HotelID: hotel.ID, Type: types.DoubleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := civil.DateOf(time.Now())
+	_, err = store.CT.Restrictions.Create(adminCtx, &types.StayRestriction{
+		HotelID: hotel.ID, Kind: types.MinLOSRestrictionKind, DateFrom: today, DateTo: today.AddDays(30),
+	})
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected min LOS without nights to be rejected, got %v", err)
+	}
+	_, err = store.CT.Restrictions.Create(guestCtx, &types.StayRestriction{
+		HotelID: hotel.ID, Kind: types.StopSellRestrictionKind, DateFrom: today, DateTo: today,
+	})
+	if _, ok := err.(controllers.ForbiddenError); !ok {
+		t.Fatalf("Expected guest not to create restrictions, got %v", err)
+	}
+	minLOS, err := store.CT.Restrictions.Create(adminCtx, &types.StayRestriction{
+		HotelID: hotel.ID, RoomType: types.DoubleRoomType, Kind: types.MinLOSRestrictionKind,
+		DateFrom: today, DateTo: today.AddDays(30), Nights: 3,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(2),
+	})
+	validationErr, ok := err.(controllers.ValidationError)
+	if !ok || len(validationErr.Fields["dateTo"]) == 0 {
+		t.Fatalf("Expected short stay to be rejected on date to, got %v", err)
+	}
+	search := &controllers.AvailabilitySearchQueryParams{DateFrom: today.AddDays(1), DateTo: today.AddDays(2)}
+	availability, err := store.CT.Availability.Search(guestCtx, hotel.ID, search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 0 {
+		t.Fatalf("Expected restricted room not to be offered, got %+v", availability)
+	}
+	search.DateTo = today.AddDays(4)
+	availability, err = store.CT.Availability.Search(guestCtx, hotel.ID, search)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 1 {
+		t.Fatalf("Expected long enough stay to be offered, got %+v", availability)
+	}
+
+	booking, err := store.CT.Bookings.Create(guestCtx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(4),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Restrictions.Create(adminCtx, &types.StayRestriction{
+		HotelID: hotel.ID, Kind: types.StopSellRestrictionKind,
+		DateFrom: today.AddDays(2), DateTo: today.AddDays(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rules added later don't block changes keeping the stay
+	updated, err := store.CT.Bookings.UpdateByID(guestCtx, booking.ID, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(4), Guests: 2,
+		Version: booking.Version,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Bookings.UpdateByID(guestCtx, booking.ID, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(5), Guests: 2,
+		Version: updated.Version,
+	})
+	validationErr, ok = err.(controllers.ValidationError)
+	if !ok || len(validationErr.Fields["roomID"]) == 0 {
+		t.Fatalf("Expected changed stay over stop-sell to be rejected, got %v", err)
+	}
+
+	err = store.CT.Restrictions.DeleteByID(adminCtx, minLOS.ID, minLOS.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Holds.Create(guestCtx, &types.Hold{
+		RoomID: roomID, DateFrom: today.AddDays(10), DateTo: today.AddDays(11),
+	}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
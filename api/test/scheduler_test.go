@@ -0,0 +1,111 @@
+package apiTest
+
+import (
+	"context"
+	"hotel/scheduler"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func TestSchedulerLeaseAndSlots(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	runs := 0
+	jobs := []*scheduler.Job{{
+		Name:     "count",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, at time.Time) (int64, error) {
+			runs++
+			return 1, nil
+		},
+	}}
+	leader := scheduler.NewScheduler(store.DB, jobs)
+	standby := scheduler.NewScheduler(store.DB, jobs)
+	ctx := context.Background()
+	now := time.Now()
+
+	ran, err := leader.Tick(ctx, now)
+	if err != nil || ran != 1 {
+		t.Fatalf("Expected leader to run job, got %d %v", ran, err)
+	}
+	ran, err = standby.Tick(ctx, now.Add(10*time.Second))
+	if err != nil || ran != 0 {
+		t.Fatalf("Expected standby to wait for lease, got %d %v", ran, err)
+	}
+	// Slot is already taken
+	ran, err = leader.Tick(ctx, now.Add(time.Minute))
+	if err != nil || ran != 0 {
+		t.Fatalf("Expected no run before next slot, got %d %v", ran, err)
+	}
+	// Leader died, lease expired
+	ran, err = standby.Tick(ctx, now.Add(leader.LeaseTTL+time.Hour))
+	if err != nil || ran != 1 || runs != 2 {
+		t.Fatalf("Expected standby to take over, got %d %v", ran, err)
+	}
+
+	admin, err := createTestUser(store, "admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobRuns, err := store.CT.Scheduler.GetRuns(contextWithUser(admin), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobRuns) != 2 || jobRuns[0].Status != types.SucceededJobRunStatus ||
+		jobRuns[0].Instance != standby.Instance {
+		t.Fatalf("Unexpected runs %+v", jobRuns)
+	}
+}
+
+func TestMarkNoShows(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	guest, err := createTestUser(store, "late@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(guest)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Empty", Location: "Riga"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today, DateTo: today.AddDays(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		marked, err := store.CT.Bookings.MarkNoShows(ctx, today.AddDays(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if marked != int64(1-i) {
+			t.Fatalf("Expected run %d to mark %d bookings, got %d", i, 1-i, marked)
+		}
+	}
+	marked, err := store.CT.Bookings.GetByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if marked.Status != types.NoShowBookingStatus {
+		t.Fatalf("Expected no-show, got %s", marked.Status)
+	}
+	_, err = store.CT.Bookings.CheckInByID(ctx, booking.ID)
+	if err == nil {
+		t.Fatal("Expected no-show not to be checked in")
+	}
+}
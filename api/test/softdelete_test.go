@@ -0,0 +1,97 @@
+package apiTest
+
+import (
+	"context"
+	"errors"
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func requireConflict(t *testing.T, err error, code controllers.ErrorCode) {
+	t.Helper()
+	var conflictErr controllers.ConflictError
+	if !errors.As(err, &conflictErr) || conflictErr.Code != code {
+		t.Fatalf("Expected %s conflict, got %v", code, err)
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	admin, err := createTestUser(store, "admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(admin)
+
+	hotel, err := store.CT.Hotels.Create(ctx, &types.Hotel{Name: "Archived", Location: "Rome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotel.ID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := civil.DateOf(time.Now())
+	bookingID, err := store.DB.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, UserID: admin.ID, Version: 1,
+		DateFrom: today.AddDays(1), DateTo: today.AddDays(3),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.CT.Rooms.DeleteByID(ctx, roomID, 0)
+	requireConflict(t, err, controllers.HasActiveBookingsErrorCode)
+	err = store.CT.Hotels.DeleteByID(ctx, hotel.ID, 0)
+	requireConflict(t, err, controllers.HasActiveBookingsErrorCode)
+	err = store.CT.Hotels.PurgeByID(ctx, hotel.ID)
+	requireConflict(t, err, controllers.NotDeletedErrorCode)
+
+	err = store.CT.Bookings.DeleteByID(ctx, bookingID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.CT.Hotels.DeleteByID(ctx, hotel.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	room, err := store.CT.Rooms.GetByID(context.Background(), roomID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room != nil {
+		t.Fatal("Room of deleted hotel wasn't archived")
+	}
+	deletedHotels, err := store.CT.Hotels.Get(ctx, &controllers.HotelGetQueryParams{Deleted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deletedHotels) != 1 {
+		t.Fatalf("Listed %d deleted hotels instead of 1", len(deletedHotels))
+	}
+	_, err = store.CT.Rooms.RestoreByID(ctx, roomID)
+	requireConflict(t, err, controllers.ParentDeletedErrorCode)
+
+	restored, err := store.CT.Hotels.RestoreByID(ctx, hotel.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored.Rooms) != 1 {
+		t.Fatalf("Hotel restored with %d rooms instead of 1", len(restored.Rooms))
+	}
+	booking, err := store.CT.Bookings.RestoreByID(ctx, bookingID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booking.DeletedAt != nil {
+		t.Fatal("Booking wasn't restored")
+	}
+}
@@ -0,0 +1,145 @@
+package apiTest
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+func taxRules(vatInclusive bool) []*types.TaxRule {
+	return []*types.TaxRule{
+		{Kind: types.VATTaxKind, Name: "VAT", Rate: 1000, Inclusive: vatInclusive},
+		{Kind: types.CityTaxKind, Name: "City tax", Amount: types.NewMoney(250, "EUR")},
+		{Kind: types.CleaningFeeTaxKind, Name: "Cleaning", Amount: types.NewMoney(3000, "EUR")},
+		{Kind: types.ResortFeeTaxKind, Name: "Resort", Amount: types.NewMoney(500, "EUR"), Inclusive: true},
+	}
+}
+
+func TestApplyTaxRules(t *testing.T) {
+	roomCost := types.NewMoney(20000, "EUR")
+	charges, total := controllers.ApplyTaxRules(taxRules(false), roomCost, 2, 2)
+	expected := map[types.TaxKind]int64{
+		types.CityTaxKind: 1000, types.CleaningFeeTaxKind: 3000,
+		types.ResortFeeTaxKind: 1000,
+		// 10% of room cost and cleaning, city tax and inclusive fee aside
+		types.VATTaxKind: 2300,
+	}
+	if len(charges) != 4 {
+		t.Fatalf("Expected 4 charges, got %d", len(charges))
+	}
+	for _, charge := range charges {
+		if charge.Amount.Minor != expected[charge.Kind] {
+			t.Fatalf("Expected %s to be %d, got %s", charge.Kind, expected[charge.Kind], charge.Amount)
+		}
+	}
+	if total != types.NewMoney(26300, "EUR") {
+		t.Fatalf("Unexpected total %s", total)
+	}
+
+	charges, total = controllers.ApplyTaxRules(taxRules(true), roomCost, 2, 2)
+	// 230 * 10 / 110, contained in price
+	if charges[3].Kind != types.VATTaxKind || charges[3].Amount.Minor != 2091 ||
+		total != types.NewMoney(24000, "EUR") {
+		t.Fatalf("Unexpected inclusive VAT %s, total %s", charges[3].Amount, total)
+	}
+
+	charges, total = controllers.ApplyTaxRules(nil, roomCost, 2, 2)
+	if len(charges) != 0 || total != roomCost {
+		t.Fatal("Expected no charges without rules")
+	}
+}
+
+func TestBookingTaxes(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	guest, err := createTestUser(store, "taxed@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(guest)
+	admin, err := createTestUser(store, "tax-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminCtx := contextWithUser(admin)
+	hotel, err := store.CT.Hotels.Create(ctx, &types.Hotel{Name: "Taxed", Location: "Berlin", Currency: "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotel.ID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Hotels.SetTaxRulesByID(ctx, hotel.ID, hotel.Version, taxRules(false))
+	if _, ok := err.(controllers.ForbiddenError); !ok {
+		t.Fatalf("Expected guest not to set tax rules, got %v", err)
+	}
+	_, err = store.CT.Hotels.SetTaxRulesByID(adminCtx, hotel.ID, hotel.Version, []*types.TaxRule{
+		{Kind: types.CityTaxKind, Amount: types.NewMoney(250, "USD")},
+	})
+	if err == nil {
+		t.Fatal("Expected fee in other currency to be rejected")
+	}
+	_, err = store.CT.Hotels.SetTaxRulesByID(adminCtx, hotel.ID, hotel.Version, []*types.TaxRule{
+		{Kind: types.VATTaxKind, Rate: 700}, {Kind: types.VATTaxKind, Rate: 1900},
+	})
+	if err == nil {
+		t.Fatal("Expected second VAT rule to be rejected")
+	}
+	updated, err := store.CT.Hotels.SetTaxRulesByID(adminCtx, hotel.ID, hotel.Version, taxRules(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.CT.Hotels.UpdateByID(ctx, hotel.ID, &types.Hotel{
+		Name: "Taxed", Location: "Berlin",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hotelAfter, err := store.CT.Hotels.GetByID(ctx, hotel.ID)
+	if err != nil || len(hotelAfter.TaxRules) != 4 || hotelAfter.Version != updated.Version+1 {
+		t.Fatalf("Expected hotel update to keep tax rules, got %+v %v", hotelAfter, err)
+	}
+
+	today := civil.DateOf(time.Now())
+	booking := &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(3), Guests: 2,
+	}
+	quote, err := store.CT.Bookings.Quote(ctx, booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.CT.Bookings.Create(ctx, booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.TotalCost != types.NewMoney(26300, "EUR") || created.TotalCost != quote.TotalCost ||
+		created.RoomCost != types.NewMoney(20000, "EUR") || len(created.Charges) != 4 {
+		t.Fatalf("Unexpected costs: quote %s, booking %+v", quote.TotalCost, created.Booking)
+	}
+
+	// Agreed charges stay until dates change, even when rules do
+	_, err = store.CT.Hotels.SetTaxRulesByID(adminCtx, hotel.ID, 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed, err := store.CT.Bookings.UpdateByID(ctx, created.ID, &types.Booking{
+		RoomID: roomID, DateFrom: created.DateFrom, DateTo: created.DateTo, Guests: 2,
+	})
+	if err != nil || changed.TotalCost != created.TotalCost {
+		t.Fatalf("Expected agreed cost to stay, got %+v %v", changed, err)
+	}
+	changed, err = store.CT.Bookings.UpdateByID(ctx, created.ID, &types.Booking{
+		RoomID: roomID, DateFrom: created.DateFrom, DateTo: created.DateTo.AddDays(1), Guests: 2,
+	})
+	if err != nil || changed.TotalCost != types.NewMoney(30000, "EUR") || len(changed.Charges) != 0 {
+		t.Fatalf("Expected cost without taxes, got %+v %v", changed, err)
+	}
+}
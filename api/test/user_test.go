@@ -3,20 +3,27 @@ package apiTest
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"hotel/api"
 	"hotel/controllers"
+	"hotel/lockout"
+	"hotel/mailer"
+	"hotel/totp"
 	"hotel/types"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestCreateUser(t *testing.T) {
 	store := setupCTStore()
 	defer teardown()
 
-	app := fiber.New()
+	app := newTestApp()
 	userHandler := api.NewUserHandler(
 		&controllers.UserController{Store: store},
 	)
@@ -94,7 +101,7 @@ func TestLoginUser(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	app := fiber.New()
+	app := newTestApp()
 	userHandler := api.NewUserHandler(userController)
 	app.Post("/", userHandler.HandleLogin)
 
@@ -109,7 +116,7 @@ func TestLoginUser(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if resp.StatusCode != fiber.StatusBadRequest {
+	if resp.StatusCode != fiber.StatusUnauthorized {
 		t.Fatalf("Incorrect login status")
 	}
 
@@ -124,7 +131,7 @@ func TestLoginUser(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if resp.StatusCode != fiber.StatusBadRequest {
+	if resp.StatusCode != fiber.StatusUnauthorized {
 		t.Fatalf("Incorrect login status")
 	}
 
@@ -155,3 +162,500 @@ func TestLoginUser(t *testing.T) {
 		t.Fatalf("Invalid user email")
 	}
 }
+
+func TestRegisterUser(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	app := newTestApp()
+	userHandler := api.NewUserHandler(
+		&controllers.UserController{Store: store},
+	)
+	app.Post("/register", userHandler.HandleRegister)
+	app.Post("/verify-email", userHandler.HandleVerifyEmail)
+	app.Post("/login", userHandler.HandleLogin)
+
+	params := types.CreateUserParams{
+		BaseUserParams: types.BaseUserParams{
+			Email:     "newguest@mail.ru",
+			FirstName: "New",
+			LastName:  "Guest",
+		},
+		Password: "12345678",
+	}
+	loginParams := types.LoginUserParams{
+		Email:    params.Email,
+		Password: params.Password,
+	}
+
+	resp, err := sendStructJSONRequest(app, "POST", "/register", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Incorrect register status %d", resp.StatusCode)
+	}
+
+	// Same email can't be registered twice
+	resp, err = sendStructJSONRequest(app, "POST", "/register", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("Duplicate email was registered")
+	}
+
+	// Email isn't verified yet
+	resp, err = sendStructJSONRequest(app, "POST", "/login", loginParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Fatalf("Unverified user logged in")
+	}
+
+	token, err := readLastMailToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = sendStructJSONRequest(
+		app, "POST", "/verify-email", types.VerifyEmailParams{Token: token},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect verify status %d", resp.StatusCode)
+	}
+
+	// Token is single-use
+	resp, err = sendStructJSONRequest(
+		app, "POST", "/verify-email", types.VerifyEmailParams{Token: token},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("Verification token was used twice")
+	}
+
+	resp, err = sendStructJSONRequest(app, "POST", "/login", loginParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect login status")
+	}
+}
+
+type failingMailer struct{}
+
+func (self failingMailer) Send(ctx context.Context, msg *mailer.Message) error {
+	return fmt.Errorf("Mail server is down")
+}
+
+func TestResendVerification(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	app := newTestApp()
+	userHandler := api.NewUserHandler(
+		&controllers.UserController{Store: store},
+	)
+	app.Post("/register", userHandler.HandleRegister)
+	app.Post("/resend-verification", userHandler.HandleResendVerification)
+	app.Post("/verify-email", userHandler.HandleVerifyEmail)
+
+	params := types.CreateUserParams{
+		BaseUserParams: types.BaseUserParams{
+			Email:     "nomail@mail.ru",
+			FirstName: "No",
+			LastName:  "Mail",
+		},
+		Password: "12345678",
+	}
+
+	// Failed email doesn't fail sign up
+	store.Mailer = failingMailer{}
+	resp, err := sendStructJSONRequest(app, "POST", "/register", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Incorrect register status %d", resp.StatusCode)
+	}
+	store.Mailer = &mailer.FileMailer{Dir: testMailDir}
+
+	// Unknown email looks the same as a known one
+	for _, email := range []string{"unknown@mail.ru", params.Email} {
+		resp, err = sendStructJSONRequest(
+			app, "POST", "/resend-verification",
+			types.ResendVerificationParams{Email: email},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusAccepted {
+			t.Fatalf("Incorrect resend status %d for %s", resp.StatusCode, email)
+		}
+	}
+
+	token, err := readLastMailToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = sendStructJSONRequest(
+		app, "POST", "/verify-email", types.VerifyEmailParams{Token: token},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect verify status %d", resp.StatusCode)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	userEmail := "forgetful@gmail.com"
+	newPassword := "brandnewpassword"
+
+	userUnsaved, err := types.NewUserFromCreateParams(
+		types.CreateUserParams{
+			BaseUserParams: types.BaseUserParams{
+				Email:     userEmail,
+				FirstName: "Forget",
+				LastName:  "Ful",
+			},
+			Password: "12345678",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userController := &controllers.UserController{Store: store}
+	_, err = userController.Create(context.Background(), userUnsaved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	userHandler := api.NewUserHandler(userController)
+	app.Post("/forgot-password", userHandler.HandleForgotPassword)
+	app.Post("/reset-password", userHandler.HandleResetPassword)
+	app.Post("/login", userHandler.HandleLogin)
+
+	resp, err := sendStructJSONRequest(
+		app, "POST", "/forgot-password",
+		types.ForgotPasswordParams{Email: userEmail},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("Incorrect forgot password status %d", resp.StatusCode)
+	}
+
+	token, err := readLastMailToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resetParams := types.ResetPasswordParams{Token: token, Password: newPassword}
+
+	resp, err = sendStructJSONRequest(app, "POST", "/reset-password", resetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Incorrect reset password status %d", resp.StatusCode)
+	}
+
+	resp, err = sendStructJSONRequest(app, "POST", "/reset-password", resetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Fatalf("Reset token was used twice")
+	}
+
+	resp, err = sendStructJSONRequest(
+		app, "POST", "/login",
+		types.LoginUserParams{Email: userEmail, Password: newPassword},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Can't login with new password")
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	userEmail := "bruteforced@gmail.com"
+	userPassword := "12345678"
+
+	userUnsaved, err := types.NewUserFromCreateParams(
+		types.CreateUserParams{
+			BaseUserParams: types.BaseUserParams{
+				Email:     userEmail,
+				FirstName: "Brute",
+				LastName:  "Forced",
+			},
+			Password: userPassword,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userController := &controllers.UserController{Store: store}
+	user, err := userController.Create(context.Background(), userUnsaved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	userHandler := api.NewUserHandler(userController)
+	app.Post("/", userHandler.HandleLogin)
+
+	for i := 0; i <= lockout.AccountPolicy.FreeAttempts; i++ {
+		resp, err := sendStructJSONRequest(
+			app, "POST", "/",
+			types.LoginUserParams{Email: userEmail, Password: "wrongpassword"},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusUnauthorized {
+			t.Fatalf("Incorrect login status %d on attempt %d", resp.StatusCode, i)
+		}
+	}
+
+	// Correct password doesn't help while account is locked
+	resp, err := sendStructJSONRequest(
+		app, "POST", "/",
+		types.LoginUserParams{Email: userEmail, Password: userPassword},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusTooManyRequests {
+		t.Fatalf("Account wasn't locked")
+	}
+	if len(resp.Header.Get(fiber.HeaderRetryAfter)) == 0 {
+		t.Fatalf("Retry-After header is missing")
+	}
+
+	// Only admins can unlock
+	_, err = userController.UnlockByID(contextWithUser(user), user.ID)
+	if err != controllers.ErrAdminOnly {
+		t.Fatalf("Non-admin unlocked account")
+	}
+
+	adminUnsaved, err := types.NewUserFromCreateParams(
+		types.CreateUserParams{
+			BaseUserParams: types.BaseUserParams{
+				Email:     "admin@gmail.com",
+				FirstName: "Ad",
+				LastName:  "Min",
+			},
+			Password: userPassword,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminUnsaved.IsAdmin = true
+	admin, err := userController.Create(context.Background(), adminUnsaved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = userController.UnlockByID(contextWithUser(admin), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = sendStructJSONRequest(
+		app, "POST", "/",
+		types.LoginUserParams{Email: userEmail, Password: userPassword},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect login status after unlock %d", resp.StatusCode)
+	}
+}
+
+func TestAdminLoginOTP(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	adminEmail := "otpadmin@gmail.com"
+	adminPassword := "12345678"
+
+	adminUnsaved, err := types.NewUserFromCreateParams(
+		types.CreateUserParams{
+			BaseUserParams: types.BaseUserParams{
+				Email:     adminEmail,
+				FirstName: "Otp",
+				LastName:  "Admin",
+			},
+			Password: adminPassword,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminUnsaved.IsAdmin = true
+
+	userController := &controllers.UserController{Store: store}
+	_, err = userController.Create(context.Background(), adminUnsaved)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	userHandler := api.NewUserHandler(userController)
+	app.Post("/login", userHandler.HandleLogin)
+	app.Post("/login/otp", userHandler.HandleLoginOTP)
+	app.Post("/login/otp/enrol", userHandler.HandleLoginEnrolOTP)
+
+	login := func() *types.LoginResult {
+		resp, err := sendStructJSONRequest(
+			app, "POST", "/login",
+			types.LoginUserParams{Email: adminEmail, Password: adminPassword},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Incorrect login status %d", resp.StatusCode)
+		}
+		var result *types.LoginResult
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.OTPRequired || len(result.Token) != 0 {
+			t.Fatalf("Admin logged in without second factor")
+		}
+		return result
+	}
+
+	// Admin without TOTP has to enrol before getting access token
+	challenge := login()
+	if !challenge.EnrolmentRequired {
+		t.Fatalf("Enrolment wasn't required")
+	}
+
+	resp, err := sendStructJSONRequest(
+		app, "POST", "/login/otp/enrol",
+		types.OTPChallengeParams{ChallengeToken: challenge.ChallengeToken},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var enrolment *types.OTPEnrolment
+	err = json.NewDecoder(resp.Body).Decode(&enrolment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enrolment.ProvisioningURI, "otpauth://totp/") {
+		t.Fatalf("Invalid provisioning URI %s", enrolment.ProvisioningURI)
+	}
+
+	code, err := totp.CodeForStep(enrolment.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = sendStructJSONRequest(
+		app, "POST", "/login/otp",
+		types.LoginOTPParams{ChallengeToken: challenge.ChallengeToken, Code: code},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Incorrect OTP login status %d", resp.StatusCode)
+	}
+	var result *types.LoginResult
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Token) == 0 || len(result.RecoveryCodes) == 0 {
+		t.Fatalf("Token or recovery codes are missing")
+	}
+
+	// Recovery code works once
+	recoveryCode := result.RecoveryCodes[0]
+	for i, expectedStatus := range []int{fiber.StatusOK, fiber.StatusBadRequest} {
+		challenge = login()
+		if challenge.EnrolmentRequired {
+			t.Fatalf("Enrolment was required twice")
+		}
+		resp, err = sendStructJSONRequest(
+			app, "POST", "/login/otp",
+			types.LoginOTPParams{
+				ChallengeToken: challenge.ChallengeToken, Code: recoveryCode,
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != expectedStatus {
+			t.Fatalf("Incorrect recovery code login status %d on attempt %d", resp.StatusCode, i)
+		}
+	}
+}
+
+func TestLegacyUserLogin(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	legacy, err := createTestUser(store, "legacy@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unverified, err := createTestUser(store, "unverified@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Registered before email verification existed
+	_, err = store.DB.Users.Update(
+		context.Background(), bson.M{"_id": legacy.ID}, bson.M{"$unset": bson.M{"isVerified": ""}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.DB.Users.Update(
+		context.Background(), bson.M{"_id": unverified.ID}, bson.M{"$set": bson.M{"isVerified": false}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.DB.Migrate(context.Background(), controllers.DefaultCurrency())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.CT.Users.Login(context.Background(), &types.LoginUserParams{
+		Email: "legacy@test.com", Password: "12345678",
+	})
+	if err != nil {
+		t.Fatalf("Expected legacy user to log in, got %v", err)
+	}
+	_, err = store.CT.Users.Login(context.Background(), &types.LoginUserParams{
+		Email: "unverified@test.com", Password: "12345678",
+	})
+	if err != controllers.ErrEmailNotVerified {
+		t.Fatalf("Expected unverified user to stay locked out, got %v", err)
+	}
+}
@@ -4,40 +4,140 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"hotel/api"
 	"hotel/controllers"
 	"hotel/db"
+	"hotel/mailer"
+	"hotel/types"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
 )
 
-func sendStructJSONRequest[T any](
-	app *fiber.App, method string, path string, params T,
-) (*http.Response, error) {
+func newTestApp() *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: api.HandleAPIError})
+	app.Use(recover.New())
+	app.Use(requestid.New(requestid.Config{
+		ContextKey: controllers.RequestIDContextKey,
+	}))
+	return app
+}
 
+func jsonRequest[T any](method string, path string, params T) *http.Request {
 	b, err := json.Marshal(params)
 	if err != nil {
-		return nil, err
+		log.Fatal(err)
 	}
 
 	req := httptest.NewRequest(method, path, bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func sendStructJSONRequest[T any](
+	app *fiber.App, method string, path string, params T,
+) (*http.Response, error) {
+	return app.Test(jsonRequest(method, path, params))
+}
 
+func sendAPIKeyRequest(
+	app *fiber.App, method string, path string, apiKey string,
+) (*http.Response, error) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(api.APIKeyHeader, apiKey)
 	return app.Test(req)
 }
 
+func createTestUser(
+	store *controllers.Store, email string, isAdmin bool,
+) (*types.User, error) {
+	user, err := types.NewUserFromCreateParams(
+		types.CreateUserParams{
+			BaseUserParams: types.BaseUserParams{
+				Email:     email,
+				FirstName: "Test",
+				LastName:  "User",
+			},
+			Password: "12345678",
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	user.IsAdmin = isAdmin
+	return store.CT.Users.Create(context.Background(), user)
+}
+
 func setupDBStore() *db.DB {
 	if err := godotenv.Load("../../.env"); err != nil {
 		log.Fatalf("No .env file found")
 	}
-	return db.GetTestDatabase()
+	dbStore := db.GetTestDatabase()
+	err := dbStore.Migrate(context.Background(), controllers.DefaultCurrency())
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dbStore
+}
+
+func testUserToken(user *types.User) *jwt.Token {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":    user.ID.Hex(),
+		"email": user.Email,
+	})
+}
+
+// Mimics context produced by jwt middleware for given user
+func contextWithUser(user *types.User) context.Context {
+	return context.WithValue(context.Background(), "user", testUserToken(user))
 }
 
+// Mimics jwt middleware, authenticating every request of app as user
+func useTestUser(app *fiber.App, user *types.User) {
+	app.Use(func(ctx *fiber.Ctx) error {
+		ctx.Locals("user", testUserToken(user))
+		return ctx.Next()
+	})
+}
+
+var testMailDir = filepath.Join(os.TempDir(), "hotel-test-mail")
+
 func setupCTStore() *controllers.Store {
-	return controllers.NewStore(setupDBStore())
+	return controllers.NewStore(
+		setupDBStore(), nil, &mailer.FileMailer{Dir: testMailDir},
+	)
+}
+
+// Returns token from the link in the latest mail captured by FileMailer
+func readLastMailToken() (string, error) {
+	files, err := filepath.Glob(filepath.Join(testMailDir, "*.eml"))
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("No mail was sent")
+	}
+	sort.Strings(files)
+	content, err := os.ReadFile(files[len(files)-1])
+	if err != nil {
+		return "", err
+	}
+	match := regexp.MustCompile(`token=([0-9a-f]+)`).FindSubmatch(content)
+	if match == nil {
+		return "", fmt.Errorf("No token in mail")
+	}
+	return string(match[1]), nil
 }
 
 func teardown() {
@@ -45,4 +145,8 @@ func teardown() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	err = os.RemoveAll(testMailDir)
+	if err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,99 @@
+package apiTest
+
+import (
+	"context"
+	"hotel/types"
+	"hotel/validate"
+	"testing"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestValidateParams(t *testing.T) {
+	existingID := primitive.NewObjectID()
+	validator := &validate.Validator{
+		Exists: func(
+			ctx context.Context, entity string, id primitive.ObjectID,
+		) (bool, error) {
+			return id == existingID, nil
+		},
+	}
+
+	cases := []struct {
+		params interface{}
+		fields []string
+	}{
+		{
+			types.CreateHotelParams{BaseHotelParams: types.BaseHotelParams{
+				Name: "H", Location: "",
+			}},
+			[]string{"name", "location"},
+		},
+		{
+			types.CreateUserParams{
+				BaseUserParams: types.BaseUserParams{
+					FirstName: "Alex", LastName: "Xela", Email: "not-an-email",
+				},
+				Password: "123",
+			},
+			[]string{"email", "password"},
+		},
+		{
+			types.CreateRoomParams{BaseRoomParams: types.BaseRoomParams{
+				Type: 7, HotelID: primitive.NewObjectID(),
+			}},
+			[]string{"type", "hotelID"},
+		},
+		{
+			types.CreateBookingParams{BaseBookingParams: types.BaseBookingParams{
+				RoomID:   existingID,
+				DateFrom: civil.Date{Year: 2030, Month: 1, Day: 10},
+				DateTo:   civil.Date{Year: 2030, Month: 1, Day: 5},
+			}},
+			[]string{"dateTo"},
+		},
+		{
+			types.CreateAPIKeyParams{
+				Name:        "partner",
+				UserID:      existingID,
+				HotelIDs:    []primitive.ObjectID{existingID},
+				Permissions: []types.APIKeyPermission{"hotels:delete"},
+			},
+			[]string{"permissions"},
+		},
+		{
+			types.SetTaxRulesParams{Rules: []*types.TaxRuleParams{
+				nil, {Kind: types.VATTaxKind, Rate: 1900}, {Kind: "unknown"},
+			}},
+			[]string{"rules[0]", "rules[2].kind"},
+		},
+		{
+			types.IssueCreditNoteParams{Lines: []*types.InvoiceLineParams{
+				{Kind: types.ExtraInvoiceLineKind}, nil,
+			}},
+			[]string{"lines[0].description", "lines[1]"},
+		},
+		{
+			types.CreateRoomParams{BaseRoomParams: types.BaseRoomParams{
+				Type: types.SingleRoomType, HotelID: existingID,
+			}},
+			[]string{},
+		},
+	}
+
+	for i, c := range cases {
+		errs, err := validator.Struct(context.Background(), c.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(errs) != len(c.fields) {
+			t.Fatalf("Case %d: expected errors for %v, got %v", i, c.fields, errs)
+		}
+		for _, field := range c.fields {
+			if len(errs[field]) == 0 {
+				t.Fatalf("Case %d: expected error for %s, got %v", i, field, errs)
+			}
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package apiTest
+
+import (
+	"context"
+	"hotel/controllers"
+	"hotel/notifications"
+	"hotel/types"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestWaitlistOfferTemplates(t *testing.T) {
+	data := &notifications.WaitlistOfferData{
+		GuestName: "Anna", HotelName: "Seaside", RoomType: types.DoubleRoomType,
+		HoldID:   "6650f0c2a1b2c3d4e5f60718",
+		DateFrom: civil.Date{Year: 2030, Month: 5, Day: 1},
+		DateTo:   civil.Date{Year: 2030, Month: 5, Day: 3},
+		Nights:   2, ExpiresAt: time.Date(2030, 4, 20, 9, 30, 0, 0, time.UTC),
+	}
+	for _, locale := range []types.Locale{types.EnglishLocale, types.GermanLocale} {
+		for _, channel := range []string{"email", "sms"} {
+			subject, body, err := notifications.DefaultTemplates.Render(
+				types.WaitlistOfferNotificationKind, locale, channel, data,
+			)
+			if err != nil {
+				t.Fatalf("Failed to render %s offer for %s: %s", locale, channel, err)
+			}
+			if !strings.Contains(subject, "Seaside") || !strings.Contains(body, "09:30") {
+				t.Fatalf("Unexpected %s offer for %s: %q %q", locale, channel, subject, body)
+			}
+		}
+	}
+}
+
+// Moves offer of entry and its hold to the past
+func expireWaitlistOffer(t *testing.T, store *controllers.Store, entry *types.WaitlistEntry) {
+	past := time.Now().Add(-time.Minute)
+	_, err := store.DB.Holds.Update(
+		context.Background(), bson.M{"_id": entry.HoldID}, bson.M{"$set": bson.M{"expiresAt": past}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.DB.Waitlist.Update(
+		context.Background(), bson.M{"_id": entry.ID}, bson.M{"$set": bson.M{"offerExpiresAt": past}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitlist(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+	email := &recordingChannel{name: "email"}
+	store.Notifier.Channels = []notifications.Channel{email}
+
+	ctxs := []context.Context{}
+	for _, address := range []string{"wait-a@test.com", "wait-b@test.com", "wait-c@test.com", "wait-d@test.com"} {
+		user, err := createTestUser(store, address, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctxs = append(ctxs, contextWithUser(user))
+	}
+	guestA, guestB, guestC, guestD := ctxs[0], ctxs[1], ctxs[2], ctxs[3]
+
+	hotelID, err := store.DB.Hotels.Create(guestA, &types.Hotel{Name: "Popular", Location: "Bergen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(guestA, &types.Room{
+		HotelID: hotelID, Type: types.DoubleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	today := civil.DateOf(time.Now())
+	stay := &types.WaitlistEntry{
+		HotelID: hotelID, RoomType: types.DoubleRoomType, DateFrom: today.AddDays(5), DateTo: today.AddDays(7),
+	}
+	_, err = store.CT.Waitlist.Create(guestB, stay)
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected joining for free room to be rejected, got %v", err)
+	}
+
+	booking, err := store.CT.Bookings.Create(guestA, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(4), DateTo: today.AddDays(8),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []*types.WaitlistEntry{}
+	for _, ctx := range []context.Context{guestB, guestC, guestD} {
+		entry := *stay
+		created, err := store.CT.Waitlist.Create(ctx, &entry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, created)
+	}
+	entry := *stay
+	_, err = store.CT.Waitlist.Create(guestB, &entry)
+	if _, ok := err.(controllers.ValidationError); !ok {
+		t.Fatalf("Expected joining twice to be rejected, got %v", err)
+	}
+
+	// Cancellation offers room to the first guest
+	err = store.CT.Bookings.DeleteByID(guestA, booking.ID, booking.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offeredB, err := store.CT.Waitlist.GetByID(guestB, entries[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offeredB.Status != types.OfferedWaitlistStatus || offeredB.HoldID.IsZero() {
+		t.Fatalf("Expected first guest to get offer, got %+v", offeredB)
+	}
+	hold, err := store.CT.Holds.GetByID(guestB, offeredB.HoldID)
+	if err != nil || hold == nil || hold.RoomID != roomID || !hold.IsActiveAt(time.Now()) {
+		t.Fatalf("Expected active offer hold, got %+v %v", hold, err)
+	}
+	waitingC, err := store.CT.Waitlist.GetByID(guestC, entries[1].ID)
+	if err != nil || waitingC.Status != types.WaitingWaitlistStatus {
+		t.Fatalf("Expected second guest to keep waiting, got %+v %v", waitingC, err)
+	}
+	store.Notifier.Wait()
+	offers := 0
+	for _, msg := range email.sent {
+		if strings.Contains(msg.Subject, "is free for you") {
+			offers++
+		}
+	}
+	if len(email.sent) != 3 || offers != 1 {
+		t.Fatalf("Expected offer email besides booking emails, got %+v", email.sent)
+	}
+
+	// Expired offer passes down the list
+	expireWaitlistOffer(t, store, offeredB)
+	expired, err := store.CT.Waitlist.ExpireOffers(context.Background(), time.Now())
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected one offer to expire, got %d %v", expired, err)
+	}
+	offeredC, err := store.CT.Waitlist.GetByID(guestC, entries[1].ID)
+	if err != nil || offeredC.Status != types.OfferedWaitlistStatus {
+		t.Fatalf("Expected offer to pass to second guest, got %+v %v", offeredC, err)
+	}
+
+	// Released offer passes down the list too
+	err = store.CT.Holds.ReleaseByID(guestC, offeredC.HoldID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offeredD, err := store.CT.Waitlist.GetByID(guestD, entries[2].ID)
+	if err != nil || offeredD.Status != types.OfferedWaitlistStatus {
+		t.Fatalf("Expected offer to pass to third guest, got %+v %v", offeredD, err)
+	}
+	booked, err := store.CT.Holds.ConvertByID(guestD, offeredD.HoldID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offeredD, err = store.CT.Waitlist.GetByID(guestD, entries[2].ID)
+	if err != nil || offeredD.Status != types.BookedWaitlistStatus || offeredD.BookingID != booked.ID {
+		t.Fatalf("Expected offer to be booked, got %+v %v", offeredD, err)
+	}
+
+	entriesB, err := store.CT.Waitlist.Get(guestB, nil)
+	if err != nil || len(entriesB) != 1 || entriesB[0].Status != types.ExpiredWaitlistStatus {
+		t.Fatalf("Expected guest to see own expired entry, got %+v %v", entriesB, err)
+	}
+
+	// Once hotel has rate plans, offers are booked with one guest can book
+	admin, err := createTestUser(store, "wait-admin@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ratePlan, err := store.CT.RatePlans.Create(contextWithUser(admin), &types.RatePlan{
+		HotelID: hotelID, Name: "Long stays", Kind: types.FlexibleRatePlanKind, MinNights: 3,
+		Cancellation: types.CancellationPolicy{Refundable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry = *stay
+	_, err = store.CT.Waitlist.Create(guestB, &entry)
+	if validationErr, ok := err.(controllers.ValidationError); !ok || len(validationErr.Fields["ratePlanID"]) == 0 {
+		t.Fatalf("Expected entry without plan to be rejected, got %v", err)
+	}
+	entry = *stay
+	entry.RatePlanID = ratePlan.ID
+	_, err = store.CT.Waitlist.Create(guestB, &entry)
+	if validationErr, ok := err.(controllers.ValidationError); !ok || len(validationErr.Fields["ratePlanID"]) == 0 {
+		t.Fatalf("Expected too short stay for plan to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package apiTest
+
+import (
+	"context"
+	"hotel/api"
+	"hotel/controllers"
+	"hotel/events"
+	"hotel/types"
+	"hotel/webhooks"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// Local partner endpoint, which checks signatures and can be told to fail
+type webhookReceiver struct {
+	mu       sync.Mutex
+	secret   string
+	status   int
+	received []string
+}
+
+func (self *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	if !webhooks.Verify(
+		self.secret, r.Header.Get(webhooks.SignatureHeader), body, time.Now(), time.Minute,
+	) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if self.status == http.StatusOK {
+		self.received = append(self.received, r.Header.Get(webhooks.EventTypeHeader))
+	}
+	w.WriteHeader(self.status)
+}
+
+func TestWebhookAdminOnly(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	guest, err := createTestUser(store, "curious@test.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hotelID, err := store.DB.Hotels.Create(context.Background(), &types.Hotel{Name: "Private", Location: "Bern"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := newTestApp()
+	useTestUser(app, guest)
+	webhookHandler := api.NewWebhookHandler(&controllers.WebhookController{Store: store})
+	app.Post("/webhook", webhookHandler.HandleCreateWebhook)
+	app.Get("/webhook", webhookHandler.HandleListWebhooks)
+
+	resp, err := sendStructJSONRequest(app, "POST", "/webhook", types.CreateWebhookParams{
+		BaseWebhookParams: types.BaseWebhookParams{
+			URL:        "https://example.com/hook",
+			EventTypes: []types.EventType{types.BookingCreatedEventType},
+		},
+		HotelID: hotelID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected guest to be forbidden to create webhook, got %d", resp.StatusCode)
+	}
+	resp, err = app.Test(httptest.NewRequest("GET", "/webhook", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected guest to be forbidden to list webhooks, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	store := setupCTStore()
+	defer teardown()
+
+	user, err := createTestUser(store, "partner@test.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := contextWithUser(user)
+	hotelID, err := store.DB.Hotels.Create(ctx, &types.Hotel{Name: "Hooked", Location: "Rome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomID, err := store.DB.Rooms.Create(ctx, &types.Room{
+		HotelID: hotelID, Type: types.SingleRoomType, Price: types.NewMoney(10000, "EUR"), Version: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiver := &webhookReceiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	webhook, err := store.CT.Webhooks.Create(ctx, &types.Webhook{
+		HotelID:    hotelID,
+		URL:        server.URL,
+		EventTypes: []types.EventType{types.BookingCreatedEventType},
+		IsActive:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver.secret = webhook.Secret
+
+	today := civil.DateOf(time.Now())
+	booking, err := store.CT.Bookings.Create(ctx, &types.Booking{
+		RoomID: roomID, DateFrom: today.AddDays(1), DateTo: today.AddDays(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Webhook isn't subscribed to cancellations
+	err = store.CT.Bookings.DeleteByID(ctx, booking.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := events.NewDispatcher(store.DB, []events.Sink{webhooks.NewSink(store.DB)})
+	_, err = dispatcher.DispatchPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := webhooks.NewWorker(store.DB)
+	worker.BaseBackoff = 0
+	worker.MaxFailures = 3
+	attempted, err := worker.DeliverPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempted != 3 {
+		t.Fatalf("Delivery was attempted %d times instead of 3", attempted)
+	}
+
+	deliveries, err := store.CT.Webhooks.GetDeliveries(ctx, webhook.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Found %d deliveries instead of 1", len(deliveries))
+	}
+	delivery := deliveries[0]
+	if delivery.Status != types.DeadWebhookDeliveryStatus || len(delivery.Attempts) != 3 {
+		t.Fatalf("Delivery is %s after %d attempts", delivery.Status, len(delivery.Attempts))
+	}
+	if delivery.Attempts[0].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Attempt logged with status %d", delivery.Attempts[0].StatusCode)
+	}
+
+	receiver.status = http.StatusOK
+	_, err = store.CT.Webhooks.Redeliver(ctx, webhook.ID, delivery.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = worker.DeliverPending(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err = store.CT.Webhooks.GetDeliveries(ctx, webhook.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deliveries[0].Status != types.SucceededWebhookDeliveryStatus {
+		t.Fatalf("Redelivery ended up %s", deliveries[0].Status)
+	}
+	if len(receiver.received) != 1 || receiver.received[0] != string(types.BookingCreatedEventType) {
+		t.Fatalf("Receiver got %v", receiver.received)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"booking.created"}`)
+	now := time.Now()
+	header := webhooks.Sign("whsec_test", now, body)
+
+	if !webhooks.Verify("whsec_test", header, body, now, time.Minute) {
+		t.Fatal("Valid signature was rejected")
+	}
+	if webhooks.Verify("whsec_other", header, body, now, time.Minute) {
+		t.Fatal("Signature with wrong secret was accepted")
+	}
+	if webhooks.Verify("whsec_test", header, []byte(`{}`), now, time.Minute) {
+		t.Fatal("Signature of different body was accepted")
+	}
+	if webhooks.Verify("whsec_test", header, body, now.Add(time.Hour), time.Minute) {
+		t.Fatal("Stale signature was accepted")
+	}
+}
@@ -3,10 +3,8 @@ package api
 import (
 	"hotel/controllers"
 	"hotel/types"
-	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserHandler struct {
@@ -21,54 +19,50 @@ func NewUserHandler(controller *controllers.UserController) *UserHandler {
 
 func (self *UserHandler) HandleLogin(ctx *fiber.Ctx) error {
 	var params types.LoginUserParams
-	err := ctx.BodyParser(&params)
+	err := ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
 
-	token, user, err := self.controller.Login(ctx.Context(), &params)
+	params.IP = ctx.IP()
+
+	result, err := self.controller.Login(ctx.Context(), &params)
 	if err != nil {
-		log.Printf("Auth failed: %s", err.Error())
-		return fiber.NewError(fiber.StatusBadRequest, "Auth failed")
+		return err
 	}
 
-	// Using just fiber.Map is intentional, for learning purposes
-	// otherwise it would've been a struct
-	return ctx.JSON(fiber.Map{
-		"token": token,
-		"user":  user,
-	})
+	return ctx.JSON(result)
 }
 
 func (self *UserHandler) HandleListUsers(ctx *fiber.Ctx) error {
 	users, err := self.controller.Get(ctx.Context())
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 
 	return ctx.JSON(users)
 }
 
 func (self *UserHandler) HandleGetUser(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
 	if err != nil {
 		return err
 	}
 
 	user, err := self.controller.GetByID(ctx.Context(), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 	if user == nil {
-		return fiber.NewError(fiber.StatusNotFound, "User not found")
+		return controllers.NotFoundError{Entity: "User"}
 	}
 
-	return ctx.JSON(user)
+	return SendWithETag(ctx, user.Version, user)
 }
 
 func (self *UserHandler) HandleCreateUser(ctx *fiber.Ctx) error {
 	var params types.CreateUserParams
-	err := ctx.BodyParser(&params)
+	err := ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -80,10 +74,6 @@ func (self *UserHandler) HandleCreateUser(ctx *fiber.Ctx) error {
 
 	createdUser, err := self.controller.Create(ctx.Context(), user)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 
@@ -91,13 +81,17 @@ func (self *UserHandler) HandleCreateUser(ctx *fiber.Ctx) error {
 }
 
 func (self *UserHandler) HandleUpdateUser(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
 	if err != nil {
 		return err
 	}
 
 	var params types.UpdateUserParams
-	err = ctx.BodyParser(&params)
+	err = ParseBody(ctx, self.controller.Store, &params)
 	if err != nil {
 		return err
 	}
@@ -106,31 +100,294 @@ func (self *UserHandler) HandleUpdateUser(ctx *fiber.Ctx) error {
 	if err != nil {
 		return err
 	}
+	data.Version = version
 
 	updatedUser, err := self.controller.UpdateByID(ctx.Context(), id, data)
 	if err != nil {
-		validationError, ok := err.(controllers.ValidationError)
-		if ok {
-			return ctx.Status(fiber.StatusBadRequest).JSON(validationError.Fields)
-		}
 		return err
 	}
 	if updatedUser == nil {
-		return fiber.NewError(fiber.StatusNotFound, "User not found")
+		return controllers.NotFoundError{Entity: "User"}
+	}
+
+	return SendWithETag(ctx, updatedUser.Version, updatedUser)
+}
+
+func (self *UserHandler) HandlePatchUser(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	current, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return controllers.NotFoundError{Entity: "User"}
+	}
+
+	var params types.UpdateUserParams
+	err = ParsePatch(ctx, self.controller.Store, current, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewUserFromUpdateParams(params)
+	if err != nil {
+		return err
 	}
+	data.Version = version
 
-	return ctx.JSON(updatedUser)
+	updatedUser, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedUser.Version, updatedUser)
 }
 
 func (self *UserHandler) HandleDeleteUser(ctx *fiber.Ctx) error {
-	id, err := primitive.ObjectIDFromHex(ctx.Params("id"))
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
 	if err != nil {
 		return err
 	}
 
-	err = self.controller.DeleteByID(ctx.Context(), id)
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
 	if err != nil {
 		return err
 	}
 	return ctx.Status(fiber.StatusNoContent).Send(nil)
 }
+
+func (self *UserHandler) HandleRegister(ctx *fiber.Ctx) error {
+	var params types.CreateUserParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	user, err := types.NewUserFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdUser, err := self.controller.Register(ctx.Context(), user)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdUser)
+}
+
+func (self *UserHandler) HandleVerifyEmail(ctx *fiber.Ctx) error {
+	var params types.VerifyEmailParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	user, err := self.controller.VerifyEmail(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return controllers.NotFoundError{Entity: "User"}
+	}
+
+	return ctx.JSON(user)
+}
+
+func (self *UserHandler) HandleResendVerification(ctx *fiber.Ctx) error {
+	var params types.ResendVerificationParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.ResendVerification(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusAccepted).Send(nil)
+}
+
+func (self *UserHandler) HandleForgotPassword(ctx *fiber.Ctx) error {
+	var params types.ForgotPasswordParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.ForgotPassword(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusAccepted).Send(nil)
+}
+
+func (self *UserHandler) HandleResetPassword(ctx *fiber.Ctx) error {
+	var params types.ResetPasswordParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.ResetPassword(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *UserHandler) HandleChangePassword(ctx *fiber.Ctx) error {
+	var params types.ChangePasswordParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.ChangePassword(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *UserHandler) HandleListLocked(ctx *fiber.Ctx) error {
+	locked, err := self.controller.GetLocked(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(locked)
+}
+
+func (self *UserHandler) HandleUnlockUser(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	user, err := self.controller.UnlockByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return controllers.NotFoundError{Entity: "User"}
+	}
+
+	return ctx.JSON(user)
+}
+
+func (self *UserHandler) HandleUnlockIP(ctx *fiber.Ctx) error {
+	var params types.UnlockIPParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.UnlockIP(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *UserHandler) HandleLoginOTP(ctx *fiber.Ctx) error {
+	var params types.LoginOTPParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+	params.IP = ctx.IP()
+
+	result, err := self.controller.LoginOTP(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(result)
+}
+
+func (self *UserHandler) HandleLoginEnrolOTP(ctx *fiber.Ctx) error {
+	var params types.OTPChallengeParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	enrolment, err := self.controller.LoginEnrolOTP(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(enrolment)
+}
+
+func (self *UserHandler) HandleEnrolOTP(ctx *fiber.Ctx) error {
+	enrolment, err := self.controller.EnrolOTP(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(enrolment)
+}
+
+func (self *UserHandler) HandleConfirmOTP(ctx *fiber.Ctx) error {
+	var params types.OTPCodeParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	codes, err := self.controller.ConfirmOTP(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(codes)
+}
+
+func (self *UserHandler) HandleDisableOTP(ctx *fiber.Ctx) error {
+	var params types.DisableOTPParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DisableOTP(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *UserHandler) HandleRegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	var params types.OTPCodeParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	codes, err := self.controller.RegenerateRecoveryCodes(ctx.Context(), &params)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(codes)
+}
@@ -2,25 +2,148 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"hotel/controllers"
+	"log"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Single shape of all error responses
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code      controllers.ErrorCode `json:"code"`
+	Message   string                `json:"message"`
+	Fields    map[string]string     `json:"fields,omitempty"`
+	RequestID string                `json:"requestId,omitempty"`
+}
+
+const (
+	BadRequestErrorCode controllers.ErrorCode = "bad_request"
+	InternalErrorCode   controllers.ErrorCode = "internal"
+)
+
+// Codes for errors produced by fiber itself and its middlewares
+var fiberErrorCodes = map[int]controllers.ErrorCode{
+	fiber.StatusBadRequest:          BadRequestErrorCode,
+	fiber.StatusUnprocessableEntity: BadRequestErrorCode,
+	fiber.StatusUnauthorized:        controllers.UnauthorizedErrorCode,
+	fiber.StatusForbidden:           controllers.ForbiddenErrorCode,
+	fiber.StatusNotFound:            controllers.NotFoundErrorCode,
+	fiber.StatusMethodNotAllowed:    BadRequestErrorCode,
+	fiber.StatusTooManyRequests:     controllers.TooManyAttemptsErrorCode,
+}
+
+func errorStatus(err error) int {
+	switch err.(type) {
+	case controllers.ValidationError:
+		return fiber.StatusBadRequest
+	case controllers.NotFoundError:
+		return fiber.StatusNotFound
+	case controllers.ConflictError:
+		return fiber.StatusConflict
+	case controllers.ForbiddenError:
+		return fiber.StatusForbidden
+	case controllers.UnauthorizedError:
+		return fiber.StatusUnauthorized
+	case controllers.LoginLockedError:
+		return fiber.StatusTooManyRequests
+	case controllers.UpstreamUnavailableError:
+		return fiber.StatusServiceUnavailable
+	case controllers.PreconditionFailedError:
+		return fiber.StatusPreconditionFailed
+	case controllers.PreconditionRequiredError:
+		return fiber.StatusPreconditionRequired
+	}
+	return fiber.StatusInternalServerError
+}
+
 func HandleAPIError(ctx *fiber.Ctx, err error) error {
-	code := fiber.StatusInternalServerError
+	status := fiber.StatusInternalServerError
+	body := ErrorBody{
+		Code:    InternalErrorCode,
+		Message: "Internal server error",
+	}
+	body.RequestID = controllers.GetRequestIDFromContext(ctx.Context())
 
-	var e *fiber.Error
-	if errors.As(err, &e) {
-		code = e.Code
+	var codedErr controllers.CodedError
+	var fiberErr *fiber.Error
+	if errors.As(err, &codedErr) {
+		status = errorStatus(codedErr)
+		body.Code = codedErr.ErrorCode()
+		body.Message = codedErr.Error()
+	} else if errors.As(err, &fiberErr) {
+		status = fiberErr.Code
+		body.Code = fiberErrorCodes[status]
+		if len(body.Code) == 0 {
+			body.Code = BadRequestErrorCode
+		}
+		body.Message = fiberErr.Message
 	}
 
-	fiberErr := ctx.Status(code).JSON(map[string]interface{}{
-		"error": err.Error(),
-	})
+	var validationErr controllers.ValidationError
+	if errors.As(err, &validationErr) {
+		body.Message = "Validation failed"
+		body.Fields = validationErr.Fields
+	}
+	var upstreamErr controllers.UpstreamUnavailableError
+	if errors.As(err, &upstreamErr) {
+		body.Message = upstreamErr.Service + " is temporarily unavailable"
+	}
+	var lockedErr controllers.LoginLockedError
+	if errors.As(err, &lockedErr) {
+		ctx.Set(
+			fiber.HeaderRetryAfter,
+			strconv.Itoa(int(lockedErr.RetryAfter.Seconds())+1),
+		)
+	}
+	// Internal details are logged, but never sent to client
+	if status >= fiber.StatusInternalServerError {
+		log.Printf("Request %s failed: %s", body.RequestID, err.Error())
+	}
 
-	if fiberErr != nil {
+	jsonErr := ctx.Status(status).JSON(ErrorResponse{Error: body})
+	if jsonErr != nil {
 		return ctx.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
 	}
 
 	return nil
 }
+
+// Used by jwt middleware, so auth errors have the same shape as others
+func HandleJWTError(ctx *fiber.Ctx, err error) error {
+	return controllers.UnauthorizedError{Message: "Missing, malformed or expired JWT"}
+}
+
+// Malformed id is client's mistake, so it's reported as validation error
+func ParseIDParam(ctx *fiber.Ctx, name string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(ctx.Params(name))
+	if err != nil {
+		return primitive.ObjectID{}, controllers.NewFieldError(name, "Invalid id")
+	}
+	return id, nil
+}
+
+// Parses request body into params and validates them by struct tags.
+// Malformed body is client's mistake, so it's reported as validation error
+func ParseBody(ctx *fiber.Ctx, store *controllers.Store, params interface{}) error {
+	err := ctx.BodyParser(params)
+	if err != nil {
+		return controllers.NewFieldError("body", fmt.Sprintf("Malformed request body: %s", err))
+	}
+	return store.ValidateParams(ctx.Context(), params)
+}
+
+// Parses query string into params and validates them by struct tags
+func ParseQuery(ctx *fiber.Ctx, store *controllers.Store, params interface{}) error {
+	err := ctx.QueryParser(params)
+	if err != nil {
+		return controllers.NewFieldError("query", fmt.Sprintf("Malformed query string: %s", err))
+	}
+	return store.ValidateParams(ctx.Context(), params)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WaitlistHandler struct {
+	controller *controllers.WaitlistController
+}
+
+func NewWaitlistHandler(controller *controllers.WaitlistController) *WaitlistHandler {
+	return &WaitlistHandler{
+		controller: controller,
+	}
+}
+
+func (self *WaitlistHandler) HandleListWaitlist(ctx *fiber.Ctx) error {
+	var query controllers.WaitlistGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	entries, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(entries)
+}
+
+func (self *WaitlistHandler) HandleGetWaitlistEntry(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	entry, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(entry)
+}
+
+func (self *WaitlistHandler) HandleJoinWaitlist(ctx *fiber.Ctx) error {
+	var params types.CreateWaitlistEntryParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	entry, err := types.NewWaitlistEntryFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdEntry, err := self.controller.Create(ctx.Context(), entry)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdEntry)
+}
+
+func (self *WaitlistHandler) HandleLeaveWaitlist(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"hotel/controllers"
+	"hotel/types"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookHandler struct {
+	controller *controllers.WebhookController
+}
+
+func NewWebhookHandler(controller *controllers.WebhookController) *WebhookHandler {
+	return &WebhookHandler{
+		controller: controller,
+	}
+}
+
+func (self *WebhookHandler) HandleListWebhooks(ctx *fiber.Ctx) error {
+	var query controllers.WebhookGetQueryParams
+	err := ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := self.controller.Get(ctx.Context(), &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(webhooks)
+}
+
+func (self *WebhookHandler) HandleGetWebhook(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+
+	webhook, err := self.controller.GetByID(ctx.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, webhook.Version, webhook)
+}
+
+func (self *WebhookHandler) HandleCreateWebhook(ctx *fiber.Ctx) error {
+	var params types.CreateWebhookParams
+	err := ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	webhook, err := types.NewWebhookFromCreateParams(params)
+	if err != nil {
+		return err
+	}
+
+	createdWebhook, err := self.controller.Create(ctx.Context(), webhook)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(createdWebhook)
+}
+
+func (self *WebhookHandler) HandleUpdateWebhook(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var params types.UpdateWebhookParams
+	err = ParseBody(ctx, self.controller.Store, &params)
+	if err != nil {
+		return err
+	}
+
+	data, err := types.NewWebhookFromUpdateParams(params)
+	if err != nil {
+		return err
+	}
+	data.Version = version
+
+	updatedWebhook, err := self.controller.UpdateByID(ctx.Context(), id, data)
+	if err != nil {
+		return err
+	}
+
+	return SendWithETag(ctx, updatedWebhook.Version, updatedWebhook)
+}
+
+func (self *WebhookHandler) HandleDeleteWebhook(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	version, err := ParseIfMatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = self.controller.DeleteByID(ctx.Context(), id, version)
+	if err != nil {
+		return err
+	}
+	return ctx.Status(fiber.StatusNoContent).Send(nil)
+}
+
+func (self *WebhookHandler) HandleListDeliveries(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	var query controllers.WebhookDeliveryGetQueryParams
+	err = ParseQuery(ctx, self.controller.Store, &query)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := self.controller.GetDeliveries(ctx.Context(), id, &query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(deliveries)
+}
+
+func (self *WebhookHandler) HandleRedeliver(ctx *fiber.Ctx) error {
+	id, err := ParseIDParam(ctx, "id")
+	if err != nil {
+		return err
+	}
+	deliveryID, err := ParseIDParam(ctx, "deliveryID")
+	if err != nil {
+		return err
+	}
+
+	delivery, err := self.controller.Redeliver(ctx.Context(), id, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(delivery)
+}
@@ -0,0 +1,43 @@
+// Runs scheduled jobs outside of API process. Set SCHEDULER_ENABLED=false
+// for API instances, if jobs should run only here
+package main
+
+import (
+	"context"
+	"hotel/controllers"
+	"hotel/db"
+	"hotel/mailer"
+	"hotel/scheduler"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Print("No .env file found")
+	}
+
+	// Jobs don't price rooms, so connection isn't awaited
+	roompricesConn, err := grpc.Dial(
+		os.Getenv("ROOMPRICES_LISTEN_URL"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		log.Fatalf("Failed to set up Roomprices connection: %s\n", err.Error())
+	}
+	defer roompricesConn.Close()
+
+	CTStore := controllers.NewStore(
+		db.GetDatabase(), roompricesConn, mailer.GetMailer(),
+	)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	scheduler.NewScheduler(CTStore.DB, scheduler.DefaultJobs(CTStore)).Run(ctx)
+}
@@ -0,0 +1,180 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hotel/types"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	APIKeyContextKey = "apiKey"
+
+	apiKeyPrefix         = "ghk"
+	apiKeyPrefixBytesLen = 4
+	apiKeySecretBytesLen = 32
+	// Last used time is coarse, so not every request causes a write
+	apiKeyLastUsedPrecision = time.Minute
+)
+
+type APIKeyController struct {
+	Store *Store
+}
+
+func GetAPIKeyFromContext(ctx context.Context) *types.APIKey {
+	return CastPtrInterface[types.APIKey](ctx.Value(APIKeyContextKey))
+}
+
+// Checks hotel against API key scope. Requests authenticated by JWT
+// aren't limited to hotels
+func RequireHotelAccess(ctx context.Context, hotelID primitive.ObjectID) error {
+	apiKey := GetAPIKeyFromContext(ctx)
+	if apiKey != nil && !apiKey.HasHotel(hotelID) {
+		return ErrHotelOutOfScope
+	}
+	return nil
+}
+
+// Returns query limiting hotel ids to API key scope, nil if not limited
+func hotelScopeQuery(ctx context.Context) interface{} {
+	apiKey := GetAPIKeyFromContext(ctx)
+	if apiKey == nil || len(apiKey.HotelIDs) == 0 {
+		return nil
+	}
+	return bson.M{"$in": apiKey.HotelIDs}
+}
+
+func (self *APIKeyController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.APIKey, error) {
+	result, err := self.Store.DB.APIKeys.GetOneByID(ctx, id, &types.APIKey{})
+	if err != nil {
+		return nil, err
+	}
+	return CastPtrInterface[types.APIKey](result), nil
+}
+
+func (self *APIKeyController) Get(ctx context.Context) ([]*types.APIKey, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.APIKeys.Get(ctx, bson.M{}, []*types.APIKey{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.APIKey](result), nil
+}
+
+func (self *APIKeyController) Create(
+	ctx context.Context, apiKey *types.APIKey,
+) (*types.APIKeyWithSecret, error) {
+	admin, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefixBytes := make([]byte, apiKeyPrefixBytesLen)
+	secretBytes := make([]byte, apiKeySecretBytesLen)
+	for _, buf := range [][]byte{prefixBytes, secretBytes} {
+		_, err = rand.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+	apiKey.Prefix = fmt.Sprintf("%s_%s", apiKeyPrefix, hex.EncodeToString(prefixBytes))
+	key := fmt.Sprintf("%s_%s", apiKey.Prefix, hex.EncodeToString(secretBytes))
+	apiKey.KeyHash = hashUserToken(key)
+	apiKey.CreatedBy = admin.ID
+	apiKey.CreatedAt = time.Now()
+
+	apiKey.Version = 1
+	var created *types.APIKey
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.APIKeys.Create(ctx, apiKey)
+		if err != nil {
+			return err
+		}
+		created, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "APIKey", id, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.APIKeyWithSecret{APIKey: created, Key: key}, nil
+}
+
+func (self *APIKeyController) RevokeByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.APIKey, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	var revoked *types.APIKey
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		result, err := self.Store.DB.APIKeys.GetOneAndUpdate(
+			ctx,
+			bson.M{"_id": id},
+			bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+			&types.APIKey{},
+		)
+		if err != nil {
+			return err
+		}
+		revoked = CastPtrInterface[types.APIKey](result)
+		if revoked == nil {
+			return nil
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "APIKey", id,
+			nil, bson.M{"revokedAt": revoked.RevokedAt},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+// Finds active key and records its usage
+func (self *APIKeyController) Authenticate(
+	ctx context.Context, key string,
+) (*types.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix+"_") {
+		return nil, ErrInvalidAPIKey
+	}
+	result, err := self.Store.DB.APIKeys.GetOne(
+		ctx,
+		bson.M{"keyHash": hashUserToken(key), "revokedAt": nil},
+		&types.APIKey{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	apiKey := CastPtrInterface[types.APIKey](result)
+	if apiKey == nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) > apiKeyLastUsedPrecision {
+		// Bookkeeping only, so version isn't changed
+		_, err = self.Store.DB.APIKeys.Update(
+			ctx, bson.M{"_id": apiKey.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		apiKey.LastUsedAt = &now
+	}
+	return apiKey, nil
+}
@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"hotel/types"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Secrets are never copied to audit log, only the fact they've changed
+var auditRedactedFields = map[string]bool{
+	"encryptedPassword": true,
+	"totpSecret":        true,
+	"totpLastStep":      true,
+	"recoveryCodes":     true,
+	"keyHash":           true,
+	"secret":            true,
+}
+
+const auditRedactedValue = "[redacted]"
+
+const (
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+type AuditController struct {
+	Store *Store
+}
+
+func auditDocument(obj interface{}) (bson.M, error) {
+	if obj == nil {
+		return bson.M{}, nil
+	}
+	return toDocument(obj)
+}
+
+func auditRedact(key string, value interface{}) interface{} {
+	if value != nil && auditRedactedFields[key] {
+		return auditRedactedValue
+	}
+	return value
+}
+
+// Returns changed fields with their values before and after mutation
+func AuditDiff(before interface{}, after interface{}) (map[string]types.AuditChange, error) {
+	beforeDoc, err := auditDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	afterDoc, err := auditDocument(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string]types.AuditChange{}
+	for key := range afterDoc {
+		if _, ok := beforeDoc[key]; !ok {
+			beforeDoc[key] = nil
+		}
+	}
+	for key, beforeValue := range beforeDoc {
+		afterValue := afterDoc[key]
+		if key == "_id" || key == "version" || reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		changes[key] = types.AuditChange{
+			Before: auditRedact(key, beforeValue),
+			After:  auditRedact(key, afterValue),
+		}
+	}
+	return changes, nil
+}
+
+// Appends record of mutation made by current actor. Before is nil
+// for creation, after is nil for deletion. Called within mutation's
+// transaction, so mutation isn't committed without its record
+func (self *AuditController) Record(
+	ctx context.Context, action types.AuditAction, entity string,
+	id primitive.ObjectID, before interface{}, after interface{},
+) error {
+	changes, err := AuditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	if action == types.UpdateAuditAction && len(changes) == 0 {
+		return nil
+	}
+	actorID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	record := &types.AuditRecord{
+		ActorID:   actorID,
+		Action:    action,
+		Entity:    entity,
+		EntityID:  id,
+		Changes:   changes,
+		RequestID: GetRequestIDFromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	if apiKey := GetAPIKeyFromContext(ctx); apiKey != nil {
+		record.APIKeyID = apiKey.ID
+	}
+	_, err = self.Store.DB.Audit.Create(ctx, record)
+	return err
+}
+
+type AuditGetQueryParams struct {
+	Entity   string             `json:"entity"`
+	EntityID primitive.ObjectID `json:"entityID"`
+	ActorID  primitive.ObjectID `json:"actorID"`
+	Action   types.AuditAction  `json:"action" validate:"enum"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to" validate:"gtfield=from"`
+	// Newest records are returned first
+	Limit int64 `json:"limit"`
+}
+
+func (self *AuditController) Get(
+	ctx context.Context, params *AuditGetQueryParams,
+) ([]*types.AuditRecord, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = &AuditGetQueryParams{}
+	}
+	query := bson.M{}
+	if len(params.Entity) != 0 {
+		query["entity"] = params.Entity
+	}
+	if !params.EntityID.IsZero() {
+		query["entityID"] = params.EntityID
+	}
+	if !params.ActorID.IsZero() {
+		query["actorID"] = params.ActorID
+	}
+	if len(params.Action) != 0 {
+		query["action"] = params.Action
+	}
+	createdAt := bson.M{}
+	if !params.From.IsZero() {
+		createdAt["$gte"] = params.From
+	}
+	if !params.To.IsZero() {
+		createdAt["$lt"] = params.To
+	}
+	if len(createdAt) != 0 {
+		query["createdAt"] = createdAt
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = auditDefaultLimit
+	}
+	if limit > auditMaxLimit {
+		limit = auditMaxLimit
+	}
+	result, err := self.Store.DB.Audit.GetSorted(
+		ctx, query, bson.D{{Key: "createdAt", Value: -1}}, limit,
+		[]*types.AuditRecord{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.AuditRecord](result), nil
+}
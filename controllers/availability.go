@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"context"
+	"hotel/types"
+	"regexp"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Events which change booked dates of rooms
+var availabilityEventTypes = []types.EventType{
+	types.BookingCreatedEventType, types.BookingChangedEventType,
+	types.BookingCancelledEventType, types.BookingRestoredEventType,
+}
+
+// Resume tokens of change streams are hex strings
+var resumeTokenRegex = regexp.MustCompile(`^[0-9A-Fa-f]+$`)
+
+type AvailabilityController struct {
+	Store *Store
+}
+
+// Changes of booked dates of hotel's rooms, or of a single room. Backed
+// by change stream of outbox, so changes committed by any instance are
+// seen in commit order, and stream can be resumed after reconnect
+type AvailabilityStream struct {
+	stream     *mongo.ChangeStream
+	controller *AvailabilityController
+	roomID     primitive.ObjectID
+}
+
+// Opens stream of changes after lastEventID, or from now if it's empty.
+// Zero roomID means all rooms of the hotel
+func (self *AvailabilityController) Watch(
+	ctx context.Context, hotelID primitive.ObjectID, roomID primitive.ObjectID,
+	lastEventID string,
+) (*AvailabilityStream, error) {
+	if len(lastEventID) != 0 && !resumeTokenRegex.MatchString(lastEventID) {
+		return nil, NewFieldError("lastEventId", "Invalid event id")
+	}
+	stream, err := self.Store.DB.Outbox.WatchInserts(
+		ctx,
+		bson.M{
+			"fullDocument.hotelID": hotelID,
+			"fullDocument.type":    bson.M{"$in": availabilityEventTypes},
+		},
+		lastEventID, time.Second,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &AvailabilityStream{stream: stream, controller: self, roomID: roomID}, nil
+}
+
+func (self *AvailabilityController) WatchRoom(
+	ctx context.Context, roomID primitive.ObjectID, lastEventID string,
+) (*AvailabilityStream, error) {
+	room, err := self.Store.CT.Rooms.GetByID(ctx, roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, NotFoundError{Entity: "Room"}
+	}
+	err = RequireHotelAccess(ctx, room.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	return self.Watch(ctx, room.HotelID, roomID, lastEventID)
+}
+
+func (self *AvailabilityController) WatchHotel(
+	ctx context.Context, hotelID primitive.ObjectID, lastEventID string,
+) (*AvailabilityStream, error) {
+	err := RequireHotelAccess(ctx, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	hotel, err := self.Store.CT.Hotels.GetByID(ctx, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	if hotel == nil {
+		return nil, NotFoundError{Entity: "Hotel"}
+	}
+	return self.Watch(ctx, hotelID, primitive.ObjectID{}, lastEventID)
+}
+
+// Rooms whose booked dates are affected by event
+func changedRoomIDs(event *types.Event) ([]primitive.ObjectID, error) {
+	switch event.Type {
+	case types.BookingChangedEventType:
+		var changed types.BookingChanged
+		err := event.Decode(&changed)
+		if err != nil {
+			return nil, err
+		}
+		if changed.Before.RoomID == changed.After.RoomID {
+			return []primitive.ObjectID{changed.After.RoomID}, nil
+		}
+		return []primitive.ObjectID{changed.Before.RoomID, changed.After.RoomID}, nil
+	default:
+		// Other availability events carry the booking alone
+		var created types.BookingCreated
+		err := event.Decode(&created)
+		if err != nil {
+			return nil, err
+		}
+		return []primitive.ObjectID{created.Booking.RoomID}, nil
+	}
+}
+
+// Waits up to a second for the next event. Returns no changes if there
+// was none, so caller can send heartbeats in between. Event id is used
+// by clients to resume the stream
+func (self *AvailabilityStream) Next(
+	ctx context.Context,
+) ([]*types.AvailabilityChange, string, error) {
+	if !self.stream.TryNext(ctx) {
+		return nil, "", self.stream.Err()
+	}
+	var changeEvent struct {
+		FullDocument types.Event `bson:"fullDocument"`
+	}
+	err := self.stream.Decode(&changeEvent)
+	if err != nil {
+		return nil, "", err
+	}
+	event := &changeEvent.FullDocument
+	eventID := self.stream.ResumeToken().Lookup("_data").StringValue()
+
+	roomIDs, err := changedRoomIDs(event)
+	if err != nil {
+		return nil, "", err
+	}
+	changes := []*types.AvailabilityChange{}
+	for _, roomID := range roomIDs {
+		if !self.roomID.IsZero() && roomID != self.roomID {
+			continue
+		}
+		bookedDates, err := self.controller.Store.CT.Bookings.GetOccupiedForRoom(ctx, roomID)
+		if err != nil {
+			return nil, "", err
+		}
+		changes = append(changes, &types.AvailabilityChange{
+			HotelID:     event.HotelID,
+			RoomID:      roomID,
+			Reason:      event.Type,
+			BookedDates: bookedDates,
+		})
+	}
+	return changes, eventID, nil
+}
+
+func (self *AvailabilityStream) Close(ctx context.Context) error {
+	return self.stream.Close(ctx)
+}
+
+type AvailabilityStreamQueryParams struct {
+	// Alternative to Last-Event-ID header
+	LastEventID string `json:"lastEventId"`
+	// Alternative to Authorization header, browsers can't set headers
+	// of EventSource requests
+	AccessToken string `json:"access_token"`
+}
+
+type AvailabilitySearchQueryParams struct {
+	DateFrom civil.Date `json:"dateFrom" validate:"required"`
+	DateTo   civil.Date `json:"dateTo" validate:"required,gtfield=dateFrom"`
+	// Defaults to 1
+	Guests int `json:"guests"`
+}
+
+// Free rooms of hotel for stay, each with rate plans it can be booked with.
+// Rooms stay restrictions don't allow the stay in are left out
+func (self *AvailabilityController) Search(
+	ctx context.Context, hotelID primitive.ObjectID, params *AvailabilitySearchQueryParams,
+) ([]*types.RoomAvailability, error) {
+	if params.Guests < 0 {
+		return nil, NewFieldError("guests", "Number of guests can't be negative")
+	}
+	err := RequireHotelAccess(ctx, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	hotel, err := self.Store.CT.Hotels.GetByID(ctx, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	if hotel == nil {
+		return nil, NotFoundError{Entity: "Hotel"}
+	}
+	userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.Rooms.Get(
+		ctx, notDeleted(bson.M{"hotelID": hotelID}), []*types.Room{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	rooms := CastInterface[[]*types.Room](result)
+	ratePlans, err := self.Store.CT.RatePlans.getForHotel(ctx, hotelID)
+	if err != nil {
+		return nil, err
+	}
+	restrictions, err := self.Store.CT.Restrictions.getForStay(
+		ctx, hotelID, params.DateFrom, params.DateTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	today := civil.DateOf(time.Now())
+	nights := params.DateTo.DaysSince(params.DateFrom)
+	guests := params.Guests
+	if guests == 0 {
+		guests = 1
+	}
+	availability := []*types.RoomAvailability{}
+	for _, room := range rooms {
+		restrictionErrors := StayRestrictionErrors(
+			restrictions, room.Type, params.DateFrom, params.DateTo,
+		)
+		if len(restrictionErrors) != 0 {
+			continue
+		}
+		isRoomFree, err := self.Store.CT.Bookings.IsRoomFreeForDate(
+			ctx, primitive.ObjectID{}, primitive.ObjectID{}, room.ID,
+			params.DateFrom, params.DateTo,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !isRoomFree {
+			continue
+		}
+		options := []*types.RateOption{}
+		if len(ratePlans) == 0 {
+			options = append(options, &types.RateOption{
+				Name:         "Standard rate",
+				Inclusions:   []string{},
+				RoomCost:     RatePlanRoomCost(nil, room.Price, nights, guests),
+				Cancellation: types.CancellationPolicy{Refundable: true},
+			})
+		}
+		for _, ratePlan := range ratePlans {
+			mismatch := RatePlanMismatch(
+				ratePlan, room.Type, params.DateFrom, params.DateTo, today, true,
+			)
+			if len(mismatch) == 0 {
+				mismatch = ratePlanPriceMismatch(ratePlan, room.Price)
+			}
+			if len(mismatch) == 0 {
+				mismatch, err = self.Store.CT.RatePlans.memberMismatch(ctx, ratePlan, userID)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if len(mismatch) != 0 {
+				continue
+			}
+			options = append(options, &types.RateOption{
+				RatePlanID:   ratePlan.ID,
+				Name:         ratePlan.Name,
+				Kind:         ratePlan.Kind,
+				Inclusions:   ratePlan.Inclusions,
+				RoomCost:     RatePlanRoomCost(ratePlan, room.Price, nights, guests),
+				Cancellation: ratePlan.Cancellation,
+			})
+		}
+		if len(options) != 0 {
+			availability = append(availability, &types.RoomAvailability{Room: room, Options: options})
+		}
+	}
+	return availability, nil
+}
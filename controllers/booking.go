@@ -2,14 +2,21 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"hotel/types"
+	"time"
 
 	"cloud.google.com/go/civil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var errCancellationNotAllowed = ConflictError{
+	Code:    CancellationNotAllowedCode,
+	Message: "Rate plan of booking doesn't allow cancelling it anymore",
+}
+
 type BookingController struct {
 	Store *Store
 }
@@ -36,10 +43,19 @@ func (self *BookingController) BookingToUnfolded(ctx context.Context, booking *t
 	}, nil
 }
 
+func bookingHotelID(booking *types.BookingUnfolded) primitive.ObjectID {
+	if booking.Room == nil {
+		return primitive.ObjectID{}
+	}
+	return booking.Room.HotelID
+}
+
 func (self *BookingController) GetByID(
 	ctx context.Context, id primitive.ObjectID,
 ) (*types.Booking, error) {
-	result, err := self.Store.DB.Bookings.GetOneByID(ctx, id, &types.Booking{})
+	result, err := self.Store.DB.Bookings.GetOne(
+		ctx, notDeleted(bson.M{"_id": id}), &types.Booking{},
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -53,12 +69,32 @@ func (self *BookingController) GetUnfoldedByID(
 	if err != nil {
 		return nil, err
 	}
-	return self.BookingToUnfolded(ctx, booking)
+	bookingUnfolded, err := self.BookingToUnfolded(ctx, booking)
+	if err != nil {
+		return nil, err
+	}
+	err = self.requireBookingAccess(ctx, bookingUnfolded)
+	if err != nil {
+		return nil, err
+	}
+	return bookingUnfolded, nil
+}
+
+// Checks booking's hotel against API key scope
+func (self *BookingController) requireBookingAccess(
+	ctx context.Context, booking *types.BookingUnfolded,
+) error {
+	if booking == nil || booking.Room == nil {
+		return nil
+	}
+	return RequireHotelAccess(ctx, booking.Room.HotelID)
 }
 
 type BookingGetQueryParams struct {
 	UserID primitive.ObjectID `bson:"userID,omitempty" json:"-"`
 	RoomID primitive.ObjectID `bson:"roomID,omitempty" json:"roomID"`
+	// Lists deleted bookings instead, admin only
+	Deleted bool `bson:"-" json:"deleted"`
 }
 
 func (self *BookingController) Get(
@@ -68,13 +104,51 @@ func (self *BookingController) Get(
 		query = &BookingGetQueryParams{}
 	}
 	user, err := GetUserFromContext(self.Store.DB, ctx)
-	if !user.IsAdmin {
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	// API keys only see bookings of their owner, even if owner is admin
+	if !user.IsAdmin || GetAPIKeyFromContext(ctx) != nil {
 		query.UserID = user.ID
 	}
+	filter := bson.M{}
+	if !query.UserID.IsZero() {
+		filter["userID"] = query.UserID
+	}
+	if !query.RoomID.IsZero() {
+		filter["roomID"] = query.RoomID
+	}
+	// Bookings are scoped by hotels of their rooms, deleted ones included
+	if scope := hotelScopeQuery(ctx); scope != nil {
+		if query.RoomID.IsZero() {
+			result, err := self.Store.DB.Rooms.Get(ctx, bson.M{"hotelID": scope}, []*types.Room{})
+			if err != nil {
+				return nil, err
+			}
+			filter["roomID"] = bson.M{"$in": roomIDs(CastInterface[[]*types.Room](result))}
+		} else {
+			result, err := self.Store.DB.Rooms.GetOneByID(ctx, query.RoomID, &types.Room{})
+			if err != nil {
+				return nil, err
+			}
+			room := CastPtrInterface[types.Room](result)
+			if room == nil {
+				return nil, NotFoundError{Entity: "Room"}
+			}
+			err = RequireHotelAccess(ctx, room.HotelID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	filter, err = deletedFilter(self.Store.DB, ctx, filter, query.Deleted)
 	if err != nil {
 		return nil, err
 	}
-	result, err := self.Store.DB.Bookings.Get(ctx, query, []*types.Booking{})
+	result, err := self.Store.DB.Bookings.Get(ctx, filter, []*types.Booking{})
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +158,7 @@ func (self *BookingController) Get(
 func (self *BookingController) GetOccupiedForRoom(
 	ctx context.Context, roomID primitive.ObjectID,
 ) ([]*types.BookingDates, error) {
-	query := &BookingGetQueryParams{RoomID: roomID}
+	query := notDeleted(bson.M{"roomID": roomID})
 	result, err := self.Store.DB.Bookings.Get(ctx, query, []*types.BookingDates{})
 	if err != nil {
 		return nil, err
@@ -92,121 +166,682 @@ func (self *BookingController) GetOccupiedForRoom(
 	return CastInterface[[]*types.BookingDates](result), nil
 }
 
+// Room is free if neither other bookings nor active holds overlap dates.
+// Booking and hold being checked are skipped, zero ids skip nothing
 func (self *BookingController) IsRoomFreeForDate(
-	ctx context.Context, bookingID primitive.ObjectID, roomID primitive.ObjectID,
-	dateFrom civil.Date, dateTo civil.Date,
+	ctx context.Context, bookingID primitive.ObjectID, holdID primitive.ObjectID,
+	roomID primitive.ObjectID, dateFrom civil.Date, dateTo civil.Date,
 ) (bool, error) {
-	filter := bson.M{
+	filter := notDeleted(bson.M{
 		"roomID":   bson.M{"$eq": roomID},
 		"_id":      bson.M{"$ne": bookingID},
 		"dateFrom": bson.M{"$lte": dateTo},
 		"dateTo":   bson.M{"$gte": dateFrom},
-	}
+	})
 
 	count, err := self.Store.DB.Bookings.GetCount(ctx, filter)
+	if err != nil || count != 0 {
+		return false, err
+	}
+	count, err = self.Store.DB.Holds.GetCount(ctx, activeHoldsFilter(bson.M{
+		"roomID":   roomID,
+		"_id":      bson.M{"$ne": holdID},
+		"dateFrom": bson.M{"$lte": dateTo},
+		"dateTo":   bson.M{"$gte": dateFrom},
+	}, time.Now()))
 	if err != nil {
 		return false, err
 	}
 	return count == 0, nil
 }
 
-func (self *BookingController) Validate(booking *types.BookingUnfolded) (map[string]string, error) {
+// Locks room within transaction and checks it's still free for dates, so
+// concurrent bookings and holds can't take the same room. Transactions
+// locking the same room conflict on its counter and the later is retried
+func (self *BookingController) reserveRoom(
+	ctx context.Context, bookingID primitive.ObjectID, holdID primitive.ObjectID,
+	roomID primitive.ObjectID, dateFrom civil.Date, dateTo civil.Date,
+) error {
+	err := self.lockRoom(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	isRoomFree, err := self.IsRoomFreeForDate(ctx, bookingID, holdID, roomID, dateFrom, dateTo)
+	if err != nil {
+		return err
+	}
+	if !isRoomFree {
+		return NewFieldError("roomID", "This room is occupied for this dates")
+	}
+	return nil
+}
+
+// Bumps room's counter, so transactions locking the same room conflict
+func (self *BookingController) lockRoom(
+	ctx context.Context, roomID primitive.ObjectID,
+) error {
+	_, err := self.Store.DB.Counters.NextSequence(ctx, "room:"+roomID.Hex())
+	return err
+}
+
+// Reports whether any of rooms has bookings, which aren't cancelled
+// and haven't ended before date, or active holds
+func (self *BookingController) HasActiveFrom(
+	ctx context.Context, roomIDs []primitive.ObjectID, date civil.Date,
+) (bool, error) {
+	count, err := self.Store.DB.Bookings.GetCount(ctx, notDeleted(bson.M{
+		"roomID": bson.M{"$in": roomIDs},
+		"dateTo": bson.M{"$gte": date},
+	}))
+	if err != nil || count != 0 {
+		return count != 0, err
+	}
+	count, err = self.Store.DB.Holds.GetCount(ctx, activeHoldsFilter(bson.M{
+		"roomID": bson.M{"$in": roomIDs},
+		"dateTo": bson.M{"$gte": date},
+	}, time.Now()))
+	if err != nil {
+		return false, err
+	}
+	return count != 0, nil
+}
+
+// Stay restrictions are checked for new bookings and changed stays only,
+// so rules added later don't block changes of guests or restores
+func (self *BookingController) Validate(
+	ctx context.Context, booking *types.BookingUnfolded, bookingBefore *types.BookingUnfolded,
+) (map[string]string, error) {
 	errors := map[string]string{}
-	if booking.Room == nil {
+	if booking.Room == nil || booking.Room.DeletedAt != nil {
 		errors["roomID"] = fmt.Sprintf("Room not found")
-	}
-	if booking.Room != nil {
-		isRoomFree, err := self.IsRoomFreeForDate(context.Background(), booking.ID, booking.Room.ID, booking.DateFrom, booking.DateTo)
+	} else {
+		isRoomFree, err := self.IsRoomFreeForDate(
+			ctx, booking.ID, booking.HoldID, booking.Room.ID,
+			booking.DateFrom, booking.DateTo,
+		)
 		if err != nil {
 			return errors, err
 		}
 		if !isRoomFree {
 			errors["roomID"] = fmt.Sprintf("This room is occupied for this dates")
 		}
+		if bookingBefore == nil || bookingBefore.RoomID != booking.RoomID ||
+			bookingBefore.DateFrom != booking.DateFrom || bookingBefore.DateTo != booking.DateTo {
+			restrictionErrors, err := self.Store.CT.Restrictions.stayErrors(
+				ctx, booking.Room, booking.DateFrom, booking.DateTo,
+			)
+			if err != nil {
+				return errors, err
+			}
+			for field, msg := range restrictionErrors {
+				if _, ok := errors[field]; !ok {
+					errors[field] = msg
+				}
+			}
+		}
 	}
 	if booking.User == nil {
 		errors["userID"] = fmt.Sprintf("User not found")
 	}
-	if booking.DateTo.Before(booking.DateFrom) {
-		errors["dateTo"] = fmt.Sprintf("Date to can't be less than date from")
+	if booking.Guests < 0 {
+		errors["guests"] = "Number of guests can't be negative"
 	}
 	return errors, nil
 }
 
-func (self *BookingController) Evaluate(booking *types.BookingUnfolded) error {
-	booking.TotalCost = booking.Room.Price * float64(booking.DateTo.DaysSince(booking.DateFrom))
+// Costs are kept as agreed, unless room, dates or guests change.
+// Otherwise they're recalculated with current taxes and fees of hotel,
+// which apply to room cost after discount
+func (self *BookingController) Evaluate(
+	ctx context.Context, booking *types.BookingUnfolded, bookingBefore *types.BookingUnfolded,
+) error {
+	if bookingBefore != nil && bookingBefore.RoomID == booking.RoomID &&
+		bookingBefore.DateFrom == booking.DateFrom &&
+		bookingBefore.DateTo == booking.DateTo &&
+		bookingBefore.GuestCount() == booking.GuestCount() &&
+		bookingBefore.RatePlanID == booking.RatePlanID {
+		booking.RatePlan = bookingBefore.RatePlan
+		booking.RoomCost = bookingBefore.RoomCost
+		booking.Discount = bookingBefore.Discount
+		booking.RedeemedPoints = bookingBefore.RedeemedPoints
+		booking.Charges = bookingBefore.Charges
+		booking.TotalCost = bookingBefore.TotalCost
+		return nil
+	}
+	result, err := self.Store.DB.Hotels.GetOneByID(ctx, booking.Room.HotelID, &types.Hotel{})
+	if err != nil {
+		return err
+	}
+	rules := []*types.TaxRule{}
+	if hotel := CastPtrInterface[types.Hotel](result); hotel != nil {
+		rules = hotel.TaxRules
+	}
+	ratePlan, err := self.selectRatePlan(ctx, booking, bookingBefore)
+	if err != nil {
+		return err
+	}
+	nights := booking.DateTo.DaysSince(booking.DateFrom)
+	booking.RoomCost = RatePlanRoomCost(ratePlan, booking.Room.Price, nights, booking.GuestCount())
+	err = self.applyDiscount(ctx, booking, bookingBefore)
+	if err != nil {
+		return err
+	}
+	roomCost := booking.RoomCost
+	if booking.Discount != nil {
+		roomCost = roomCost.Sub(booking.Discount.Amount)
+	}
+	err = self.applyPoints(ctx, booking, bookingBefore, roomCost)
+	if err != nil {
+		return err
+	}
+	if booking.RedeemedPoints != nil {
+		roomCost = roomCost.Sub(booking.RedeemedPoints.Amount)
+	}
+	booking.Charges, booking.TotalCost = ApplyTaxRules(
+		rules, roomCost, nights, booking.GuestCount(),
+	)
+	return nil
+}
+
+// Rate plan booking is sold with. Once hotel has rate plans, one of them
+// should be chosen, hotels without them sell rooms at room price
+func (self *BookingController) selectRatePlan(
+	ctx context.Context, booking *types.BookingUnfolded, bookingBefore *types.BookingUnfolded,
+) (*types.RatePlan, error) {
+	booking.RatePlan = nil
+	if booking.RatePlanID.IsZero() {
+		count, err := self.Store.DB.RatePlans.GetCount(ctx, bson.M{"hotelID": booking.Room.HotelID})
+		if err != nil {
+			return nil, err
+		}
+		if count != 0 {
+			return nil, NewFieldError("ratePlanID", "Choose one of hotel's rate plans")
+		}
+		return nil, nil
+	}
+	ratePlan, err := self.Store.CT.RatePlans.getByID(ctx, booking.RatePlanID)
+	if err != nil {
+		return nil, err
+	}
+	if ratePlan == nil || ratePlan.HotelID != booking.Room.HotelID {
+		return nil, NewFieldError("ratePlanID", "Rate plan isn't sold by this hotel")
+	}
+	isNewPlan := bookingBefore == nil || bookingBefore.RatePlanID != booking.RatePlanID
+	mismatch := RatePlanMismatch(
+		ratePlan, booking.Room.Type, booking.DateFrom, booking.DateTo, civil.DateOf(time.Now()),
+		isNewPlan || bookingBefore.DateFrom != booking.DateFrom,
+	)
+	if len(mismatch) == 0 && isNewPlan {
+		mismatch, err = self.Store.CT.RatePlans.memberMismatch(ctx, ratePlan, booking.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(mismatch) == 0 {
+		mismatch = ratePlanPriceMismatch(ratePlan, booking.Room.Price)
+	}
+	if len(mismatch) != 0 {
+		return nil, NewFieldError("ratePlanID", mismatch)
+	}
+	booking.RatePlan = &types.BookingRatePlan{
+		Name:         ratePlan.Name,
+		Kind:         ratePlan.Kind,
+		Inclusions:   ratePlan.Inclusions,
+		Cancellation: ratePlan.Cancellation,
+	}
+	return ratePlan, nil
+}
+
+// New bookings get promotion of entered code or the best automatic one.
+// Changed bookings keep promotion they were made with, while stay
+// qualifies for it, and never get another one
+func (self *BookingController) applyDiscount(
+	ctx context.Context, booking *types.BookingUnfolded, bookingBefore *types.BookingUnfolded,
+) error {
+	var promotion *types.Promotion
+	var err error
+	now := time.Now()
+	switch {
+	case bookingBefore != nil:
+		if bookingBefore.Discount == nil {
+			break
+		}
+		promotion, err = self.Store.CT.Promotions.getByID(ctx, bookingBefore.Discount.PromotionID)
+		if promotion != nil && len(PromotionMismatch(promotion, booking)) != 0 {
+			promotion = nil
+		}
+	case len(booking.PromoCode) != 0:
+		promotion, err = self.Store.CT.Promotions.findByCode(ctx, booking.PromoCode, booking, now)
+	default:
+		promotion, err = self.Store.CT.Promotions.findBestAutomatic(ctx, booking, now)
+	}
+	if err != nil {
+		return err
+	}
+	booking.Discount = nil
+	if promotion != nil {
+		booking.Discount = &types.BookingDiscount{
+			PromotionID: promotion.ID,
+			Code:        promotion.Code,
+			Name:        promotion.Name,
+			Amount:      PromotionDiscount(promotion, booking.RoomCost),
+		}
+	}
 	return nil
 }
 
-func (self *BookingController) Create(
+// Spends points guest asked for on new booking. Changed bookings keep
+// points spent, their discount is capped by new cost
+func (self *BookingController) applyPoints(
+	ctx context.Context, booking *types.BookingUnfolded, bookingBefore *types.BookingUnfolded,
+	cost types.Money,
+) error {
+	points := booking.PointsToRedeem
+	if bookingBefore != nil {
+		points = 0
+		if bookingBefore.RedeemedPoints != nil {
+			points = bookingBefore.RedeemedPoints.Points
+		}
+	}
+	booking.RedeemedPoints = nil
+	if points == 0 {
+		return nil
+	}
+	amount := LoyaltyPointsValue(points, cost.Currency)
+	if bookingBefore != nil {
+		if amount.Cmp(cost) > 0 {
+			amount = cost
+		}
+		booking.RedeemedPoints = &types.RedeemedPoints{Points: points, Amount: amount}
+		return nil
+	}
+	if points < 0 {
+		return NewFieldError("loyaltyPoints", "Points can't be negative")
+	}
+	if amount.Cmp(cost) > 0 {
+		return NewFieldError("loyaltyPoints", "Points are worth more than the stay")
+	}
+	account, err := self.Store.CT.Loyalty.getAccount(ctx, booking.UserID)
+	if err != nil {
+		return err
+	}
+	if account.Balance < points {
+		return NewFieldError(
+			"loyaltyPoints", fmt.Sprintf("Only %d points are available", account.Balance),
+		)
+	}
+	booking.RedeemedPoints = &types.RedeemedPoints{Points: points, Amount: amount}
+	return nil
+}
+
+// Validates and prices new booking of user from context
+func (self *BookingController) prepareNew(
 	ctx context.Context, booking *types.Booking,
 ) (*types.BookingUnfolded, error) {
-	userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	// Bookings are made for guest of context, unless made for someone
+	// else already, like holder of converted hold
+	if booking.UserID.IsZero() {
+		userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+		if err != nil {
+			return nil, err
+		}
+		booking.UserID = userID
+	}
+	bookingUnfolded, err := self.BookingToUnfolded(ctx, booking)
 	if err != nil {
 		return nil, err
 	}
-	booking.UserID = userID
-	bookingUnfolded, err := self.BookingToUnfolded(ctx, booking)
+	err = self.requireBookingAccess(ctx, bookingUnfolded)
 	if err != nil {
 		return nil, err
 	}
-	fieldErrors, err := self.Validate(bookingUnfolded)
+	fieldErrors, err := self.Validate(ctx, bookingUnfolded, nil)
 	if err != nil {
 		return nil, err
 	}
 	if len(fieldErrors) != 0 {
 		return nil, ValidationError{Fields: fieldErrors}
 	}
-	err = self.Evaluate(bookingUnfolded)
+	err = self.Evaluate(ctx, bookingUnfolded, nil)
 	if err != nil {
 		return nil, err
 	}
-	id, err := self.Store.DB.Bookings.Create(ctx, bookingUnfolded.Booking)
+	return bookingUnfolded, nil
+}
+
+// Prices booking with discount, taxes and fees without making it.
+// Promotion and points aren't redeemed, so they may be used up by the
+// time of booking
+func (self *BookingController) Quote(
+	ctx context.Context, booking *types.Booking,
+) (*types.BookingUnfolded, error) {
+	return self.prepareNew(ctx, booking)
+}
+
+func (self *BookingController) Create(
+	ctx context.Context, booking *types.Booking,
+) (*types.BookingUnfolded, error) {
+	bookingUnfolded, err := self.prepareNew(ctx, booking)
 	if err != nil {
 		return nil, err
 	}
-	created, err := self.GetByID(ctx, id)
+	bookingUnfolded.Version = 1
+	bookingUnfolded.Status = types.ConfirmedBookingStatus
+	var created *types.Booking
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := self.reserveRoom(
+			ctx, primitive.ObjectID{}, bookingUnfolded.HoldID, bookingUnfolded.RoomID,
+			bookingUnfolded.DateFrom, bookingUnfolded.DateTo,
+		)
+		if err != nil {
+			return err
+		}
+		id, err := self.Store.DB.Bookings.Create(ctx, bookingUnfolded.Booking)
+		if err != nil {
+			return err
+		}
+		bookingUnfolded.ID = id
+		if !bookingUnfolded.HoldID.IsZero() {
+			err = self.Store.CT.Holds.convert(ctx, bookingUnfolded.HoldID, id)
+			if err != nil {
+				return err
+			}
+		}
+		err = self.Store.CT.Promotions.redeem(ctx, bookingUnfolded.Booking)
+		if err != nil {
+			return err
+		}
+		err = self.Store.CT.Loyalty.redeem(ctx, bookingUnfolded.Booking, time.Now())
+		if err != nil {
+			return err
+		}
+		err = emitEvent(ctx, self.Store.DB, &types.BookingCreated{
+			HotelID: bookingHotelID(bookingUnfolded), Booking: bookingUnfolded.Booking,
+		})
+		if err != nil {
+			return err
+		}
+		created, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Booking", id, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
+	self.notifyAndLog(ctx, types.BookingConfirmedNotificationKind, created)
 	return self.BookingToUnfolded(ctx, created)
 }
 
 func (self *BookingController) UpdateByID(
 	ctx context.Context, id primitive.ObjectID, booking *types.Booking,
 ) (*types.BookingUnfolded, error) {
-	booking.ID = id
-	userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	bookingBefore, err := self.GetUnfoldedByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	booking.UserID = userID
+	if bookingBefore == nil {
+		return nil, NotFoundError{Entity: "Booking"}
+	}
+	err = RequireOwnerOrAdmin(self.Store.DB, ctx, bookingBefore.UserID, "Booking")
+	if err != nil {
+		return nil, err
+	}
+	err = CheckVersion("Booking", booking.Version, bookingBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	booking.ID = id
+	// Booking stays with its guest, even if updated by admin
+	booking.UserID = bookingBefore.UserID
+	booking.HoldID = bookingBefore.HoldID
+	booking.Status = bookingBefore.Status
+	booking.ReminderSentAt = bookingBefore.ReminderSentAt
 	bookingUnfolded, err := self.BookingToUnfolded(ctx, booking)
 	if err != nil {
 		return nil, err
 	}
+	err = self.requireBookingAccess(ctx, bookingUnfolded)
+	if err != nil {
+		return nil, err
+	}
 
-	fieldErrors, err := self.Validate(bookingUnfolded)
+	fieldErrors, err := self.Validate(ctx, bookingUnfolded, bookingBefore)
 	if err != nil {
 		return nil, err
 	}
 	if len(fieldErrors) != 0 {
 		return nil, ValidationError{Fields: fieldErrors}
 	}
-	err = self.Evaluate(bookingUnfolded)
+	err = self.Evaluate(ctx, bookingUnfolded, bookingBefore)
 	if err != nil {
 		return nil, err
 	}
 
-	err = self.Store.DB.Bookings.UpdateByID(ctx, id, bookingUnfolded.Booking)
+	changed, err := ChangedFields(bookingBefore.Booking, bookingUnfolded.Booking)
 	if err != nil {
 		return nil, err
 	}
-	return self.GetUnfoldedByID(ctx, id)
+	var updated *types.BookingUnfolded
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		if bookingBefore.RoomID != bookingUnfolded.RoomID ||
+			bookingBefore.DateFrom != bookingUnfolded.DateFrom ||
+			bookingBefore.DateTo != bookingUnfolded.DateTo {
+			err := self.reserveRoom(
+				ctx, id, bookingUnfolded.HoldID, bookingUnfolded.RoomID,
+				bookingUnfolded.DateFrom, bookingUnfolded.DateTo,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Bookings, "Booking", id, bookingBefore.Version,
+			bookingBefore.Booking, bookingUnfolded.Booking,
+		)
+		if err != nil {
+			return err
+		}
+		if len(changed) != 0 {
+			if bookingBefore.Discount != nil && bookingUnfolded.Discount == nil {
+				err = self.Store.CT.Promotions.release(ctx, id, time.Now())
+				if err != nil {
+					return err
+				}
+			}
+			err = emitEvent(ctx, self.Store.DB, &types.BookingChanged{
+				HotelID: bookingHotelID(bookingUnfolded),
+				Before:  bookingBefore.Booking,
+				After:   bookingUnfolded.Booking,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		updated, err = self.GetUnfoldedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Booking", id, bookingBefore.Booking, updated.Booking,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(changed) != 0 {
+		self.notifyAndLog(ctx, types.BookingModifiedNotificationKind, updated.Booking)
+	}
+	return updated, nil
 }
 
 func (self *BookingController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	booking, err := self.GetUnfoldedByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if booking == nil {
+		return NotFoundError{Entity: "Booking"}
+	}
+	err = RequireOwnerOrAdmin(self.Store.DB, ctx, booking.UserID, "Booking")
+	if err != nil {
+		return err
+	}
+	err = CheckVersion("Booking", version, booking.Version)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	// Guests cancel by rate plan's policy, admins regardless of it
+	if !FreeCancellationAllowed(booking.RatePlan, booking.DateFrom, civil.DateOf(now)) {
+		_, err = RequireAdmin(self.Store.DB, ctx)
+		if errors.Is(err, ErrAdminOnly) {
+			return errCancellationNotAllowed
+		}
+		if err != nil {
+			return err
+		}
+	}
+	deleted := *booking.Booking
+	deleted.DeletedAt = &now
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := softDeleteByID(ctx, self.Store.DB.Bookings, "Booking", id, booking.Version, now)
+		if err != nil {
+			return err
+		}
+		err = self.Store.CT.Promotions.release(ctx, id, now)
+		if err != nil {
+			return err
+		}
+		err = self.Store.CT.Loyalty.reverse(ctx, booking.Booking, now)
+		if err != nil {
+			return err
+		}
+		err = emitEvent(ctx, self.Store.DB, &types.BookingCancelled{
+			HotelID: bookingHotelID(booking), Booking: &deleted,
+		})
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.DeleteAuditAction, "Booking", id, booking.Booking, &deleted,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	self.notifyAndLog(ctx, types.BookingCancelledNotificationKind, &deleted)
+	self.Store.CT.Waitlist.offerFreedAndLog(ctx, booking)
+	return nil
+}
+
+// Brings cancelled booking back, if its room is still free for the dates
+func (self *BookingController) RestoreByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.BookingUnfolded, error) {
+	result, err := self.Store.DB.Bookings.GetOneByID(ctx, id, &types.Booking{})
+	if err != nil {
+		return nil, err
+	}
+	booking, err := self.BookingToUnfolded(ctx, CastPtrInterface[types.Booking](result))
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil {
+		return nil, NotFoundError{Entity: "Booking"}
+	}
+	err = self.requireBookingAccess(ctx, booking)
+	if err != nil {
+		return nil, err
+	}
+	if booking.DeletedAt == nil {
+		return booking, nil
+	}
+	// Restored booking keeps stay it was made with
+	fieldErrors, err := self.Validate(ctx, booking, booking)
+	if err != nil {
+		return nil, err
+	}
+	if len(fieldErrors) != 0 {
+		return nil, ValidationError{Fields: fieldErrors}
+	}
+	restored := *booking.Booking
+	restored.DeletedAt = nil
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := self.reserveRoom(
+			ctx, id, restored.HoldID, restored.RoomID, restored.DateFrom, restored.DateTo,
+		)
+		if err != nil {
+			return err
+		}
+		err = restoreByID(ctx, self.Store.DB.Bookings, id)
+		if err != nil {
+			return err
+		}
+		// Promotion and points may have been used up meanwhile
+		err = self.Store.CT.Promotions.redeem(ctx, &restored)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = self.Store.CT.Loyalty.redeem(ctx, &restored, now)
+		if err != nil {
+			return err
+		}
+		if restored.Status == types.CheckedOutBookingStatus {
+			err = self.Store.CT.Loyalty.accrue(ctx, &restored, now)
+			if err != nil {
+				return err
+			}
+		}
+		err = emitEvent(ctx, self.Store.DB, &types.BookingRestored{
+			HotelID: bookingHotelID(booking), Booking: &restored,
+		})
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.RestoreAuditAction, "Booking", id, booking.Booking, &restored,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return self.GetUnfoldedByID(ctx, id)
+}
+
+// Removes deleted booking permanently
+func (self *BookingController) PurgeByID(
 	ctx context.Context, id primitive.ObjectID,
 ) error {
-	return self.Store.DB.Bookings.DeleteByID(ctx, id)
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	result, err := self.Store.DB.Bookings.GetOneByID(ctx, id, &types.Booking{})
+	if err != nil {
+		return err
+	}
+	booking := CastPtrInterface[types.Booking](result)
+	if booking == nil {
+		return NotFoundError{Entity: "Booking"}
+	}
+	if booking.DeletedAt == nil {
+		return errNotDeleted("Booking")
+	}
+	return self.purge(ctx, booking)
+}
+
+func (self *BookingController) purge(ctx context.Context, booking *types.Booking) error {
+	id := booking.ID
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Bookings.DeleteByID(ctx, id, 0)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.PurgeAuditAction, "Booking", id, booking, nil)
+	})
 }
@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hotel/types"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Confirmed bookings, including ones made before statuses were introduced
+var confirmedStatusQuery = bson.M{"$in": bson.A{types.ConfirmedBookingStatus, nil}}
+
+func errBookingStatus(message string) ConflictError {
+	return ConflictError{Code: BookingStatusErrorCode, Message: message}
+}
+
+// Moves booking to status, if it's still at its version. Checked out
+// bookings earn loyalty points
+func (self *BookingController) setStatus(
+	ctx context.Context, booking *types.Booking, status types.BookingStatus,
+) (*types.Booking, error) {
+	changed := *booking
+	changed.Status = status
+	err := self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Bookings, "Booking", booking.ID, booking.Version,
+			booking, &changed,
+		)
+		if err != nil {
+			return err
+		}
+		if status == types.CheckedOutBookingStatus {
+			err = self.Store.CT.Loyalty.accrue(ctx, &changed, time.Now())
+			if err != nil {
+				return err
+			}
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Booking", booking.ID, booking, &changed,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status == types.CheckedOutBookingStatus {
+		self.notifyAndLog(ctx, types.BookingReceiptNotificationKind, &changed)
+	}
+	return &changed, nil
+}
+
+// Registers guest's arrival, possible only on arrival date. Later the
+// booking is marked as no-show instead. Admin only
+func (self *BookingController) CheckInByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.BookingUnfolded, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	booking, err := self.GetUnfoldedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil {
+		return nil, NotFoundError{Entity: "Booking"}
+	}
+	if !booking.IsConfirmed() {
+		return nil, errBookingStatus(
+			fmt.Sprintf("Booking can't be checked in from %s status", booking.Status),
+		)
+	}
+	today := civil.DateOf(time.Now())
+	if today != booking.DateFrom {
+		return nil, errBookingStatus("Booking can be checked in only on arrival date")
+	}
+	_, err = self.setStatus(ctx, booking.Booking, types.CheckedInBookingStatus)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetUnfoldedByID(ctx, id)
+}
+
+// Registers guest's departure. Admin only
+func (self *BookingController) CheckOutByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.BookingUnfolded, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	booking, err := self.GetUnfoldedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil {
+		return nil, NotFoundError{Entity: "Booking"}
+	}
+	if booking.Status != types.CheckedInBookingStatus {
+		return nil, errBookingStatus("Only checked in bookings can be checked out")
+	}
+	_, err = self.setStatus(ctx, booking.Booking, types.CheckedOutBookingStatus)
+	if err != nil {
+		return nil, err
+	}
+	return self.GetUnfoldedByID(ctx, id)
+}
+
+// Moves all bookings matching filter to status. Bookings changed
+// concurrently are skipped, next run picks them up if still matching
+func (self *BookingController) setStatusAll(
+	ctx context.Context, filter bson.M, status types.BookingStatus,
+) (int64, error) {
+	result, err := self.Store.DB.Bookings.Get(ctx, notDeleted(filter), []*types.Booking{})
+	if err != nil {
+		return 0, err
+	}
+	changed := int64(0)
+	for _, booking := range CastInterface[[]*types.Booking](result) {
+		_, err = self.setStatus(ctx, booking, status)
+		if errors.As(err, &PreconditionFailedError{}) {
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// Marks confirmed bookings, whose guests haven't checked in by the end
+// of arrival date, as no-shows. Returns number of marked bookings
+func (self *BookingController) MarkNoShows(ctx context.Context, today civil.Date) (int64, error) {
+	return self.setStatusAll(ctx, bson.M{
+		"status":   confirmedStatusQuery,
+		"dateFrom": bson.M{"$lt": today},
+	}, types.NoShowBookingStatus)
+}
+
+// Checks out guests, who didn't do it themselves by the end of departure date
+func (self *BookingController) CheckOutEnded(ctx context.Context, today civil.Date) (int64, error) {
+	return self.setStatusAll(ctx, bson.M{
+		"status": types.CheckedInBookingStatus,
+		"dateTo": bson.M{"$lt": today},
+	}, types.CheckedOutBookingStatus)
+}
+
+// Reminds guests arriving within daysBefore days. Each booking is claimed
+// before sending, so reminder is sent at most once, unless it isn't
+// delivered. Undelivered ones are tried again by the next run
+func (self *BookingController) SendReminders(
+	ctx context.Context, now time.Time, daysBefore int,
+) (int64, error) {
+	today := civil.DateOf(now)
+	sent := int64(0)
+	failedIDs := []primitive.ObjectID{}
+	var errs []error
+	for {
+		result, err := self.Store.DB.Bookings.GetOneAndUpdate(
+			ctx,
+			notDeleted(bson.M{
+				"_id":            bson.M{"$nin": failedIDs},
+				"status":         confirmedStatusQuery,
+				"dateFrom":       bson.M{"$gte": today, "$lte": today.AddDays(daysBefore)},
+				"reminderSentAt": nil,
+			}),
+			bson.M{"$set": bson.M{"reminderSentAt": now}},
+			&types.Booking{},
+		)
+		if err != nil {
+			return sent, errors.Join(append(errs, err)...)
+		}
+		booking := CastPtrInterface[types.Booking](result)
+		if booking == nil {
+			return sent, errors.Join(errs...)
+		}
+		err = self.notify(ctx, types.BookingReminderNotificationKind, booking)
+		if err != nil {
+			// Released for the next run, but skipped by this one
+			_, releaseErr := self.Store.DB.Bookings.Update(
+				ctx, bson.M{"_id": booking.ID}, bson.M{"$unset": bson.M{"reminderSentAt": ""}},
+			)
+			failedIDs = append(failedIDs, booking.ID)
+			errs = append(errs, err, releaseErr)
+			continue
+		}
+		sent++
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"hotel/types"
+	"os"
+)
+
+const defaultCurrency types.Currency = "EUR"
+
+var errCurrencyInUse = ConflictError{
+	Code:    CurrencyInUseErrorCode,
+	Message: "Hotel has rooms, fees or supplements priced in its currency, currency can't change",
+}
+
+// Currency of new hotels, DEFAULT_CURRENCY
+func DefaultCurrency() types.Currency {
+	currency := types.Currency(os.Getenv("DEFAULT_CURRENCY"))
+	if !currency.IsValid() {
+		return defaultCurrency
+	}
+	return currency
+}
+
+// Hotels created before currencies were introduced use the default one
+func HotelCurrency(hotel *types.Hotel) types.Currency {
+	if hotel == nil || len(hotel.Currency) == 0 {
+		return DefaultCurrency()
+	}
+	return hotel.Currency
+}
+
+type DisplayCurrencyQueryParams struct {
+	// Adds prices converted with exchange rates from EXCHANGE_RATES_FILE
+	Currency types.Currency `json:"currency" validate:"enum"`
+}
+
+// Converts amount for display. Nil if no currency is asked for or
+// amount has none, being stored before currencies were introduced
+func (self *Store) DisplayAmount(
+	money types.Money, currency types.Currency,
+) (*types.Money, error) {
+	if len(currency) == 0 || len(money.Currency) == 0 {
+		return nil, nil
+	}
+	if money.Currency == currency {
+		return &money, nil
+	}
+	if self.ExchangeRates == nil {
+		return nil, NewFieldError("currency", "Exchange rates aren't configured")
+	}
+	for _, currency := range []types.Currency{money.Currency, currency} {
+		if !self.ExchangeRates.Has(currency) {
+			return nil, NewFieldError("currency", "No exchange rate for "+string(currency))
+		}
+	}
+	converted, err := self.ExchangeRates.Convert(money, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &converted, nil
+}
+
+func (self *RoomController) WithDisplayPrice(
+	room *types.RoomUnfolded, currency types.Currency,
+) error {
+	price, err := self.Store.DisplayAmount(room.Price, currency)
+	room.DisplayPrice = price
+	return err
+}
+
+func (self *BookingController) WithDisplayTotalCost(
+	booking *types.BookingUnfolded, currency types.Currency,
+) error {
+	totalCost, err := self.Store.DisplayAmount(booking.TotalCost, currency)
+	booking.DisplayTotalCost = totalCost
+	return err
+}
@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+)
+
+// Stable, machine-readable error codes. Clients rely on them,
+// so existing values must never change
+type ErrorCode string
+
+const (
+	ValidationErrorCode          ErrorCode = "validation_failed"
+	NotFoundErrorCode            ErrorCode = "not_found"
+	ConflictErrorCode            ErrorCode = "conflict"
+	ForbiddenErrorCode           ErrorCode = "forbidden"
+	UnauthorizedErrorCode        ErrorCode = "unauthorized"
+	TooManyAttemptsErrorCode     ErrorCode = "too_many_attempts"
+	UpstreamUnavailableErrorCode ErrorCode = "upstream_unavailable"
+	PreconditionFailedErrorCode  ErrorCode = "precondition_failed"
+	PreconditionRequiredCode     ErrorCode = "precondition_required"
+
+	InvalidCredentialsErrorCode ErrorCode = "invalid_credentials"
+	EmailNotVerifiedErrorCode   ErrorCode = "email_not_verified"
+	InvalidAPIKeyErrorCode      ErrorCode = "invalid_api_key"
+	AdminOnlyErrorCode          ErrorCode = "admin_only"
+	PermissionDeniedErrorCode   ErrorCode = "permission_denied"
+	HotelOutOfScopeErrorCode    ErrorCode = "hotel_out_of_scope"
+	HasActiveBookingsErrorCode  ErrorCode = "has_active_bookings"
+	ParentDeletedErrorCode      ErrorCode = "parent_deleted"
+	NotDeletedErrorCode         ErrorCode = "not_deleted"
+	HoldNotActiveErrorCode      ErrorCode = "hold_not_active"
+	BookingStatusErrorCode      ErrorCode = "invalid_booking_status"
+	AlreadyInvoicedErrorCode    ErrorCode = "already_invoiced"
+	CurrencyInUseErrorCode      ErrorCode = "currency_in_use"
+	PromotionExhaustedErrorCode ErrorCode = "promotion_exhausted"
+	InsufficientPointsErrorCode ErrorCode = "insufficient_points"
+	CancellationNotAllowedCode  ErrorCode = "cancellation_not_allowed"
+)
+
+// Implemented by all errors, which are safe to show to API clients
+type CodedError interface {
+	error
+	ErrorCode() ErrorCode
+}
+
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func (self ValidationError) Error() string {
+	errStr := "ValidationError:"
+	for k, v := range self.Fields {
+		errStr += fmt.Sprintf("\n%s: %s", k, v)
+	}
+	return errStr
+}
+
+func (self ValidationError) ErrorCode() ErrorCode {
+	return ValidationErrorCode
+}
+
+func NewFieldError(field string, message string) ValidationError {
+	return ValidationError{Fields: map[string]string{field: message}}
+}
+
+type NotFoundError struct {
+	Entity string
+}
+
+func (self NotFoundError) Error() string {
+	return fmt.Sprintf("%s not found", self.Entity)
+}
+
+func (self NotFoundError) ErrorCode() ErrorCode {
+	return NotFoundErrorCode
+}
+
+// Request can't be applied to the current state of entity
+type ConflictError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (self ConflictError) Error() string {
+	return self.Message
+}
+
+func (self ConflictError) ErrorCode() ErrorCode {
+	if len(self.Code) != 0 {
+		return self.Code
+	}
+	return ConflictErrorCode
+}
+
+// Caller is known, but isn't allowed to perform the action
+type ForbiddenError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (self ForbiddenError) Error() string {
+	return self.Message
+}
+
+func (self ForbiddenError) ErrorCode() ErrorCode {
+	if len(self.Code) != 0 {
+		return self.Code
+	}
+	return ForbiddenErrorCode
+}
+
+// Caller can't be identified
+type UnauthorizedError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (self UnauthorizedError) Error() string {
+	return self.Message
+}
+
+func (self UnauthorizedError) ErrorCode() ErrorCode {
+	if len(self.Code) != 0 {
+		return self.Code
+	}
+	return UnauthorizedErrorCode
+}
+
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (self LoginLockedError) Error() string {
+	return fmt.Sprintf(
+		"Too many failed login attempts, try again in %d seconds",
+		int(self.RetryAfter.Seconds())+1,
+	)
+}
+
+func (self LoginLockedError) ErrorCode() ErrorCode {
+	return TooManyAttemptsErrorCode
+}
+
+// Service we depend on (roomprices, mail server) failed
+type UpstreamUnavailableError struct {
+	Service string
+	Err     error
+}
+
+func (self UpstreamUnavailableError) Error() string {
+	return fmt.Sprintf("%s is unavailable: %s", self.Service, self.Err.Error())
+}
+
+func (self UpstreamUnavailableError) Unwrap() error {
+	return self.Err
+}
+
+func (self UpstreamUnavailableError) ErrorCode() ErrorCode {
+	return UpstreamUnavailableErrorCode
+}
+
+// Entity was changed since client has read it
+type PreconditionFailedError struct {
+	Entity string
+}
+
+func (self PreconditionFailedError) Error() string {
+	return fmt.Sprintf(
+		"%s was modified by someone else, fetch it again and retry", self.Entity,
+	)
+}
+
+func (self PreconditionFailedError) ErrorCode() ErrorCode {
+	return PreconditionFailedErrorCode
+}
+
+// Write requires version the client is based on
+type PreconditionRequiredError struct {
+	Message string
+}
+
+func (self PreconditionRequiredError) Error() string {
+	return self.Message
+}
+
+func (self PreconditionRequiredError) ErrorCode() ErrorCode {
+	return PreconditionRequiredCode
+}
+
+var (
+	ErrAdminOnly = ForbiddenError{
+		Code: AdminOnlyErrorCode, Message: "Only admins can perform this action",
+	}
+	ErrInvalidCredentials = UnauthorizedError{
+		Code: InvalidCredentialsErrorCode, Message: "Invalid credentials",
+	}
+	ErrNotAuthenticated = UnauthorizedError{
+		Message: "Authentication required",
+	}
+	ErrEmailNotVerified = ForbiddenError{
+		Code: EmailNotVerifiedErrorCode, Message: "Email is not verified",
+	}
+	ErrInvalidAPIKey = UnauthorizedError{
+		Code: InvalidAPIKeyErrorCode, Message: "API key is invalid or revoked",
+	}
+	ErrHotelOutOfScope = ForbiddenError{
+		Code: HotelOutOfScopeErrorCode, Message: "API key has no access to this hotel",
+	}
+	ErrPermissionDenied = ForbiddenError{
+		Code: PermissionDeniedErrorCode, Message: "API key has no permission for this action",
+	}
+	ErrPreconditionRequired = PreconditionRequiredError{
+		Message: "If-Match header with entity version is required",
+	}
+)
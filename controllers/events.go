@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"hotel/db"
+	"hotel/types"
+	"time"
+)
+
+// Writes event to outbox. Should be called in the same transaction
+// as the change it describes, so event is stored if and only if the
+// change is. Delivery is done later by events.Dispatcher
+func emitEvent(ctx context.Context, dbStore *db.DB, event types.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = dbStore.Outbox.Create(ctx, &types.Event{
+		Type:          event.EventType(),
+		HotelID:       event.EventHotelID(),
+		Payload:       payload,
+		RequestID:     GetRequestIDFromContext(ctx),
+		CreatedAt:     now,
+		DeliveredTo:   []string{},
+		NextAttemptAt: now,
+	})
+	return err
+}
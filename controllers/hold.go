@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/types"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultHoldTTLMinutes = 15
+
+var errHoldNotActive = ConflictError{
+	Code:    HoldNotActiveErrorCode,
+	Message: "Hold has expired, was released or already converted",
+}
+
+// Limits query to holds locking their rooms at given time
+func activeHoldsFilter(query bson.M, at time.Time) bson.M {
+	query["status"] = types.ActiveHoldStatus
+	query["expiresAt"] = bson.M{"$gt": at}
+	return query
+}
+
+// Default and maximum length of holds, HOLD_TTL_MINUTES
+func HoldTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("HOLD_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = defaultHoldTTLMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+type HoldController struct {
+	Store *Store
+}
+
+func (self *HoldController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Hold, error) {
+	result, err := self.Store.DB.Holds.GetOneByID(ctx, id, &types.Hold{})
+	if err != nil {
+		return nil, err
+	}
+	hold := CastPtrInterface[types.Hold](result)
+	if hold == nil {
+		return nil, nil
+	}
+	err = RequireOwnerOrAdmin(self.Store.DB, ctx, hold.UserID, "Hold")
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// Locks room for dates for given number of minutes, zero means HoldTTL
+func (self *HoldController) Create(
+	ctx context.Context, hold *types.Hold, minutes int,
+) (*types.Hold, error) {
+	ttl := HoldTTL()
+	if minutes < 0 || time.Duration(minutes)*time.Minute > ttl {
+		return nil, NewFieldError(
+			"minutes", fmt.Sprintf("Minutes should be between 1 and %d", int(ttl.Minutes())),
+		)
+	}
+	if minutes != 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+	userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	room, err := self.Store.CT.Rooms.GetByID(ctx, hold.RoomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, NewFieldError("roomID", "Room not found")
+	}
+	err = RequireHotelAccess(ctx, room.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	restrictionErrors, err := self.Store.CT.Restrictions.stayErrors(
+		ctx, room, hold.DateFrom, hold.DateTo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(restrictionErrors) != 0 {
+		return nil, ValidationError{Fields: restrictionErrors}
+	}
+	// Held stay should be bookable with held rate plan, once converted
+	_, err = self.Store.CT.Bookings.selectRatePlan(ctx, &types.BookingUnfolded{
+		Booking: &types.Booking{
+			UserID: userID, RoomID: room.ID, DateFrom: hold.DateFrom, DateTo: hold.DateTo,
+			RatePlanID: hold.RatePlanID,
+		},
+		Room: room,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	hold.UserID = userID
+	hold.Status = types.ActiveHoldStatus
+	hold.CreatedAt = now
+	hold.ExpiresAt = now.Add(ttl)
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := self.Store.CT.Bookings.reserveRoom(
+			ctx, primitive.ObjectID{}, primitive.ObjectID{}, room.ID, hold.DateFrom, hold.DateTo,
+		)
+		if err != nil {
+			return err
+		}
+		hold.ID, err = self.Store.DB.Holds.Create(ctx, hold)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Hold", hold.ID, nil, hold)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// Moves active hold to status. Returns nil if hold isn't active anymore
+func (self *HoldController) finish(
+	ctx context.Context, id primitive.ObjectID, status types.HoldStatus,
+) (*types.Hold, error) {
+	result, err := self.Store.DB.Holds.GetOneAndUpdate(
+		ctx,
+		activeHoldsFilter(bson.M{"_id": id}, time.Now()),
+		bson.M{"$set": bson.M{"status": status}},
+		&types.Hold{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastPtrInterface[types.Hold](result), nil
+}
+
+// Unlocks room before hold expires
+func (self *HoldController) ReleaseByID(ctx context.Context, id primitive.ObjectID) error {
+	hold, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hold == nil {
+		return NotFoundError{Entity: "Hold"}
+	}
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		released, err := self.finish(ctx, id, types.ReleasedHoldStatus)
+		if err != nil {
+			return err
+		}
+		if released == nil {
+			return errHoldNotActive
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.DeleteAuditAction, "Hold", id, hold, released,
+		)
+	})
+	if err != nil {
+		return err
+	}
+	return self.Store.CT.Waitlist.finishOffer(ctx, hold, primitive.ObjectID{})
+}
+
+// Marks active hold as converted into booking, within booking's
+// transaction, so hold is converted at most once
+func (self *HoldController) convert(
+	ctx context.Context, id primitive.ObjectID, bookingID primitive.ObjectID,
+) error {
+	result, err := self.Store.DB.Holds.GetOneAndUpdate(
+		ctx,
+		activeHoldsFilter(bson.M{"_id": id}, time.Now()),
+		bson.M{"$set": bson.M{"status": types.ConvertedHoldStatus, "bookingID": bookingID}},
+		&types.Hold{},
+	)
+	if err != nil {
+		return err
+	}
+	claimed := CastPtrInterface[types.Hold](result)
+	if claimed == nil {
+		return errHoldNotActive
+	}
+	hold := *claimed
+	hold.Status = types.ActiveHoldStatus
+	hold.BookingID = primitive.ObjectID{}
+	return self.Store.CT.Audit.Record(ctx, types.UpdateAuditAction, "Hold", id, &hold, claimed)
+}
+
+// Books held room for held dates for holder. Hold keeps the room until
+// booking commits, which converts it in the same transaction
+func (self *HoldController) ConvertByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.BookingUnfolded, error) {
+	hold, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold == nil {
+		return nil, NotFoundError{Entity: "Hold"}
+	}
+	if !hold.IsActiveAt(time.Now()) {
+		return nil, errHoldNotActive
+	}
+
+	booking, err := self.Store.CT.Bookings.Create(ctx, &types.Booking{
+		UserID:     hold.UserID,
+		RoomID:     hold.RoomID,
+		DateFrom:   hold.DateFrom,
+		DateTo:     hold.DateTo,
+		Guests:     hold.Guests,
+		RatePlanID: hold.RatePlanID,
+		HoldID:     hold.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = self.Store.CT.Waitlist.finishOffer(ctx, hold, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	return booking, nil
+}
+
+// Marks holds past their expiry as expired. Returns number of expired holds.
+// Holds stop locking rooms at expiry anyway, this only keeps statuses accurate
+func (self *HoldController) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	return self.Store.DB.Holds.Update(
+		ctx,
+		bson.M{"status": types.ActiveHoldStatus, "expiresAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": types.ExpiredHoldStatus}},
+	)
+}
@@ -2,18 +2,14 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	"hotel/types"
+	"time"
 
+	"cloud.google.com/go/civil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-const (
-	minHotelNameLen     = 2
-	minHotelLocationLen = 2
-)
-
 type HotelController struct {
 	Store *Store
 }
@@ -22,7 +18,7 @@ func (self *HotelController) HotelToWIthRooms(ctx context.Context, hotel *types.
 	if hotel == nil {
 		return nil, nil
 	}
-	roomQuery, err := bson.Marshal(RoomGetQueryParams{HotelID: hotel.ID})
+	roomQuery := bson.M{"hotelID": hotel.ID, "deletedAt": hotel.DeletedAt}
 	result, err := self.Store.DB.Rooms.Get(ctx, roomQuery, []*types.Room{})
 	if err != nil {
 		return nil, err
@@ -38,7 +34,9 @@ func (self *HotelController) HotelToWIthRooms(ctx context.Context, hotel *types.
 func (self *HotelController) GetByID(
 	ctx context.Context, id primitive.ObjectID,
 ) (*types.Hotel, error) {
-	result, err := self.Store.DB.Hotels.GetOneByID(ctx, id, &types.Hotel{})
+	result, err := self.Store.DB.Hotels.GetOne(
+		ctx, notDeleted(bson.M{"_id": id}), &types.Hotel{},
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +46,10 @@ func (self *HotelController) GetByID(
 func (self *HotelController) GetWithRoomsByID(
 	ctx context.Context, id primitive.ObjectID,
 ) (*types.HotelWithRooms, error) {
+	err := RequireHotelAccess(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 	hotel, err := self.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -55,29 +57,30 @@ func (self *HotelController) GetWithRoomsByID(
 	return self.HotelToWIthRooms(ctx, hotel)
 }
 
-func (self *HotelController) Get(ctx context.Context) ([]*types.Hotel, error) {
-	result, err := self.Store.DB.Hotels.Get(ctx, bson.M{}, []*types.Hotel{})
-	if err != nil {
-		return nil, err
-	}
-	return CastInterface[[]*types.Hotel](result), nil
+type HotelGetQueryParams struct {
+	// Lists deleted hotels instead, admin only
+	Deleted bool `json:"deleted"`
 }
 
-func (self *HotelController) Validate(hotel *types.Hotel) map[string]string {
-	errors := map[string]string{}
-	if len(hotel.Name) < minHotelNameLen {
-		errors["firstName"] = fmt.Sprintf(
-			"Name length should be at least %d characters", minHotelNameLen,
-		)
+func (self *HotelController) Get(
+	ctx context.Context, params *HotelGetQueryParams,
+) ([]*types.Hotel, error) {
+	if params == nil {
+		params = &HotelGetQueryParams{}
 	}
-
-	if len(hotel.Location) < minHotelLocationLen {
-		errors["lastName"] = fmt.Sprintf(
-			"Location length should be at least %d characters", minHotelLocationLen,
-		)
+	query := bson.M{}
+	if scope := hotelScopeQuery(ctx); scope != nil {
+		query["_id"] = scope
 	}
-
-	return errors
+	query, err := deletedFilter(self.Store.DB, ctx, query, params.Deleted)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.Hotels.Get(ctx, query, []*types.Hotel{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Hotel](result), nil
 }
 
 func (self *HotelController) Evaluate(hotel *types.Hotel) error {
@@ -87,39 +90,100 @@ func (self *HotelController) Evaluate(hotel *types.Hotel) error {
 func (self *HotelController) Create(
 	ctx context.Context, hotel *types.Hotel,
 ) (*types.HotelWithRooms, error) {
-	errs := self.Validate(hotel)
-	if len(errs) != 0 {
-		return nil, ValidationError{Fields: errs}
+	if hotelScopeQuery(ctx) != nil {
+		return nil, ErrHotelOutOfScope
 	}
 	err := self.Evaluate(hotel)
 	if err != nil {
 		return nil, err
 	}
-	id, err := self.Store.DB.Hotels.Create(ctx, hotel)
+	hotel.Currency = HotelCurrency(hotel)
+	hotel.Version = 1
+	var created *types.Hotel
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.Hotels.Create(ctx, hotel)
+		if err != nil {
+			return err
+		}
+		created, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Hotel", id, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
-	created, err := self.GetByID(ctx, id)
 	return self.HotelToWIthRooms(ctx, created)
 }
 
 func (self *HotelController) UpdateByID(
 	ctx context.Context, id primitive.ObjectID, hotel *types.Hotel,
 ) (*types.HotelWithRooms, error) {
-	errs := self.Validate(hotel)
-	if len(errs) != 0 {
-		return nil, ValidationError{Fields: errs}
+	err := RequireHotelAccess(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	err := self.Evaluate(hotel)
+	hotelBefore, err := self.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-
-	err = self.Store.DB.Hotels.UpdateByID(ctx, id, hotel)
+	if hotelBefore == nil {
+		return nil, NotFoundError{Entity: "Hotel"}
+	}
+	err = CheckVersion("Hotel", hotel.Version, hotelBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = self.Evaluate(hotel)
 	if err != nil {
 		return nil, err
 	}
-	updated, err := self.GetByID(ctx, id)
+	if len(hotel.Currency) == 0 {
+		hotel.Currency = hotelBefore.Currency
+	}
+	hotel.TaxRules = hotelBefore.TaxRules
+	if hotel.Currency != hotelBefore.Currency {
+		for _, rule := range hotel.TaxRules {
+			if !rule.Amount.IsZero() {
+				return nil, errCurrencyInUse
+			}
+		}
+		// Deleted rooms count too, they may be restored
+		rooms, err := self.Store.DB.Rooms.GetCount(ctx, bson.M{"hotelID": id})
+		if err != nil {
+			return nil, err
+		}
+		if rooms != 0 {
+			return nil, errCurrencyInUse
+		}
+		ratePlans, err := self.Store.DB.RatePlans.GetCount(ctx, bson.M{
+			"hotelID": id, "guestNightSupplement.minor": bson.M{"$gt": 0},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ratePlans != 0 {
+			return nil, errCurrencyInUse
+		}
+	}
+
+	var updated *types.Hotel
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Hotels, "Hotel", id, hotelBefore.Version, hotelBefore, hotel,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Hotel", id, hotelBefore, updated,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -127,7 +191,208 @@ func (self *HotelController) UpdateByID(
 }
 
 func (self *HotelController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	err := RequireHotelAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+	hotel, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hotel == nil {
+		return NotFoundError{Entity: "Hotel"}
+	}
+	err = CheckVersion("Hotel", version, hotel.Version)
+	if err != nil {
+		return err
+	}
+	// Rooms are archived with the same timestamp, so restore
+	// brings back exactly those, not ones deleted earlier
+	deletedAt := time.Now()
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		rooms, err := self.getRooms(ctx, bson.M{"hotelID": id, "deletedAt": nil})
+		if err != nil {
+			return err
+		}
+		ids := roomIDs(rooms)
+		// Locked like on booking, so rooms can't be booked while they're deleted
+		for _, roomID := range ids {
+			err = self.Store.CT.Bookings.lockRoom(ctx, roomID)
+			if err != nil {
+				return err
+			}
+		}
+		hasBookings, err := self.Store.CT.Bookings.HasActiveFrom(
+			ctx, ids, civil.DateOf(deletedAt),
+		)
+		if err != nil {
+			return err
+		}
+		if hasBookings {
+			return errHasActiveBookings
+		}
+		err = softDeleteByID(ctx, self.Store.DB.Hotels, "Hotel", id, hotel.Version, deletedAt)
+		if err != nil {
+			return err
+		}
+		_, err = self.Store.DB.Rooms.Update(
+			ctx, bson.M{"_id": bson.M{"$in": ids}},
+			bson.M{"$set": bson.M{"deletedAt": deletedAt}, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return err
+		}
+		deleted := *hotel
+		deleted.DeletedAt = &deletedAt
+		err = self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "Hotel", id, hotel, &deleted)
+		if err != nil {
+			return err
+		}
+		for _, room := range rooms {
+			deletedRoom := *room
+			deletedRoom.DeletedAt = &deletedAt
+			err = self.Store.CT.Audit.Record(
+				ctx, types.DeleteAuditAction, "Room", room.ID, room, &deletedRoom,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (self *HotelController) getRooms(
+	ctx context.Context, query bson.M,
+) ([]*types.Room, error) {
+	result, err := self.Store.DB.Rooms.Get(ctx, query, []*types.Room{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Room](result), nil
+}
+
+func roomIDs(rooms []*types.Room) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(rooms))
+	for i, room := range rooms {
+		ids[i] = room.ID
+	}
+	return ids
+}
+
+// Gets hotel regardless of whether it's deleted
+func (self *HotelController) getAnyByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Hotel, error) {
+	err := RequireHotelAccess(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.Hotels.GetOneByID(ctx, id, &types.Hotel{})
+	if err != nil {
+		return nil, err
+	}
+	hotel := CastPtrInterface[types.Hotel](result)
+	if hotel == nil {
+		return nil, NotFoundError{Entity: "Hotel"}
+	}
+	return hotel, nil
+}
+
+// Brings deleted hotel back along with rooms archived by its deletion
+func (self *HotelController) RestoreByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.HotelWithRooms, error) {
+	hotel, err := self.getAnyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hotel.DeletedAt == nil {
+		return self.GetWithRoomsByID(ctx, id)
+	}
+	archivedQuery := bson.M{"hotelID": id, "deletedAt": hotel.DeletedAt}
+	rooms, err := self.getRooms(ctx, archivedQuery)
+	if err != nil {
+		return nil, err
+	}
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Rooms.Update(
+			ctx, archivedQuery,
+			bson.M{"$set": bson.M{"deletedAt": nil}, "$inc": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return err
+		}
+		err = restoreByID(ctx, self.Store.DB.Hotels, id)
+		if err != nil {
+			return err
+		}
+		restored := *hotel
+		restored.DeletedAt = nil
+		err = self.Store.CT.Audit.Record(ctx, types.RestoreAuditAction, "Hotel", id, hotel, &restored)
+		if err != nil {
+			return err
+		}
+		for _, room := range rooms {
+			restoredRoom := *room
+			restoredRoom.DeletedAt = nil
+			err = self.Store.CT.Audit.Record(
+				ctx, types.RestoreAuditAction, "Room", room.ID, room, &restoredRoom,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return self.GetWithRoomsByID(ctx, id)
+}
+
+// Removes deleted hotel permanently along with its rooms and their bookings
+func (self *HotelController) PurgeByID(
 	ctx context.Context, id primitive.ObjectID,
 ) error {
-	return self.Store.DB.Hotels.DeleteByID(ctx, id)
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	hotel, err := self.getAnyByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if hotel.DeletedAt == nil {
+		return errNotDeleted("Hotel")
+	}
+	return self.purge(ctx, hotel)
+}
+
+func (self *HotelController) purge(ctx context.Context, hotel *types.Hotel) error {
+	id := hotel.ID
+	rooms, err := self.getRooms(ctx, bson.M{"hotelID": id})
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Bookings.Delete(
+			ctx, bson.M{"roomID": bson.M{"$in": roomIDs(rooms)}},
+		)
+		if err != nil {
+			return err
+		}
+		_, err = self.Store.DB.Rooms.Delete(ctx, bson.M{"hotelID": id})
+		if err != nil {
+			return err
+		}
+		_, err = self.Store.DB.Hotels.DeleteByID(ctx, id, 0)
+		if err != nil {
+			return err
+		}
+		// Purge of hotel covers its rooms and their bookings
+		return self.Store.CT.Audit.Record(ctx, types.PurgeAuditAction, "Hotel", id, hotel, nil)
+	})
 }
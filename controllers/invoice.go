@@ -0,0 +1,379 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var invoiceNumberPrefixes = map[types.InvoiceKind]string{
+	types.InvoiceInvoiceKind:    "INV",
+	types.CreditNoteInvoiceKind: "CN",
+}
+
+var errAlreadyInvoiced = ConflictError{
+	Code:    AlreadyInvoicedErrorCode,
+	Message: "Booking is already invoiced, credit the invoice in full to issue a new one",
+}
+
+// Checks lines given by client against invoice's kind and currency
+func validateInvoiceLines(lines []*types.InvoiceLine, currency types.Currency) error {
+	fields := map[string]string{}
+	for i, line := range lines {
+		name := fmt.Sprintf("lines[%d]", i)
+		switch {
+		case line.Kind != types.ExtraInvoiceLineKind && line.Kind != types.TaxInvoiceLineKind:
+			fields[name+".kind"] = "Line kind should be extra or tax"
+		case len(line.Description) == 0:
+			fields[name+".description"] = "Description is required"
+		case line.Quantity < 1:
+			fields[name+".quantity"] = "Quantity should be positive"
+		case line.UnitPrice.IsNegative():
+			fields[name+".unitPrice"] = "Unit price can't be negative"
+		case line.UnitPrice.Currency != currency:
+			fields[name+".unitPrice"] = fmt.Sprintf("Unit price should be in %s", currency)
+		}
+	}
+	if len(fields) != 0 {
+		return ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Fills amounts of lines and totals of invoice
+func calculateInvoice(invoice *types.Invoice) {
+	invoice.Subtotal = types.NewMoney(0, invoice.Currency)
+	invoice.TaxTotal = types.NewMoney(0, invoice.Currency)
+	for _, line := range invoice.Lines {
+		if line.Amount.IsZero() {
+			line.Amount = line.UnitPrice.Mul(int64(line.Quantity))
+		}
+		if line.Kind == types.TaxInvoiceLineKind {
+			invoice.TaxTotal = invoice.TaxTotal.Add(line.Amount)
+		} else {
+			invoice.Subtotal = invoice.Subtotal.Add(line.Amount)
+		}
+	}
+	invoice.Total = invoice.Subtotal.Add(invoice.TaxTotal)
+}
+
+type InvoiceController struct {
+	Store *Store
+}
+
+func (self *InvoiceController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Invoice, error) {
+	result, err := self.Store.DB.Invoices.GetOneByID(ctx, id, &types.Invoice{})
+	if err != nil {
+		return nil, err
+	}
+	invoice := CastPtrInterface[types.Invoice](result)
+	if invoice == nil {
+		return nil, nil
+	}
+	err = RequireOwnerOrAdmin(self.Store.DB, ctx, invoice.UserID, "Invoice")
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+type InvoiceGetQueryParams struct {
+	HotelID   primitive.ObjectID `json:"hotelID"`
+	BookingID primitive.ObjectID `json:"bookingID"`
+	Kind      types.InvoiceKind  `json:"kind" validate:"enum"`
+}
+
+// Users see their own invoices, admins see everyone's
+func (self *InvoiceController) Get(
+	ctx context.Context, params *InvoiceGetQueryParams,
+) ([]*types.Invoice, error) {
+	if params == nil {
+		params = &InvoiceGetQueryParams{}
+	}
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	query := bson.M{}
+	if !user.IsAdmin || GetAPIKeyFromContext(ctx) != nil {
+		query["userID"] = user.ID
+	}
+	if !params.HotelID.IsZero() {
+		query["hotelID"] = params.HotelID
+	}
+	if !params.BookingID.IsZero() {
+		query["bookingID"] = params.BookingID
+	}
+	if len(params.Kind) != 0 {
+		query["kind"] = params.Kind
+	}
+	result, err := self.Store.DB.Invoices.GetSorted(
+		ctx, query, bson.D{{Key: "issuedAt", Value: 1}}, 0, []*types.Invoice{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Invoice](result), nil
+}
+
+// Sum of credit notes issued for invoice, negative
+func (self *InvoiceController) creditedTotal(
+	ctx context.Context, invoice *types.Invoice,
+) (types.Money, error) {
+	credited := types.NewMoney(0, invoice.Currency)
+	result, err := self.Store.DB.Invoices.Get(
+		ctx, bson.M{"creditedInvoiceID": invoice.ID}, []*types.Invoice{},
+	)
+	if err != nil {
+		return credited, err
+	}
+	for _, creditNote := range CastInterface[[]*types.Invoice](result) {
+		credited = credited.Add(creditNote.Total)
+	}
+	return credited, nil
+}
+
+// Numbers and stores invoice. Number is taken in the same transaction,
+// so failed inserts don't leave gaps in hotel's series. Check runs in the
+// transaction too, after the number, which serializes issuing per series
+func (self *InvoiceController) issue(
+	ctx context.Context, invoice *types.Invoice, check func(ctx context.Context) error,
+) (*types.Invoice, error) {
+	issuedBy, err := GetUserIDFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	invoice.IssuedBy = issuedBy
+	invoice.IssuedAt = time.Now().UTC().Truncate(time.Millisecond)
+	calculateInvoice(invoice)
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		sequence, err := self.Store.DB.Counters.NextSequence(
+			ctx, fmt.Sprintf("invoice:%s:%s", invoice.HotelID.Hex(), invoice.Kind),
+		)
+		if err != nil {
+			return err
+		}
+		invoice.Sequence = sequence
+		invoice.Number = fmt.Sprintf(
+			"%s-%06d", invoiceNumberPrefixes[invoice.Kind], sequence,
+		)
+		err = check(ctx)
+		if err != nil {
+			return err
+		}
+		id, err := self.Store.DB.Invoices.Create(ctx, invoice)
+		if err != nil {
+			return err
+		}
+		invoice.ID = id
+		return self.Store.CT.Audit.Record(
+			ctx, types.CreateAuditAction, "Invoice", id, nil, invoice,
+		)
+	})
+	// Unique index keeps one open invoice per booking
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errAlreadyInvoiced
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// Issues invoice for nights of the stay and given extra lines, admin only
+func (self *InvoiceController) IssueForBooking(
+	ctx context.Context, bookingID primitive.ObjectID, lines []*types.InvoiceLine,
+) (*types.Invoice, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	booking, err := self.Store.CT.Bookings.GetUnfoldedByID(ctx, bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if booking == nil || booking.Room == nil {
+		return nil, NotFoundError{Entity: "Booking"}
+	}
+
+	result, err := self.Store.DB.Hotels.GetOneByID(ctx, booking.Room.HotelID, &types.Hotel{})
+	if err != nil {
+		return nil, err
+	}
+	hotel := CastPtrInterface[types.Hotel](result)
+	if hotel == nil {
+		return nil, NotFoundError{Entity: "Hotel"}
+	}
+	currency := HotelCurrency(hotel)
+	err = validateInvoiceLines(lines, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &types.Invoice{
+		Kind:      types.InvoiceInvoiceKind,
+		HotelID:   hotel.ID,
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		Currency:  currency,
+		Seller:    types.InvoiceParty{Name: hotel.Name, Address: hotel.Location},
+	}
+	if booking.User != nil {
+		invoice.BilledTo = types.InvoiceParty{
+			Name:  fmt.Sprintf("%s %s", booking.User.FirstName, booking.User.LastName),
+			Email: booking.User.Email,
+		}
+	}
+	nights := booking.DateTo.DaysSince(booking.DateFrom)
+	roomCost := booking.RoomCost
+	if roomCost.IsZero() && len(booking.Charges) == 0 {
+		// Booked before taxes and fees were introduced
+		roomCost = booking.TotalCost
+	}
+	if nights > 0 {
+		invoice.Lines = append(invoice.Lines, &types.InvoiceLine{
+			Kind: types.NightInvoiceLineKind,
+			Description: fmt.Sprintf(
+				"%s room, %s - %s", booking.Room.Type, booking.DateFrom, booking.DateTo,
+			),
+			Quantity:  nights,
+			UnitPrice: roomCost.Div(int64(nights)),
+			// Agreed cost, unit price may be rounded
+			Amount: roomCost,
+		})
+	}
+	if booking.Discount != nil && !booking.Discount.Amount.IsZero() {
+		invoice.Lines = append(invoice.Lines, &types.InvoiceLine{
+			Kind: types.DiscountInvoiceLineKind, Description: booking.Discount.Name,
+			Quantity: 1, UnitPrice: booking.Discount.Amount.Neg(),
+			Amount: booking.Discount.Amount.Neg(),
+		})
+	}
+	if booking.RedeemedPoints != nil && !booking.RedeemedPoints.Amount.IsZero() {
+		invoice.Lines = append(invoice.Lines, &types.InvoiceLine{
+			Kind:        types.DiscountInvoiceLineKind,
+			Description: fmt.Sprintf("Loyalty points (%d)", booking.RedeemedPoints.Points),
+			Quantity:    1,
+			UnitPrice:   booking.RedeemedPoints.Amount.Neg(),
+			Amount:      booking.RedeemedPoints.Amount.Neg(),
+		})
+	}
+	// Inclusive charges are part of room cost already
+	for _, charge := range booking.Charges {
+		if charge.Inclusive || charge.Amount.IsZero() {
+			continue
+		}
+		kind := types.ExtraInvoiceLineKind
+		if charge.Kind == types.VATTaxKind || charge.Kind == types.CityTaxKind {
+			kind = types.TaxInvoiceLineKind
+		}
+		invoice.Lines = append(invoice.Lines, &types.InvoiceLine{
+			Kind: kind, Description: charge.Name, Quantity: 1,
+			UnitPrice: charge.Amount, Amount: charge.Amount,
+		})
+	}
+	invoice.Lines = append(invoice.Lines, lines...)
+	if len(invoice.Lines) == 0 {
+		return nil, NewFieldError("lines", "Invoice should have at least one line")
+	}
+	// Unique index of open invoices keeps one per booking
+	invoice.ID = primitive.NewObjectID()
+	return self.issue(ctx, invoice, func(ctx context.Context) error {
+		_, err := self.Store.DB.OpenInvoices.Create(ctx, &types.OpenInvoice{
+			InvoiceID: invoice.ID, BookingID: bookingID,
+		})
+		return err
+	})
+}
+
+// Refunds given lines of invoice, or the whole invoice if there are
+// none. Credit notes can't exceed what's left of the invoice, admin only
+func (self *InvoiceController) IssueCreditNote(
+	ctx context.Context, invoiceID primitive.ObjectID, lines []*types.InvoiceLine,
+) (*types.Invoice, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	invoice, err := self.GetByID(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	if invoice == nil || invoice.Kind != types.InvoiceInvoiceKind {
+		return nil, NotFoundError{Entity: "Invoice"}
+	}
+	err = validateInvoiceLines(lines, invoice.Currency)
+	if err != nil {
+		return nil, err
+	}
+	credited, err := self.creditedTotal(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	if !credited.IsZero() && invoice.Total.Add(credited).IsZero() {
+		return nil, NewFieldError("lines", "Invoice is fully credited already")
+	}
+
+	if len(lines) == 0 {
+		if !credited.IsZero() {
+			return nil, NewFieldError(
+				"lines", "Invoice is partially credited, lines to credit are required",
+			)
+		}
+		for _, line := range invoice.Lines {
+			lines = append(lines, &types.InvoiceLine{
+				Kind:        line.Kind,
+				Description: line.Description,
+				Quantity:    line.Quantity,
+				UnitPrice:   line.UnitPrice,
+				Amount:      line.Amount,
+			})
+		}
+	}
+	for _, line := range lines {
+		line.UnitPrice = line.UnitPrice.Neg()
+		line.Amount = line.Amount.Neg()
+	}
+	creditNote := &types.Invoice{
+		Kind:              types.CreditNoteInvoiceKind,
+		HotelID:           invoice.HotelID,
+		BookingID:         invoice.BookingID,
+		UserID:            invoice.UserID,
+		Currency:          invoice.Currency,
+		CreditedInvoiceID: invoice.ID,
+		Seller:            invoice.Seller,
+		BilledTo:          invoice.BilledTo,
+		Lines:             lines,
+	}
+	calculateInvoice(creditNote)
+	// Credited total is taken again in the transaction, so concurrent
+	// credit notes can't exceed the invoice together
+	return self.issue(ctx, creditNote, func(ctx context.Context) error {
+		credited, err := self.creditedTotal(ctx, invoice)
+		if err != nil {
+			return err
+		}
+		left := invoice.Total.Add(credited)
+		if left.Add(creditNote.Total).IsNegative() {
+			return NewFieldError("lines", fmt.Sprintf(
+				"Credit note exceeds %s left to credit", left,
+			))
+		}
+		if !left.Add(creditNote.Total).IsZero() {
+			return nil
+		}
+		// Booking can be invoiced again
+		_, err = self.Store.DB.OpenInvoices.DeleteByID(ctx, invoice.ID, 0)
+		return err
+	})
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"hotel/lockout"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type loginAttemptKey struct {
+	Key    string
+	Policy lockout.Policy
+}
+
+func (self *UserController) loginAttemptKeys(params *types.LoginUserParams) []loginAttemptKey {
+	keys := []loginAttemptKey{}
+	if len(params.Email) != 0 {
+		keys = append(keys, loginAttemptKey{
+			Key: lockout.AccountKey(params.Email), Policy: lockout.AccountPolicy,
+		})
+	}
+	if len(params.IP) != 0 {
+		keys = append(keys, loginAttemptKey{
+			Key: lockout.IPKey(params.IP), Policy: lockout.IPPolicy,
+		})
+	}
+	return keys
+}
+
+func (self *UserController) checkLoginLocked(
+	ctx context.Context, params *types.LoginUserParams,
+) error {
+	now := time.Now()
+	for _, key := range self.loginAttemptKeys(params) {
+		attempts, err := self.Store.LoginAttempts.Get(ctx, key.Key)
+		if err != nil {
+			return err
+		}
+		if attempts.IsLocked(now) {
+			return LoginLockedError{RetryAfter: attempts.LockedUntil.Sub(now)}
+		}
+	}
+	return nil
+}
+
+func (self *UserController) registerLoginFailure(
+	ctx context.Context, params *types.LoginUserParams,
+) error {
+	now := time.Now()
+	for _, key := range self.loginAttemptKeys(params) {
+		attempts, err := self.Store.LoginAttempts.Increment(
+			ctx, key.Key, now, key.Policy.Window,
+		)
+		if err != nil {
+			return err
+		}
+		lockDuration := key.Policy.LockDuration(attempts.Failures)
+		if lockDuration == 0 {
+			continue
+		}
+		lockedUntil := now.Add(lockDuration)
+		err = self.Store.LoginAttempts.Lock(ctx, key.Key, lockedUntil)
+		if err != nil {
+			return err
+		}
+		err = self.addAuthEvent(ctx, &types.AuthEvent{
+			Kind:        types.LockoutAuthEventKind,
+			Key:         key.Key,
+			Failures:    attempts.Failures,
+			LockedUntil: lockedUntil,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *UserController) addAuthEvent(
+	ctx context.Context, event *types.AuthEvent,
+) error {
+	event.CreatedAt = time.Now()
+	_, err := self.Store.DB.AuthEvents.Create(ctx, event)
+	return err
+}
+
+func (self *UserController) GetLocked(
+	ctx context.Context,
+) ([]*types.LoginAttempts, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return self.Store.LoginAttempts.GetLocked(ctx, time.Now())
+}
+
+func (self *UserController) unlock(ctx context.Context, key string) error {
+	admin, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	attempts, err := self.Store.LoginAttempts.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	err = self.Store.LoginAttempts.Reset(ctx, key)
+	if err != nil {
+		return err
+	}
+	event := &types.AuthEvent{
+		Kind:    types.UnlockAuthEventKind,
+		Key:     key,
+		ActorID: admin.ID,
+	}
+	if attempts != nil {
+		event.Failures = attempts.Failures
+	}
+	return self.addAuthEvent(ctx, event)
+}
+
+// Returns nil user if it doesn't exist
+func (self *UserController) UnlockByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.User, error) {
+	user, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+	err = self.unlock(ctx, lockout.AccountKey(user.Email))
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (self *UserController) UnlockIP(
+	ctx context.Context, params *types.UnlockIPParams,
+) error {
+	if len(params.IP) == 0 {
+		return NewFieldError("ip", "IP is required")
+	}
+	return self.unlock(ctx, lockout.IPKey(params.IP))
+}
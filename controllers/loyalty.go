@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"context"
+	"hotel/types"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Points earned per whole currency unit paid for nights, before bonus
+	loyaltyPointsEarnedPerUnit = 1
+	// Points worth one currency unit when redeemed
+	loyaltyPointsPerUnitRedeemed = 100
+)
+
+var errInsufficientPoints = ConflictError{
+	Code:    InsufficientPointsErrorCode,
+	Message: "Not enough loyalty points",
+}
+
+// Tiers by nights stayed in the current year, highest first. Bonus is
+// extra percent of points earned
+var loyaltyTiers = []struct {
+	Tier   types.LoyaltyTier
+	Nights int
+	Bonus  int64
+}{
+	{types.PlatinumLoyaltyTier, 50, 50},
+	{types.GoldLoyaltyTier, 25, 25},
+	{types.SilverLoyaltyTier, 10, 10},
+	{types.MemberLoyaltyTier, 0, 0},
+}
+
+// Tier reached with nights and its bonus percent
+func LoyaltyTierFor(nights int) (types.LoyaltyTier, int64) {
+	for _, tier := range loyaltyTiers {
+		if nights >= tier.Nights {
+			return tier.Tier, tier.Bonus
+		}
+	}
+	return types.MemberLoyaltyTier, 0
+}
+
+// Minor units in one unit of currency
+func currencyUnit(currency types.Currency) int64 {
+	return int64(math.Pow10(currency.Exponent()))
+}
+
+// Points earned by paying amount with tier bonus, fractions are dropped
+func LoyaltyPointsEarned(paid types.Money, bonus int64) int64 {
+	if paid.Minor <= 0 {
+		return 0
+	}
+	points := paid.Minor * loyaltyPointsEarnedPerUnit / currencyUnit(paid.Currency)
+	return points * (100 + bonus) / 100
+}
+
+// Discount points give in currency
+func LoyaltyPointsValue(points int64, currency types.Currency) types.Money {
+	return types.NewMoney(points, currency).MulRat(currencyUnit(currency), loyaltyPointsPerUnitRedeemed)
+}
+
+// Builds account from user's ledger, entries are expected newest first
+func LoyaltyAccountFromEntries(
+	userID primitive.ObjectID, entries []*types.LoyaltyEntry, now time.Time,
+) *types.LoyaltyAccount {
+	account := &types.LoyaltyAccount{UserID: userID, Entries: entries}
+	for _, entry := range entries {
+		account.Balance += entry.Points
+		if entry.CreatedAt.Year() == now.Year() {
+			account.NightsThisYear += entry.Nights
+		}
+	}
+	account.Tier, _ = LoyaltyTierFor(account.NightsThisYear)
+	return account
+}
+
+// Room cost guest pays after discount and points, which earns points
+func paidRoomCost(booking *types.Booking) types.Money {
+	paid := booking.RoomCost
+	if paid.IsZero() && len(booking.Charges) == 0 {
+		// Booked before taxes and fees were introduced
+		return booking.TotalCost
+	}
+	if booking.Discount != nil {
+		paid = paid.Sub(booking.Discount.Amount)
+	}
+	if booking.RedeemedPoints != nil {
+		paid = paid.Sub(booking.RedeemedPoints.Amount)
+	}
+	return paid
+}
+
+type LoyaltyController struct {
+	Store *Store
+}
+
+func (self *LoyaltyController) getEntries(
+	ctx context.Context, query bson.M,
+) ([]*types.LoyaltyEntry, error) {
+	result, err := self.Store.DB.LoyaltyLedger.GetSorted(
+		ctx, query, bson.D{{Key: "sequence", Value: -1}}, 0, []*types.LoyaltyEntry{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.LoyaltyEntry](result), nil
+}
+
+func (self *LoyaltyController) getAccount(
+	ctx context.Context, userID primitive.ObjectID,
+) (*types.LoyaltyAccount, error) {
+	entries, err := self.getEntries(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return nil, err
+	}
+	return LoyaltyAccountFromEntries(userID, entries, time.Now()), nil
+}
+
+// Balance, tier and history of user's points
+func (self *LoyaltyController) GetAccount(
+	ctx context.Context, userID primitive.ObjectID,
+) (*types.LoyaltyAccount, error) {
+	err := RequireOwnerOrAdmin(self.Store.DB, ctx, userID, "User")
+	if err != nil {
+		return nil, err
+	}
+	return self.getAccount(ctx, userID)
+}
+
+// Takes next sequence of user's ledger, within transaction of entry.
+// Concurrent writes to one ledger conflict on it and are retried, so
+// balance read after it is up to date
+func (self *LoyaltyController) nextSequence(
+	ctx context.Context, userID primitive.ObjectID,
+) (int64, error) {
+	return self.Store.DB.Counters.NextSequence(ctx, "loyalty:"+userID.Hex())
+}
+
+// Spends points on booking, within its transaction
+func (self *LoyaltyController) redeem(
+	ctx context.Context, booking *types.Booking, now time.Time,
+) error {
+	if booking.RedeemedPoints == nil || booking.RedeemedPoints.Points == 0 {
+		return nil
+	}
+	sequence, err := self.nextSequence(ctx, booking.UserID)
+	if err != nil {
+		return err
+	}
+	account, err := self.getAccount(ctx, booking.UserID)
+	if err != nil {
+		return err
+	}
+	if account.Balance < booking.RedeemedPoints.Points {
+		return errInsufficientPoints
+	}
+	_, err = self.Store.DB.LoyaltyLedger.Create(ctx, &types.LoyaltyEntry{
+		UserID:    booking.UserID,
+		Sequence:  sequence,
+		BookingID: booking.ID,
+		Kind:      types.RedemptionLoyaltyEntryKind,
+		Points:    -booking.RedeemedPoints.Points,
+		CreatedAt: now,
+	})
+	return err
+}
+
+// Credits points and nights of checked out booking, within transaction
+// of its status change. Bonus is of tier reached before the stay
+func (self *LoyaltyController) accrue(
+	ctx context.Context, booking *types.Booking, now time.Time,
+) error {
+	sequence, err := self.nextSequence(ctx, booking.UserID)
+	if err != nil {
+		return err
+	}
+	account, err := self.getAccount(ctx, booking.UserID)
+	if err != nil {
+		return err
+	}
+	_, bonus := LoyaltyTierFor(account.NightsThisYear)
+	_, err = self.Store.DB.LoyaltyLedger.Create(ctx, &types.LoyaltyEntry{
+		UserID:    booking.UserID,
+		Sequence:  sequence,
+		BookingID: booking.ID,
+		Kind:      types.AccrualLoyaltyEntryKind,
+		Points:    LoyaltyPointsEarned(paidRoomCost(booking), bonus),
+		Nights:    booking.DateTo.DaysSince(booking.DateFrom),
+		CreatedAt: now,
+	})
+	return err
+}
+
+// Gives back points spent on cancelled booking and takes back ones it
+// earned, within its transaction. Balance may go negative, if earned
+// points were spent already
+func (self *LoyaltyController) reverse(
+	ctx context.Context, booking *types.Booking, now time.Time,
+) error {
+	entries, err := self.getEntries(ctx, bson.M{"bookingID": booking.ID})
+	if err != nil {
+		return err
+	}
+	points, nights := int64(0), 0
+	for _, entry := range entries {
+		points += entry.Points
+		nights += entry.Nights
+	}
+	if points == 0 && nights == 0 {
+		return nil
+	}
+	sequence, err := self.nextSequence(ctx, booking.UserID)
+	if err != nil {
+		return err
+	}
+	_, err = self.Store.DB.LoyaltyLedger.Create(ctx, &types.LoyaltyEntry{
+		UserID:    booking.UserID,
+		Sequence:  sequence,
+		BookingID: booking.ID,
+		Kind:      types.ReversalLoyaltyEntryKind,
+		Points:    -points,
+		Nights:    -nights,
+		CreatedAt: now,
+	})
+	return err
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"hotel/notifications"
+	"hotel/types"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	notificationsDefaultLimit = 100
+	notificationsMaxLimit     = 1000
+)
+
+// Sends notification about booking to its guest. Channel failures are
+// recorded by notifier and returned
+func (self *BookingController) notify(
+	ctx context.Context, kind types.NotificationKind, booking *types.Booking,
+) error {
+	bookingUnfolded, err := self.BookingToUnfolded(ctx, booking)
+	if err != nil {
+		return err
+	}
+	if bookingUnfolded.User == nil {
+		return nil
+	}
+	data := &notifications.BookingData{
+		GuestName: bookingUnfolded.User.FirstName,
+		BookingID: booking.ID.Hex(),
+		DateFrom:  booking.DateFrom,
+		DateTo:    booking.DateTo,
+		Nights:    booking.DateTo.DaysSince(booking.DateFrom),
+		TotalCost: booking.TotalCost,
+	}
+	if bookingUnfolded.Room != nil {
+		data.RoomType = bookingUnfolded.Room.Type
+		// Hotel may be deleted already, e.g. when its bookings are cancelled
+		result, err := self.Store.DB.Hotels.GetOneByID(
+			ctx, bookingUnfolded.Room.HotelID, &types.Hotel{},
+		)
+		if err != nil {
+			return err
+		}
+		if hotel := CastPtrInterface[types.Hotel](result); hotel != nil {
+			data.HotelName = hotel.Name
+		}
+	}
+	return self.Store.Notifier.Notify(ctx, kind, bookingUnfolded.User, booking.ID, data)
+}
+
+// Notifications neither fail nor delay operations which trigger them.
+// They're sent in background, outliving request context
+func (self *BookingController) notifyAndLog(
+	ctx context.Context, kind types.NotificationKind, booking *types.Booking,
+) {
+	self.Store.Notifier.Go(func() {
+		err := self.notify(context.Background(), kind, booking)
+		if err != nil {
+			log.Printf("Failed to notify about booking %s: %s\n", booking.ID.Hex(), err.Error())
+		}
+	})
+}
+
+type NotificationController struct {
+	Store *Store
+}
+
+type NotificationGetQueryParams struct {
+	UserID    primitive.ObjectID       `json:"userID"`
+	BookingID primitive.ObjectID       `json:"bookingID"`
+	Status    types.NotificationStatus `json:"status" validate:"enum"`
+	// Newest notifications are returned first
+	Limit int64 `json:"limit"`
+}
+
+// Users see their own notifications, admins see everyone's
+func (self *NotificationController) Get(
+	ctx context.Context, params *NotificationGetQueryParams,
+) ([]*types.Notification, error) {
+	if params == nil {
+		params = &NotificationGetQueryParams{}
+	}
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	if !user.IsAdmin || GetAPIKeyFromContext(ctx) != nil {
+		params.UserID = user.ID
+	}
+	query := bson.M{}
+	if !params.UserID.IsZero() {
+		query["userID"] = params.UserID
+	}
+	if !params.BookingID.IsZero() {
+		query["bookingID"] = params.BookingID
+	}
+	if len(params.Status) != 0 {
+		query["status"] = params.Status
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = notificationsDefaultLimit
+	}
+	if limit > notificationsMaxLimit {
+		limit = notificationsMaxLimit
+	}
+	result, err := self.Store.DB.Notifications.GetSorted(
+		ctx, query, bson.D{{Key: "createdAt", Value: -1}}, limit,
+		[]*types.Notification{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Notification](result), nil
+}
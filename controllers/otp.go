@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"hotel/lockout"
+	"hotel/totp"
+	"hotel/types"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	otpIssuer            = "gohotel"
+	otpChallengeTokenTTL = 5 * time.Minute
+
+	recoveryCodesCount   = 10
+	recoveryCodeBytesLen = 10
+	recoveryCodeGroupLen = 4
+)
+
+var (
+	errInvalidOTPCode = NewFieldError("code", "Code is invalid")
+	errOTPEnabled     = ConflictError{
+		Message: "Two-factor authentication is already enabled",
+	}
+)
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// Returns plain codes to show to user once and their hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := []string{}
+	hashes := []string{}
+	for i := 0; i < recoveryCodesCount; i++ {
+		codeBytes := make([]byte, recoveryCodeBytesLen)
+		_, err := rand.Read(codeBytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		raw := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(codeBytes)
+		groups := []string{}
+		for j := 0; j < len(raw); j += recoveryCodeGroupLen {
+			groups = append(groups, raw[j:j+recoveryCodeGroupLen])
+		}
+		codes = append(codes, strings.Join(groups, "-"))
+		hashes = append(hashes, hashUserToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+func (self *UserController) startOTPEnrolment(
+	ctx context.Context, user *types.User,
+) (*types.OTPEnrolment, error) {
+	if user.TOTPEnabled {
+		return nil, errOTPEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	_, err = self.Store.DB.Users.UpdateByID(ctx, user.ID, 0, bson.M{"totpSecret": secret})
+	if err != nil {
+		return nil, err
+	}
+	return &types.OTPEnrolment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(otpIssuer, user.Email, secret),
+	}, nil
+}
+
+// Enables TOTP once user proves that authenticator app is set up
+func (self *UserController) completeOTPEnrolment(
+	ctx context.Context, user *types.User, code string,
+) ([]string, error) {
+	if user.TOTPEnabled {
+		return nil, errOTPEnabled
+	}
+	if len(user.TOTPSecret) == 0 {
+		return nil, ConflictError{
+			Message: "Two-factor authentication enrolment wasn't started",
+		}
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, errInvalidOTPCode
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled := bson.M{
+		"totpEnabled":   true,
+		"totpLastStep":  step,
+		"recoveryCodes": hashes,
+	}
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Users.UpdateByID(ctx, user.ID, 0, enabled)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "User", user.ID,
+			bson.M{"totpEnabled": false}, enabled,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Accepts either TOTP code or unused recovery code. Both can be used only once
+func (self *UserController) checkSecondFactor(
+	ctx context.Context, user *types.User, code string,
+) (bool, error) {
+	if !user.TOTPEnabled {
+		return false, nil
+	}
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if ok {
+		result, err := self.Store.DB.Users.GetOneAndUpdate(
+			ctx,
+			bson.M{
+				"_id": user.ID,
+				"$or": bson.A{
+					bson.M{"totpLastStep": bson.M{"$lt": step}},
+					bson.M{"totpLastStep": bson.M{"$exists": false}},
+				},
+			},
+			bson.M{"$set": bson.M{"totpLastStep": step}},
+			&types.User{},
+		)
+		if err != nil {
+			return false, err
+		}
+		return result != nil, nil
+	}
+
+	codeHash := hashUserToken(normalizeRecoveryCode(code))
+	result, err := self.Store.DB.Users.GetOneAndUpdate(
+		ctx,
+		bson.M{"_id": user.ID, "recoveryCodes": codeHash},
+		bson.M{"$pull": bson.M{"recoveryCodes": codeHash}},
+		&types.User{},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result != nil, nil
+}
+
+func (self *UserController) getChallengeUser(
+	ctx context.Context, challengeToken string,
+) (*types.User, error) {
+	challenge, err := self.findToken(ctx, challengeToken, types.OTPChallengeTokenKind)
+	if err != nil {
+		return nil, err
+	}
+	user, err := self.GetByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errInvalidUserToken
+	}
+	return user, nil
+}
+
+// Lets admin, who hasn't set up second factor yet, enrol during login
+func (self *UserController) LoginEnrolOTP(
+	ctx context.Context, params *types.OTPChallengeParams,
+) (*types.OTPEnrolment, error) {
+	user, err := self.getChallengeUser(ctx, params.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return self.startOTPEnrolment(ctx, user)
+}
+
+// Second step of login: exchanges challenge token and code for access token
+func (self *UserController) LoginOTP(
+	ctx context.Context, params *types.LoginOTPParams,
+) (*types.LoginResult, error) {
+	user, err := self.getChallengeUser(ctx, params.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	loginParams := &types.LoginUserParams{Email: user.Email, IP: params.IP}
+	err = self.checkLoginLocked(ctx, loginParams)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.LoginResult{}
+	if user.TOTPEnabled {
+		ok, err := self.checkSecondFactor(ctx, user, params.Code)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			err = errInvalidOTPCode
+		}
+	} else {
+		result.RecoveryCodes, err = self.completeOTPEnrolment(ctx, user, params.Code)
+	}
+	if err != nil {
+		if _, ok := err.(ValidationError); ok {
+			failureErr := self.registerLoginFailure(ctx, loginParams)
+			if failureErr != nil {
+				return nil, failureErr
+			}
+		}
+		return nil, err
+	}
+
+	_, err = self.consumeToken(ctx, params.ChallengeToken, types.OTPChallengeTokenKind)
+	if err != nil {
+		return nil, err
+	}
+	err = self.Store.LoginAttempts.Reset(ctx, lockout.AccountKey(user.Email))
+	if err != nil {
+		return nil, err
+	}
+	result.User, err = self.GetByID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	result.Token, err = self.IssueAccessToken(result.User)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (self *UserController) EnrolOTP(ctx context.Context) (*types.OTPEnrolment, error) {
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	return self.startOTPEnrolment(ctx, user)
+}
+
+func (self *UserController) ConfirmOTP(
+	ctx context.Context, params *types.OTPCodeParams,
+) (*types.RecoveryCodes, error) {
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	codes, err := self.completeOTPEnrolment(ctx, user, params.Code)
+	if err != nil {
+		return nil, err
+	}
+	return &types.RecoveryCodes{RecoveryCodes: codes}, nil
+}
+
+func (self *UserController) DisableOTP(
+	ctx context.Context, params *types.DisableOTPParams,
+) error {
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNotAuthenticated
+	}
+	if user.IsAdmin {
+		return ForbiddenError{
+			Message: "Two-factor authentication is mandatory for admins",
+		}
+	}
+	if !self.CheckPasswordValid(user, params.Password) {
+		return NewFieldError("password", "Password is incorrect")
+	}
+	ok, err := self.checkSecondFactor(ctx, user, params.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidOTPCode
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Users.Update(
+			ctx, bson.M{"_id": user.ID},
+			bson.M{
+				"$unset": bson.M{
+					"totpEnabled":   "",
+					"totpSecret":    "",
+					"totpLastStep":  "",
+					"recoveryCodes": "",
+				},
+				"$inc": bson.M{"version": 1},
+			},
+		)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "User", user.ID,
+			bson.M{
+				"totpEnabled":   true,
+				"totpSecret":    user.TOTPSecret,
+				"recoveryCodes": user.RecoveryCodes,
+			},
+			nil,
+		)
+	})
+}
+
+// Replaces all recovery codes, e.g. when most of them are used up
+func (self *UserController) RegenerateRecoveryCodes(
+	ctx context.Context, params *types.OTPCodeParams,
+) (*types.RecoveryCodes, error) {
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	ok, err := self.checkSecondFactor(ctx, user, params.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errInvalidOTPCode
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Users.UpdateByID(ctx, user.ID, 0, bson.M{"recoveryCodes": hashes})
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "User", user.ID,
+			bson.M{"recoveryCodes": user.RecoveryCodes}, bson.M{"recoveryCodes": hashes},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.RecoveryCodes{RecoveryCodes: codes}, nil
+}
@@ -0,0 +1,461 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errPromotionExhausted = ConflictError{
+	Code:    PromotionExhaustedErrorCode,
+	Message: "Promotion is used up or no longer available",
+}
+
+func errPromotionCodeTaken() error {
+	return NewFieldError("code", "Promotion with this code already exists")
+}
+
+// Checks parts of promotion validator can't express
+func validatePromotion(promotion *types.Promotion) error {
+	fields := map[string]string{}
+	switch promotion.DiscountKind {
+	case types.PercentDiscountKind:
+		if promotion.Rate <= 0 || promotion.Rate > fullTaxRate {
+			fields["rate"] = "Rate should be between 1 and 10000 hundredths of percent"
+		}
+		if !promotion.Amount.IsZero() {
+			fields["amount"] = "Percent discount is a rate, not an amount"
+		}
+	case types.FixedDiscountKind:
+		if promotion.Rate != 0 {
+			fields["rate"] = "Fixed discount is an amount, not a rate"
+		}
+		if promotion.Amount.Minor <= 0 {
+			fields["amount"] = "Amount should be positive"
+		}
+	}
+	if promotion.MinNights < 0 {
+		fields["minNights"] = "Minimum nights can't be negative"
+	}
+	if promotion.MaxRedemptions < 0 {
+		fields["maxRedemptions"] = "Maximum redemptions can't be negative"
+	}
+	if promotion.MaxRedemptionsPerUser < 0 {
+		fields["maxRedemptionsPerUser"] = "Maximum redemptions can't be negative"
+	}
+	if len(fields) != 0 {
+		return ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Discount of promotion on room cost, fixed ones don't exceed it
+func PromotionDiscount(promotion *types.Promotion, roomCost types.Money) types.Money {
+	if promotion.DiscountKind == types.PercentDiscountKind {
+		return roomCost.MulRat(promotion.Rate, fullTaxRate)
+	}
+	if promotion.Amount.Currency != roomCost.Currency {
+		return types.NewMoney(0, roomCost.Currency)
+	}
+	if promotion.Amount.Cmp(roomCost) > 0 {
+		return roomCost
+	}
+	return promotion.Amount
+}
+
+// Reason promotion doesn't apply to stay, empty if it does. Booking
+// window and caps aren't checked, they don't matter for made bookings
+func PromotionMismatch(promotion *types.Promotion, booking *types.BookingUnfolded) string {
+	nights := booking.DateTo.DaysSince(booking.DateFrom)
+	lastNight := booking.DateTo.AddDays(-1)
+	switch {
+	case promotion.StayFrom.IsValid() && booking.DateFrom.Before(promotion.StayFrom),
+		promotion.StayTo.IsValid() && promotion.StayTo.Before(lastNight):
+		return "Promotion isn't valid for these dates"
+	case nights < promotion.MinNights:
+		return fmt.Sprintf("Promotion is for stays of %d nights or more", promotion.MinNights)
+	case len(promotion.HotelIDs) != 0 && !containsID(promotion.HotelIDs, booking.Room.HotelID):
+		return "Promotion isn't valid for this hotel"
+	case len(promotion.RoomTypes) != 0 && !containsRoomType(promotion.RoomTypes, booking.Room.Type):
+		return "Promotion isn't valid for this room"
+	case promotion.DiscountKind == types.FixedDiscountKind &&
+		promotion.Amount.Currency != booking.Room.Price.Currency:
+		return fmt.Sprintf("Promotion is for prices in %s", promotion.Amount.Currency)
+	}
+	return ""
+}
+
+func promotionOpen(promotion *types.Promotion, now time.Time) bool {
+	return (promotion.ValidFrom.IsZero() || !now.Before(promotion.ValidFrom)) &&
+		(promotion.ValidTo.IsZero() || now.Before(promotion.ValidTo))
+}
+
+func containsID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsRoomType(roomTypes []types.RoomType, roomType types.RoomType) bool {
+	for _, item := range roomTypes {
+		if item == roomType {
+			return true
+		}
+	}
+	return false
+}
+
+type PromotionController struct {
+	Store *Store
+}
+
+func (self *PromotionController) getByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Promotion, error) {
+	result, err := self.Store.DB.Promotions.GetOneByID(ctx, id, &types.Promotion{})
+	if err != nil {
+		return nil, err
+	}
+	return CastPtrInterface[types.Promotion](result), nil
+}
+
+// Admin only
+func (self *PromotionController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Promotion, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	promotion, err := self.getByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if promotion == nil {
+		return nil, NotFoundError{Entity: "Promotion"}
+	}
+	return promotion, nil
+}
+
+type PromotionGetQueryParams struct {
+	Code    string             `json:"code"`
+	HotelID primitive.ObjectID `json:"hotelID"`
+	// Lists only promotions without code
+	Automatic bool `json:"automatic"`
+}
+
+// Admin only
+func (self *PromotionController) Get(
+	ctx context.Context, params *PromotionGetQueryParams,
+) ([]*types.Promotion, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = &PromotionGetQueryParams{}
+	}
+	query := bson.M{}
+	if len(params.Code) != 0 {
+		query["code"] = types.NormalizePromoCode(params.Code)
+	}
+	if params.Automatic {
+		query["code"] = ""
+	}
+	// Promotions for all hotels apply to the hotel too
+	if !params.HotelID.IsZero() {
+		query["$or"] = bson.A{
+			bson.M{"hotelIDs": params.HotelID},
+			bson.M{"hotelIDs": bson.M{"$size": 0}},
+			bson.M{"hotelIDs": nil},
+		}
+	}
+	result, err := self.Store.DB.Promotions.Get(ctx, query, []*types.Promotion{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Promotion](result), nil
+}
+
+// Codes are unique, so guest's code finds one promotion
+func (self *PromotionController) checkCode(
+	ctx context.Context, id primitive.ObjectID, code string,
+) error {
+	if len(code) == 0 {
+		return nil
+	}
+	count, err := self.Store.DB.Promotions.GetCount(
+		ctx, bson.M{"code": code, "_id": bson.M{"$ne": id}},
+	)
+	if err != nil {
+		return err
+	}
+	if count != 0 {
+		return errPromotionCodeTaken()
+	}
+	return nil
+}
+
+func (self *PromotionController) Create(
+	ctx context.Context, promotion *types.Promotion,
+) (*types.Promotion, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = validatePromotion(promotion)
+	if err != nil {
+		return nil, err
+	}
+	err = self.checkCode(ctx, primitive.ObjectID{}, promotion.Code)
+	if err != nil {
+		return nil, err
+	}
+	promotion.Redemptions = 0
+	promotion.CreatedAt = time.Now()
+	promotion.Version = 1
+	var created *types.Promotion
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.Promotions.Create(ctx, promotion)
+		if err != nil {
+			return err
+		}
+		created, err = self.getByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Promotion", id, nil, created)
+	})
+	// Unique index catches codes taken since the check
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errPromotionCodeTaken()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Redemptions stay as counted, bookings made keep their discounts
+func (self *PromotionController) UpdateByID(
+	ctx context.Context, id primitive.ObjectID, promotion *types.Promotion,
+) (*types.Promotion, error) {
+	promotionBefore, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckVersion("Promotion", promotion.Version, promotionBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = validatePromotion(promotion)
+	if err != nil {
+		return nil, err
+	}
+	err = self.checkCode(ctx, id, promotion.Code)
+	if err != nil {
+		return nil, err
+	}
+	promotion.ID = id
+	promotion.Redemptions = promotionBefore.Redemptions
+	promotion.CreatedAt = promotionBefore.CreatedAt
+
+	var updated *types.Promotion
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Promotions, "Promotion", id, promotionBefore.Version,
+			promotionBefore, promotion,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.getByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Promotion", id, promotionBefore, updated,
+		)
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, errPromotionCodeTaken()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Bookings made keep their discounts until their stays change,
+// redemption log is kept
+func (self *PromotionController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	promotion, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = CheckVersion("Promotion", version, promotion.Version)
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := DeleteVersionedByID(ctx, self.Store.DB.Promotions, "Promotion", id, promotion.Version)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "Promotion", id, promotion, nil)
+	})
+}
+
+// Checks caps before booking is made. They're checked again on
+// redemption, since other bookings may use promotion up in between
+func (self *PromotionController) hasRedemptionsLeft(
+	ctx context.Context, promotion *types.Promotion, userID primitive.ObjectID,
+) (bool, error) {
+	if promotion.MaxRedemptions != 0 && promotion.Redemptions >= promotion.MaxRedemptions {
+		return false, nil
+	}
+	if promotion.MaxRedemptionsPerUser == 0 {
+		return true, nil
+	}
+	count, err := self.Store.DB.PromotionRedemptions.GetCount(ctx, bson.M{
+		"promotionID": promotion.ID, "userID": userID, "releasedAt": nil,
+	})
+	if err != nil {
+		return false, err
+	}
+	return count < promotion.MaxRedemptionsPerUser, nil
+}
+
+// Promotion of code guest entered, if it's open and applies to booking
+func (self *PromotionController) findByCode(
+	ctx context.Context, code string, booking *types.BookingUnfolded, now time.Time,
+) (*types.Promotion, error) {
+	result, err := self.Store.DB.Promotions.GetOne(
+		ctx, bson.M{"code": types.NormalizePromoCode(code)}, &types.Promotion{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	promotion := CastPtrInterface[types.Promotion](result)
+	if promotion == nil || !promotionOpen(promotion, now) {
+		return nil, NewFieldError("promoCode", "Promo code is invalid or expired")
+	}
+	if mismatch := PromotionMismatch(promotion, booking); len(mismatch) != 0 {
+		return nil, NewFieldError("promoCode", mismatch)
+	}
+	hasLeft, err := self.hasRedemptionsLeft(ctx, promotion, booking.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !hasLeft {
+		return nil, NewFieldError("promoCode", "Promo code has been used up")
+	}
+	return promotion, nil
+}
+
+// Automatic promotion giving the biggest discount on booking, nil if none applies
+func (self *PromotionController) findBestAutomatic(
+	ctx context.Context, booking *types.BookingUnfolded, now time.Time,
+) (*types.Promotion, error) {
+	result, err := self.Store.DB.Promotions.Get(ctx, bson.M{"code": ""}, []*types.Promotion{})
+	if err != nil {
+		return nil, err
+	}
+	var best *types.Promotion
+	bestDiscount := types.NewMoney(0, booking.RoomCost.Currency)
+	for _, promotion := range CastInterface[[]*types.Promotion](result) {
+		if !promotionOpen(promotion, now) || len(PromotionMismatch(promotion, booking)) != 0 {
+			continue
+		}
+		discount := PromotionDiscount(promotion, booking.RoomCost)
+		if discount.Cmp(bestDiscount) <= 0 {
+			continue
+		}
+		hasLeft, err := self.hasRedemptionsLeft(ctx, promotion, booking.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if hasLeft {
+			best, bestDiscount = promotion, discount
+		}
+	}
+	return best, nil
+}
+
+// Counts booking against caps of promotion, within booking's transaction.
+// Counter is incremented only while under cap, and concurrent redemptions
+// conflict on it, so per user count is read after the others commit
+func (self *PromotionController) redeem(
+	ctx context.Context, booking *types.Booking,
+) error {
+	if booking.Discount == nil {
+		return nil
+	}
+	result, err := self.Store.DB.Promotions.GetOneAndUpdate(
+		ctx,
+		bson.M{"_id": booking.Discount.PromotionID, "$expr": bson.M{"$or": bson.A{
+			bson.M{"$eq": bson.A{"$maxRedemptions", 0}},
+			bson.M{"$lt": bson.A{"$redemptions", "$maxRedemptions"}},
+		}}},
+		bson.M{"$inc": bson.M{"redemptions": 1}},
+		&types.Promotion{},
+	)
+	if err != nil {
+		return err
+	}
+	promotion := CastPtrInterface[types.Promotion](result)
+	if promotion == nil {
+		return errPromotionExhausted
+	}
+	if promotion.MaxRedemptionsPerUser != 0 {
+		count, err := self.Store.DB.PromotionRedemptions.GetCount(ctx, bson.M{
+			"promotionID": promotion.ID, "userID": booking.UserID, "releasedAt": nil,
+		})
+		if err != nil {
+			return err
+		}
+		if count >= promotion.MaxRedemptionsPerUser {
+			return errPromotionExhausted
+		}
+	}
+	_, err = self.Store.DB.PromotionRedemptions.Create(ctx, &types.PromotionRedemption{
+		PromotionID: promotion.ID,
+		UserID:      booking.UserID,
+		BookingID:   booking.ID,
+		CreatedAt:   time.Now(),
+	})
+	return err
+}
+
+// Gives redemption of cancelled booking back, within its transaction
+func (self *PromotionController) release(
+	ctx context.Context, bookingID primitive.ObjectID, now time.Time,
+) error {
+	result, err := self.Store.DB.PromotionRedemptions.GetOneAndUpdate(
+		ctx,
+		bson.M{"bookingID": bookingID, "releasedAt": nil},
+		bson.M{"$set": bson.M{"releasedAt": now}},
+		&types.PromotionRedemption{},
+	)
+	if err != nil {
+		return err
+	}
+	redemption := CastPtrInterface[types.PromotionRedemption](result)
+	if redemption == nil {
+		return nil
+	}
+	_, err = self.Store.DB.Promotions.Update(
+		ctx, bson.M{"_id": redemption.PromotionID}, bson.M{"$inc": bson.M{"redemptions": -1}},
+	)
+	return err
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/types"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Checks parts of rate plan validator can't express
+func validateRatePlan(ratePlan *types.RatePlan, currency types.Currency) error {
+	fields := map[string]string{}
+	if ratePlan.PriceAdjustment <= -fullTaxRate {
+		fields["priceAdjustment"] = "Adjustment should be above -10000 hundredths of percent"
+	}
+	supplement := ratePlan.GuestNightSupplement
+	switch {
+	case supplement.IsNegative():
+		fields["guestNightSupplement"] = "Supplement can't be negative"
+	case !supplement.IsZero() && supplement.Currency != currency:
+		fields["guestNightSupplement"] = fmt.Sprintf("Supplement should be in %s", currency)
+	}
+	if ratePlan.Kind == types.NonRefundableRatePlanKind && ratePlan.Cancellation.Refundable {
+		fields["cancellation.refundable"] = "Non-refundable plan can't be refundable"
+	}
+	if ratePlan.Cancellation.FreeCancellationDays < 0 {
+		fields["cancellation.freeCancellationDays"] = "Days can't be negative"
+	}
+	if ratePlan.MinNights < 0 {
+		fields["minNights"] = "Minimum nights can't be negative"
+	}
+	if ratePlan.MaxNights < 0 || (ratePlan.MaxNights != 0 && ratePlan.MaxNights < ratePlan.MinNights) {
+		fields["maxNights"] = "Maximum nights should be at least minimum nights"
+	}
+	if ratePlan.AdvancePurchaseDays < 0 {
+		fields["advancePurchaseDays"] = "Days can't be negative"
+	}
+	if len(ratePlan.MinTier) != 0 && ratePlan.Kind != types.MemberOnlyRatePlanKind {
+		fields["minTier"] = "Only member only plans have minimum tier"
+	}
+	if len(fields) != 0 {
+		return ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Cost of nights with plan's adjustment and supplements. Without
+// plan it's plain room price
+func RatePlanRoomCost(
+	ratePlan *types.RatePlan, price types.Money, nights int, guests int,
+) types.Money {
+	if ratePlan == nil {
+		return price.Mul(int64(nights))
+	}
+	nightly := price.MulRat(fullTaxRate+ratePlan.PriceAdjustment, fullTaxRate)
+	supplement := ratePlan.GuestNightSupplement.Mul(int64(guests))
+	return nightly.Add(supplement).Mul(int64(nights))
+}
+
+// Reason plan can't sell room for stay, empty if it can. Advance
+// purchase is checked only for new arrival dates
+func RatePlanMismatch(
+	ratePlan *types.RatePlan, roomType types.RoomType, dateFrom civil.Date, dateTo civil.Date,
+	today civil.Date, checkAdvance bool,
+) string {
+	nights := dateTo.DaysSince(dateFrom)
+	switch {
+	case len(ratePlan.RoomTypes) != 0 && !containsRoomType(ratePlan.RoomTypes, roomType):
+		return "Rate plan isn't sold for this room"
+	case nights < ratePlan.MinNights:
+		return fmt.Sprintf("Rate plan requires at least %d nights", ratePlan.MinNights)
+	case ratePlan.MaxNights != 0 && nights > ratePlan.MaxNights:
+		return fmt.Sprintf("Rate plan allows at most %d nights", ratePlan.MaxNights)
+	case checkAdvance && dateFrom.DaysSince(today) < ratePlan.AdvancePurchaseDays:
+		return fmt.Sprintf(
+			"Rate plan should be booked at least %d days before arrival", ratePlan.AdvancePurchaseDays,
+		)
+	}
+	return ""
+}
+
+// Reason plan can't price room, empty if it can. Supplement is in
+// hotel's currency, rooms priced in another one can't be sold with it
+func ratePlanPriceMismatch(ratePlan *types.RatePlan, price types.Money) string {
+	if types.CheckSameCurrency(ratePlan.GuestNightSupplement, price) != nil {
+		return fmt.Sprintf(
+			"Rate plan's supplement is in %s, room is priced in %s",
+			ratePlan.GuestNightSupplement.Currency, price.Currency,
+		)
+	}
+	return ""
+}
+
+// Whether guest may cancel booking made with plan today. Bookings without
+// plan are always refundable
+func FreeCancellationAllowed(
+	ratePlan *types.BookingRatePlan, dateFrom civil.Date, today civil.Date,
+) bool {
+	if ratePlan == nil {
+		return true
+	}
+	policy := ratePlan.Cancellation
+	return policy.Refundable && dateFrom.DaysSince(today) >= policy.FreeCancellationDays
+}
+
+// Whether tier is the same as or above another one
+func loyaltyTierReached(tier types.LoyaltyTier, min types.LoyaltyTier) bool {
+	for _, item := range loyaltyTiers {
+		switch item.Tier {
+		case tier:
+			return true
+		case min:
+			return false
+		}
+	}
+	return false
+}
+
+type RatePlanController struct {
+	Store *Store
+}
+
+func (self *RatePlanController) getByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.RatePlan, error) {
+	result, err := self.Store.DB.RatePlans.GetOneByID(ctx, id, &types.RatePlan{})
+	if err != nil {
+		return nil, err
+	}
+	return CastPtrInterface[types.RatePlan](result), nil
+}
+
+func (self *RatePlanController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.RatePlan, error) {
+	ratePlan, err := self.getByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ratePlan == nil {
+		return nil, NotFoundError{Entity: "Rate plan"}
+	}
+	err = RequireHotelAccess(ctx, ratePlan.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	return ratePlan, nil
+}
+
+type RatePlanGetQueryParams struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+}
+
+func (self *RatePlanController) Get(
+	ctx context.Context, params *RatePlanGetQueryParams,
+) ([]*types.RatePlan, error) {
+	if params == nil {
+		params = &RatePlanGetQueryParams{}
+	}
+	query := bson.M{}
+	if !params.HotelID.IsZero() {
+		query["hotelID"] = params.HotelID
+	}
+	if scope := hotelScopeQuery(ctx); scope != nil {
+		if params.HotelID.IsZero() {
+			query["hotelID"] = scope
+		} else if err := RequireHotelAccess(ctx, params.HotelID); err != nil {
+			return nil, err
+		}
+	}
+	result, err := self.Store.DB.RatePlans.Get(ctx, query, []*types.RatePlan{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.RatePlan](result), nil
+}
+
+func (self *RatePlanController) getForHotel(
+	ctx context.Context, hotelID primitive.ObjectID,
+) ([]*types.RatePlan, error) {
+	result, err := self.Store.DB.RatePlans.Get(ctx, bson.M{"hotelID": hotelID}, []*types.RatePlan{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.RatePlan](result), nil
+}
+
+func (self *RatePlanController) hotelCurrency(
+	ctx context.Context, hotelID primitive.ObjectID,
+) (types.Currency, error) {
+	hotel, err := self.Store.CT.Hotels.GetByID(ctx, hotelID)
+	if err != nil {
+		return "", err
+	}
+	if hotel == nil {
+		return "", NewFieldError("hotelID", "Hotel not found")
+	}
+	return HotelCurrency(hotel), nil
+}
+
+// Admin only
+func (self *RatePlanController) Create(
+	ctx context.Context, ratePlan *types.RatePlan,
+) (*types.RatePlan, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	currency, err := self.hotelCurrency(ctx, ratePlan.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	err = validateRatePlan(ratePlan, currency)
+	if err != nil {
+		return nil, err
+	}
+	ratePlan.CreatedAt = time.Now()
+	ratePlan.Version = 1
+	var created *types.RatePlan
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.RatePlans.Create(ctx, ratePlan)
+		if err != nil {
+			return err
+		}
+		created, err = self.getByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "RatePlan", id, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Hotel stays as it is, bookings made keep plan as it was. Admin only
+func (self *RatePlanController) UpdateByID(
+	ctx context.Context, id primitive.ObjectID, ratePlan *types.RatePlan,
+) (*types.RatePlan, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	ratePlanBefore, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckVersion("Rate plan", ratePlan.Version, ratePlanBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	ratePlan.ID = id
+	ratePlan.HotelID = ratePlanBefore.HotelID
+	ratePlan.CreatedAt = ratePlanBefore.CreatedAt
+	currency, err := self.hotelCurrency(ctx, ratePlan.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	err = validateRatePlan(ratePlan, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *types.RatePlan
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.RatePlans, "Rate plan", id, ratePlanBefore.Version,
+			ratePlanBefore, ratePlan,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.getByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "RatePlan", id, ratePlanBefore, updated,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Bookings made keep plan as it was, until their stays change. Admin only
+func (self *RatePlanController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	ratePlan, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = CheckVersion("Rate plan", version, ratePlan.Version)
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := DeleteVersionedByID(ctx, self.Store.DB.RatePlans, "Rate plan", id, ratePlan.Version)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "RatePlan", id, ratePlan, nil)
+	})
+}
+
+// Reason user can't book member only plan, empty if they can or plan
+// is for everyone. Partners using API keys get public rates only
+func (self *RatePlanController) memberMismatch(
+	ctx context.Context, ratePlan *types.RatePlan, userID primitive.ObjectID,
+) (string, error) {
+	if ratePlan.Kind != types.MemberOnlyRatePlanKind {
+		return "", nil
+	}
+	if GetAPIKeyFromContext(ctx) != nil {
+		return "Rate plan is for members only", nil
+	}
+	if len(ratePlan.MinTier) == 0 {
+		return "", nil
+	}
+	account, err := self.Store.CT.Loyalty.getAccount(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if !loyaltyTierReached(account.Tier, ratePlan.MinTier) {
+		return fmt.Sprintf("Rate plan is for %s members", ratePlan.MinTier), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,304 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/types"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Checks parts of restriction validator can't express
+func validateStayRestriction(restriction *types.StayRestriction) error {
+	fields := map[string]string{}
+	if restriction.Kind.HasNights() {
+		if restriction.Nights < 1 {
+			fields["nights"] = "Nights should be at least 1"
+		}
+	} else if restriction.Nights != 0 {
+		fields["nights"] = fmt.Sprintf("Nights aren't used by %s rule", restriction.Kind)
+	}
+	for _, weekday := range restriction.Weekdays {
+		if weekday < time.Sunday || weekday > time.Saturday {
+			fields["weekdays"] = "Weekdays should be between 0 (Sunday) and 6 (Saturday)"
+		}
+	}
+	if len(fields) != 0 {
+		return ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Whether rule covers date for rooms of type
+func restrictionCovers(
+	restriction *types.StayRestriction, roomType types.RoomType, date civil.Date,
+) bool {
+	if restriction.RoomType != 0 && restriction.RoomType != roomType {
+		return false
+	}
+	if date.Before(restriction.DateFrom) || date.After(restriction.DateTo) {
+		return false
+	}
+	if len(restriction.Weekdays) == 0 {
+		return true
+	}
+	weekday := date.In(time.UTC).Weekday()
+	for _, item := range restriction.Weekdays {
+		if item == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// Stay's violations of rules by booking field, empty if there are none.
+// Only the first violation of each field is kept
+func StayRestrictionErrors(
+	restrictions []*types.StayRestriction, roomType types.RoomType,
+	dateFrom civil.Date, dateTo civil.Date,
+) map[string]string {
+	errors := map[string]string{}
+	set := func(field string, msg string) {
+		if _, ok := errors[field]; !ok {
+			errors[field] = msg
+		}
+	}
+	nights := dateTo.DaysSince(dateFrom)
+	for _, restriction := range restrictions {
+		switch restriction.Kind {
+		case types.MinLOSRestrictionKind:
+			if restrictionCovers(restriction, roomType, dateFrom) && nights < restriction.Nights {
+				set("dateTo", fmt.Sprintf(
+					"Stays arriving on %s should last at least %d nights", dateFrom, restriction.Nights,
+				))
+			}
+		case types.MaxLOSRestrictionKind:
+			if restrictionCovers(restriction, roomType, dateFrom) && nights > restriction.Nights {
+				set("dateTo", fmt.Sprintf(
+					"Stays arriving on %s should last at most %d nights", dateFrom, restriction.Nights,
+				))
+			}
+		case types.ClosedToArrivalRestrictionKind:
+			if restrictionCovers(restriction, roomType, dateFrom) {
+				set("dateFrom", fmt.Sprintf("Arrivals aren't allowed on %s", dateFrom))
+			}
+		case types.ClosedToDepartureRestrictionKind:
+			if restrictionCovers(restriction, roomType, dateTo) {
+				set("dateTo", fmt.Sprintf("Departures aren't allowed on %s", dateTo))
+			}
+		case types.StopSellRestrictionKind:
+			for date := dateFrom; date.Before(dateTo); date = date.AddDays(1) {
+				if restrictionCovers(restriction, roomType, date) {
+					set("roomID", fmt.Sprintf("Room isn't sold for night of %s", date))
+					break
+				}
+			}
+		}
+	}
+	return errors
+}
+
+type StayRestrictionController struct {
+	Store *Store
+}
+
+func (self *StayRestrictionController) getByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.StayRestriction, error) {
+	result, err := self.Store.DB.StayRestrictions.GetOneByID(ctx, id, &types.StayRestriction{})
+	if err != nil {
+		return nil, err
+	}
+	return CastPtrInterface[types.StayRestriction](result), nil
+}
+
+func (self *StayRestrictionController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.StayRestriction, error) {
+	restriction, err := self.getByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if restriction == nil {
+		return nil, NotFoundError{Entity: "Stay restriction"}
+	}
+	err = RequireHotelAccess(ctx, restriction.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	return restriction, nil
+}
+
+type StayRestrictionGetQueryParams struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+}
+
+func (self *StayRestrictionController) Get(
+	ctx context.Context, params *StayRestrictionGetQueryParams,
+) ([]*types.StayRestriction, error) {
+	if params == nil {
+		params = &StayRestrictionGetQueryParams{}
+	}
+	query := bson.M{}
+	if !params.HotelID.IsZero() {
+		query["hotelID"] = params.HotelID
+	}
+	if scope := hotelScopeQuery(ctx); scope != nil {
+		if params.HotelID.IsZero() {
+			query["hotelID"] = scope
+		} else if err := RequireHotelAccess(ctx, params.HotelID); err != nil {
+			return nil, err
+		}
+	}
+	result, err := self.Store.DB.StayRestrictions.GetSorted(
+		ctx, query, bson.D{{Key: "dateFrom", Value: 1}}, 0, []*types.StayRestriction{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.StayRestriction](result), nil
+}
+
+// Hotel's rules covering any date from arrival to departure
+func (self *StayRestrictionController) getForStay(
+	ctx context.Context, hotelID primitive.ObjectID, dateFrom civil.Date, dateTo civil.Date,
+) ([]*types.StayRestriction, error) {
+	result, err := self.Store.DB.StayRestrictions.GetSorted(
+		ctx,
+		bson.M{
+			"hotelID":  hotelID,
+			"dateFrom": bson.M{"$lte": dateTo},
+			"dateTo":   bson.M{"$gte": dateFrom},
+		},
+		bson.D{{Key: "dateFrom", Value: 1}},
+		0,
+		[]*types.StayRestriction{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.StayRestriction](result), nil
+}
+
+// Admin only
+func (self *StayRestrictionController) Create(
+	ctx context.Context, restriction *types.StayRestriction,
+) (*types.StayRestriction, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = validateStayRestriction(restriction)
+	if err != nil {
+		return nil, err
+	}
+	restriction.CreatedAt = time.Now()
+	restriction.Version = 1
+	var created *types.StayRestriction
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.StayRestrictions.Create(ctx, restriction)
+		if err != nil {
+			return err
+		}
+		created, err = self.getByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "StayRestriction", id, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Hotel stays as it is. Bookings made are kept, rules apply once their
+// stays change. Admin only
+func (self *StayRestrictionController) UpdateByID(
+	ctx context.Context, id primitive.ObjectID, restriction *types.StayRestriction,
+) (*types.StayRestriction, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	restrictionBefore, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckVersion("Stay restriction", restriction.Version, restrictionBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	restriction.ID = id
+	restriction.HotelID = restrictionBefore.HotelID
+	restriction.CreatedAt = restrictionBefore.CreatedAt
+	err = validateStayRestriction(restriction)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *types.StayRestriction
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.StayRestrictions, "Stay restriction", id, restrictionBefore.Version,
+			restrictionBefore, restriction,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.getByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "StayRestriction", id, restrictionBefore, updated,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Admin only
+func (self *StayRestrictionController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	restriction, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = CheckVersion("Stay restriction", version, restriction.Version)
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := DeleteVersionedByID(
+			ctx, self.Store.DB.StayRestrictions, "Stay restriction", id, restriction.Version,
+		)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.DeleteAuditAction, "StayRestriction", id, restriction, nil,
+		)
+	})
+}
+
+// Violations of hotel's rules by stay in room, see StayRestrictionErrors
+func (self *StayRestrictionController) stayErrors(
+	ctx context.Context, room *types.Room, dateFrom civil.Date, dateTo civil.Date,
+) (map[string]string, error) {
+	restrictions, err := self.getForStay(ctx, room.HotelID, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+	return StayRestrictionErrors(restrictions, room.Type, dateFrom, dateTo), nil
+}
@@ -7,6 +7,7 @@ import (
 	"hotel/types"
 	"time"
 
+	"cloud.google.com/go/civil"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -40,7 +41,9 @@ func (self *RoomController) RoomToUnfolded(ctx context.Context, room *types.Room
 func (self *RoomController) GetByID(
 	ctx context.Context, id primitive.ObjectID,
 ) (*types.Room, error) {
-	result, err := self.Store.DB.Rooms.GetOneByID(ctx, id, &types.Room{})
+	result, err := self.Store.DB.Rooms.GetOne(
+		ctx, notDeleted(bson.M{"_id": id}), &types.Room{},
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -54,11 +57,19 @@ func (self *RoomController) GetUnfoldedByID(
 	if err != nil {
 		return nil, err
 	}
+	if room != nil {
+		err = RequireHotelAccess(ctx, room.HotelID)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return self.RoomToUnfolded(ctx, room)
 }
 
 type RoomGetQueryParams struct {
 	HotelID primitive.ObjectID `bson:"hotelID,omitempty" json:"hotelID"`
+	// Lists deleted rooms instead, admin only
+	Deleted bool `bson:"-" json:"deleted"`
 }
 
 func (self *RoomController) Get(
@@ -67,61 +78,87 @@ func (self *RoomController) Get(
 	if query == nil {
 		query = &RoomGetQueryParams{}
 	}
-	queryMarshalled, err := bson.Marshal(query)
+	filter := bson.M{}
+	if !query.HotelID.IsZero() {
+		filter["hotelID"] = query.HotelID
+	}
+	if scope := hotelScopeQuery(ctx); scope != nil {
+		if query.HotelID.IsZero() {
+			filter["hotelID"] = scope
+		} else if err := RequireHotelAccess(ctx, query.HotelID); err != nil {
+			return nil, err
+		}
+	}
+	filter, err := deletedFilter(self.Store.DB, ctx, filter, query.Deleted)
 	if err != nil {
 		return nil, err
 	}
-	result, err := self.Store.DB.Rooms.Get(ctx, queryMarshalled, []*types.Room{})
+	result, err := self.Store.DB.Rooms.Get(ctx, filter, []*types.Room{})
 	if err != nil {
 		return nil, err
 	}
 	return CastInterface[[]*types.Room](result), nil
 }
 
-func (self *RoomController) Validate(room *types.RoomUnfolded) map[string]string {
-	errors := map[string]string{}
-	if !room.Type.IsValid() {
-		errors["type"] = fmt.Sprintf("Invalid room type")
+// Price is requested only for new rooms and when type or currency
+// changes, so unrelated updates don't reprice the room
+func (self *RoomController) Evaluate(
+	ctx context.Context, room *types.RoomUnfolded, roomBefore *types.Room,
+) error {
+	currency := HotelCurrency(room.Hotel)
+	if roomBefore != nil && roomBefore.Type == room.Type && roomBefore.Price.Currency == currency {
+		room.Price = roomBefore.Price
+		return nil
 	}
-
-	return errors
-}
-
-func (self *RoomController) Evaluate(ctx context.Context, room *types.RoomUnfolded) error {
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 	resp, err := self.Store.RoomPrices.GetRoomPrice(
 		ctxWithTimeout, &roomprices_rpc.RoomPriceRequest{
-			Type: int64(room.Type),
+			Type:     int64(room.Type),
+			Currency: string(currency),
 		},
 	)
 	if err != nil {
-		return err
+		return UpstreamUnavailableError{Service: "roomprices", Err: err}
 	}
-	room.Price = resp.Price
+	price := resp.GetPrice()
+	if types.Currency(price.GetCurrency()) != currency {
+		return UpstreamUnavailableError{Service: "roomprices", Err: fmt.Errorf(
+			"Price quoted in %q instead of %s", price.GetCurrency(), currency,
+		)}
+	}
+	room.Price = types.NewMoney(price.GetMinor(), currency)
 	return nil
 }
 
 func (self *RoomController) Create(
 	ctx context.Context, room *types.Room,
 ) (*types.RoomUnfolded, error) {
-	roomUnfolded, err := self.RoomToUnfolded(ctx, room)
+	err := RequireHotelAccess(ctx, room.HotelID)
 	if err != nil {
 		return nil, err
 	}
-	errs := self.Validate(roomUnfolded)
-	if len(errs) != 0 {
-		return nil, ValidationError{Fields: errs}
-	}
-	err = self.Evaluate(ctx, roomUnfolded)
+	roomUnfolded, err := self.RoomToUnfolded(ctx, room)
 	if err != nil {
 		return nil, err
 	}
-	id, err := self.Store.DB.Rooms.Create(ctx, roomUnfolded.Room)
+	err = self.Evaluate(ctx, roomUnfolded, nil)
 	if err != nil {
 		return nil, err
 	}
-	created, err := self.GetByID(ctx, id)
+	roomUnfolded.Version = 1
+	var created *types.Room
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.Rooms.Create(ctx, roomUnfolded.Room)
+		if err != nil {
+			return err
+		}
+		created, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Room", id, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -131,29 +168,199 @@ func (self *RoomController) Create(
 func (self *RoomController) UpdateByID(
 	ctx context.Context, id primitive.ObjectID, room *types.Room,
 ) (*types.RoomUnfolded, error) {
+	roomBefore, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if roomBefore == nil {
+		return nil, NotFoundError{Entity: "Room"}
+	}
+	err = RequireHotelAccess(ctx, roomBefore.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckVersion("Room", room.Version, roomBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = RequireHotelAccess(ctx, room.HotelID)
+	if err != nil {
+		return nil, err
+	}
 	roomUnfolded, err := self.RoomToUnfolded(ctx, room)
 	if err != nil {
 		return nil, err
 	}
 
-	errs := self.Validate(roomUnfolded)
-	if len(errs) != 0 {
-		return nil, ValidationError{Fields: errs}
+	err = self.Evaluate(ctx, roomUnfolded, roomBefore)
+	if err != nil {
+		return nil, err
 	}
-	err = self.Evaluate(ctx, roomUnfolded)
+
+	var updated *types.RoomUnfolded
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Rooms, "Room", id, roomBefore.Version,
+			roomBefore, roomUnfolded.Room,
+		)
+		if err != nil {
+			return err
+		}
+		if roomUnfolded.Price != roomBefore.Price {
+			err = emitEvent(ctx, self.Store.DB, &types.RoomRepriced{
+				HotelID:  roomUnfolded.HotelID,
+				RoomID:   id,
+				OldPrice: roomBefore.Price,
+				NewPrice: roomUnfolded.Price,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		updated, err = self.GetUnfoldedByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Room", id, roomBefore, updated.Room,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return updated, nil
+}
 
-	err = self.Store.DB.Rooms.UpdateByID(ctx, id, roomUnfolded.Room)
+func (self *RoomController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	room, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return NotFoundError{Entity: "Room"}
+	}
+	err = RequireHotelAccess(ctx, room.HotelID)
+	if err != nil {
+		return err
+	}
+	err = CheckVersion("Room", version, room.Version)
+	if err != nil {
+		return err
+	}
+	deleted := *room
+	now := time.Now()
+	deleted.DeletedAt = &now
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		// Locked like on booking, so room can't be booked while it's deleted
+		err := self.Store.CT.Bookings.lockRoom(ctx, id)
+		if err != nil {
+			return err
+		}
+		hasBookings, err := self.Store.CT.Bookings.HasActiveFrom(
+			ctx, []primitive.ObjectID{id}, civil.DateOf(now),
+		)
+		if err != nil {
+			return err
+		}
+		if hasBookings {
+			return errHasActiveBookings
+		}
+		err = softDeleteByID(ctx, self.Store.DB.Rooms, "Room", id, room.Version, now)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "Room", id, room, &deleted)
+	})
+}
+
+// Gets room regardless of whether it's deleted
+func (self *RoomController) getAnyByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Room, error) {
+	result, err := self.Store.DB.Rooms.GetOneByID(ctx, id, &types.Room{})
+	if err != nil {
+		return nil, err
+	}
+	room := CastPtrInterface[types.Room](result)
+	if room == nil {
+		return nil, NotFoundError{Entity: "Room"}
+	}
+	err = RequireHotelAccess(ctx, room.HotelID)
 	if err != nil {
 		return nil, err
 	}
+	return room, nil
+}
+
+// Brings deleted room back, unless its hotel is deleted too
+func (self *RoomController) RestoreByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.RoomUnfolded, error) {
+	room, err := self.getAnyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if room.DeletedAt != nil {
+		hotel, err := self.Store.CT.Hotels.GetByID(ctx, room.HotelID)
+		if err != nil {
+			return nil, err
+		}
+		if hotel == nil {
+			return nil, ConflictError{
+				Code:    ParentDeletedErrorCode,
+				Message: "Room's hotel is deleted, restore the hotel first",
+			}
+		}
+		err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+			err := restoreByID(ctx, self.Store.DB.Rooms, id)
+			if err != nil {
+				return err
+			}
+			restored := *room
+			restored.DeletedAt = nil
+			return self.Store.CT.Audit.Record(
+				ctx, types.RestoreAuditAction, "Room", id, room, &restored,
+			)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	return self.GetUnfoldedByID(ctx, id)
 }
 
-func (self *RoomController) DeleteByID(
+// Removes deleted room permanently along with its bookings
+func (self *RoomController) PurgeByID(
 	ctx context.Context, id primitive.ObjectID,
 ) error {
-	return self.Store.DB.Rooms.DeleteByID(ctx, id)
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	room, err := self.getAnyByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if room.DeletedAt == nil {
+		return errNotDeleted("Room")
+	}
+	return self.purge(ctx, room)
+}
+
+func (self *RoomController) purge(ctx context.Context, room *types.Room) error {
+	id := room.ID
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Bookings.Delete(ctx, bson.M{"roomID": id})
+		if err != nil {
+			return err
+		}
+		_, err = self.Store.DB.Rooms.DeleteByID(ctx, id, 0)
+		if err != nil {
+			return err
+		}
+		// Purge of room covers its bookings
+		return self.Store.CT.Audit.Record(ctx, types.PurgeAuditAction, "Room", id, room, nil)
+	})
 }
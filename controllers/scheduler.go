@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"hotel/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	jobRunsDefaultLimit = 100
+	jobRunsMaxLimit     = 1000
+)
+
+// Read-only view of scheduler.Scheduler state for admins
+type SchedulerController struct {
+	Store *Store
+}
+
+func (self *SchedulerController) GetSchedules(ctx context.Context) ([]*types.Schedule, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.Schedules.GetSorted(
+		ctx, bson.M{}, bson.D{{Key: "_id", Value: 1}}, 0, []*types.Schedule{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Schedule](result), nil
+}
+
+type JobRunGetQueryParams struct {
+	Job    string             `json:"job"`
+	Status types.JobRunStatus `json:"status" validate:"enum"`
+	// Newest runs are returned first
+	Limit int64 `json:"limit"`
+}
+
+func (self *SchedulerController) GetRuns(
+	ctx context.Context, params *JobRunGetQueryParams,
+) ([]*types.JobRun, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = &JobRunGetQueryParams{}
+	}
+	query := bson.M{}
+	if len(params.Job) != 0 {
+		query["job"] = params.Job
+	}
+	if len(params.Status) != 0 {
+		query["status"] = params.Status
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = jobRunsDefaultLimit
+	}
+	if limit > jobRunsMaxLimit {
+		limit = jobRunsMaxLimit
+	}
+	result, err := self.Store.DB.JobRuns.GetSorted(
+		ctx, query, bson.D{{Key: "startedAt", Value: -1}}, limit, []*types.JobRun{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.JobRun](result), nil
+}
@@ -2,7 +2,12 @@ package controllers
 
 import (
 	"hotel/db"
+	"hotel/exchange"
+	"hotel/lockout"
+	"hotel/mailer"
+	"hotel/notifications"
 	roomprices_rpc "hotel/services/roomprices/rpc"
+	"hotel/validate"
 
 	"google.golang.org/grpc"
 )
@@ -12,23 +17,65 @@ type Controllers struct {
 	Hotels   *HotelController
 	Rooms    *RoomController
 	Bookings *BookingController
+	APIKeys  *APIKeyController
+	Audit    *AuditController
+	Webhooks *WebhookController
+	// Streams of booked dates changes
+	Availability *AvailabilityController
+	Holds        *HoldController
+	Scheduler    *SchedulerController
+	// Delivery log of notifications
+	Notifications *NotificationController
+	Invoices      *InvoiceController
+	Promotions    *PromotionController
+	Loyalty       *LoyaltyController
+	RatePlans     *RatePlanController
+	Restrictions  *StayRestrictionController
+	Waitlist      *WaitlistController
 }
 
 type Store struct {
 	DB         *db.DB
 	CT         *Controllers
 	RoomPrices roomprices_rpc.RoomPricesServiceClient
+	Mailer     mailer.Mailer
+	Notifier   *notifications.Notifier
+	// Optional, used only for display prices
+	ExchangeRates *exchange.Rates
+	// Failed login attempts per account and per IP
+	LoginAttempts lockout.Counter
+	Validator     *validate.Validator
 }
 
-func NewStore(DB *db.DB, roompricesConn *grpc.ClientConn) *Store {
+func NewStore(
+	DB *db.DB, roompricesConn *grpc.ClientConn, mailer mailer.Mailer,
+) *Store {
 	store := &Store{
-		DB:         DB,
-		CT:         &Controllers{},
-		RoomPrices: roomprices_rpc.NewRoomPricesServiceClient(roompricesConn),
+		DB:            DB,
+		CT:            &Controllers{},
+		RoomPrices:    roomprices_rpc.NewRoomPricesServiceClient(roompricesConn),
+		Mailer:        mailer,
+		Notifier:      notifications.NewNotifier(DB, notifications.GetChannels(mailer)),
+		ExchangeRates: exchange.GetRates(),
+		LoginAttempts: lockout.GetCounter(DB),
+		Validator:     newValidator(DB),
 	}
 	store.CT.Users = &UserController{store}
 	store.CT.Hotels = &HotelController{store}
 	store.CT.Rooms = &RoomController{store}
 	store.CT.Bookings = &BookingController{store}
+	store.CT.APIKeys = &APIKeyController{store}
+	store.CT.Audit = &AuditController{store}
+	store.CT.Webhooks = &WebhookController{store}
+	store.CT.Availability = &AvailabilityController{store}
+	store.CT.Holds = &HoldController{store}
+	store.CT.Scheduler = &SchedulerController{store}
+	store.CT.Notifications = &NotificationController{store}
+	store.CT.Invoices = &InvoiceController{store}
+	store.CT.Promotions = &PromotionController{store}
+	store.CT.Loyalty = &LoyaltyController{store}
+	store.CT.RatePlans = &RatePlanController{store}
+	store.CT.Restrictions = &StayRestrictionController{store}
+	store.CT.Waitlist = &WaitlistController{store}
 	return store
 }
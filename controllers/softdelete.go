@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/db"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hides soft deleted documents
+func notDeleted(query bson.M) bson.M {
+	query["deletedAt"] = nil
+	return query
+}
+
+// Lists either live or deleted documents. Deleted ones are visible
+// to admins only, e.g. to find what to restore or purge
+func deletedFilter(
+	dbStore *db.DB, ctx context.Context, query bson.M, deleted bool,
+) (bson.M, error) {
+	if !deleted {
+		return notDeleted(query), nil
+	}
+	_, err := RequireAdmin(dbStore, ctx)
+	if err != nil {
+		return nil, err
+	}
+	query["deletedAt"] = bson.M{"$ne": nil}
+	return query, nil
+}
+
+// Marks document as deleted, if it's still at given version
+func softDeleteByID(
+	ctx context.Context, store *db.MongoStore, entity string,
+	id primitive.ObjectID, version int64, at time.Time,
+) error {
+	updated, err := store.UpdateByID(ctx, id, version, bson.M{"deletedAt": at})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return PreconditionFailedError{Entity: entity}
+	}
+	return nil
+}
+
+func restoreByID(
+	ctx context.Context, store *db.MongoStore, id primitive.ObjectID,
+) error {
+	_, err := store.UpdateByID(ctx, id, 0, bson.M{"deletedAt": nil})
+	return err
+}
+
+// Purge is irreversible, so it's allowed only for already deleted entities
+func errNotDeleted(entity string) ConflictError {
+	return ConflictError{
+		Code:    NotDeletedErrorCode,
+		Message: fmt.Sprintf("%s isn't deleted, delete it before purging", entity),
+	}
+}
+
+var errHasActiveBookings = ConflictError{
+	Code:    HasActiveBookingsErrorCode,
+	Message: "Room has upcoming bookings or holds, cancel them first",
+}
+
+// Permanently removes hotels, rooms and bookings deleted before cutoff.
+// Returns number of purged entities, cascaded ones aren't counted
+func PurgeDeletedBefore(ctx context.Context, store *Store, cutoff time.Time) (int64, error) {
+	filter := func() bson.M {
+		return bson.M{"deletedAt": bson.M{"$ne": nil, "$lt": cutoff}}
+	}
+	purged := int64(0)
+
+	result, err := store.DB.Hotels.Get(ctx, filter(), []*types.Hotel{})
+	if err != nil {
+		return purged, err
+	}
+	for _, hotel := range CastInterface[[]*types.Hotel](result) {
+		err = store.CT.Hotels.purge(ctx, hotel)
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	result, err = store.DB.Rooms.Get(ctx, filter(), []*types.Room{})
+	if err != nil {
+		return purged, err
+	}
+	for _, room := range CastInterface[[]*types.Room](result) {
+		err = store.CT.Rooms.purge(ctx, room)
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	result, err = store.DB.Bookings.Get(ctx, filter(), []*types.Booking{})
+	if err != nil {
+		return purged, err
+	}
+	for _, booking := range CastInterface[[]*types.Booking](result) {
+		err = store.CT.Bookings.purge(ctx, booking)
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Hundredths of percent in 100%
+const fullTaxRate = 10000
+
+var taxRuleNames = map[types.TaxKind]string{
+	types.VATTaxKind:         "VAT",
+	types.CityTaxKind:        "City tax",
+	types.CleaningFeeTaxKind: "Cleaning fee",
+	types.ResortFeeTaxKind:   "Resort fee",
+}
+
+// Checks rules given by client against each other and hotel's currency
+func validateTaxRules(rules []*types.TaxRule, currency types.Currency) error {
+	fields := map[string]string{}
+	seen := map[types.TaxKind]bool{}
+	for i, rule := range rules {
+		name := fmt.Sprintf("rules[%d]", i)
+		switch {
+		case !rule.Kind.IsValid():
+			fields[name+".kind"] = "Unknown kind of tax"
+		case seen[rule.Kind]:
+			fields[name+".kind"] = fmt.Sprintf("There can be only one %s rule", rule.Kind)
+		case rule.Kind == types.VATTaxKind && (rule.Rate <= 0 || rule.Rate > fullTaxRate):
+			fields[name+".rate"] = "Rate should be between 1 and 10000 hundredths of percent"
+		case rule.Kind == types.VATTaxKind && !rule.Amount.IsZero():
+			fields[name+".amount"] = "VAT is a rate, not an amount"
+		case rule.Kind != types.VATTaxKind && rule.Rate != 0:
+			fields[name+".rate"] = "Fees are amounts, not rates"
+		case rule.Kind != types.VATTaxKind && rule.Amount.IsNegative():
+			fields[name+".amount"] = "Amount can't be negative"
+		case rule.Kind != types.VATTaxKind && rule.Amount.Currency != currency:
+			fields[name+".amount"] = fmt.Sprintf("Amount should be in %s", currency)
+		}
+		seen[rule.Kind] = true
+		if len(rule.Name) == 0 {
+			rule.Name = taxRuleNames[rule.Kind]
+		}
+	}
+	if len(fields) != 0 {
+		return ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// Applies hotel's rules to cost of nights. Fees go first, then VAT is
+// taken from room cost and exclusive fees except city tax. Inclusive VAT
+// is extracted from that gross amount, exclusive one is added on top
+func ApplyTaxRules(
+	rules []*types.TaxRule, roomCost types.Money, nights int, guests int,
+) ([]*types.BookingCharge, types.Money) {
+	charges := []*types.BookingCharge{}
+	total, taxable := roomCost, roomCost
+	charge := func(rule *types.TaxRule, amount types.Money) {
+		charges = append(charges, &types.BookingCharge{
+			Kind: rule.Kind, Name: rule.Name, Amount: amount, Inclusive: rule.Inclusive,
+		})
+		if !rule.Inclusive {
+			total = total.Add(amount)
+		}
+	}
+	for _, rule := range rules {
+		var amount types.Money
+		switch rule.Kind {
+		case types.CityTaxKind:
+			amount = rule.Amount.Mul(int64(nights * guests))
+		case types.CleaningFeeTaxKind:
+			amount = rule.Amount
+		case types.ResortFeeTaxKind:
+			amount = rule.Amount.Mul(int64(nights))
+		default:
+			continue
+		}
+		charge(rule, amount)
+		if !rule.Inclusive && rule.Kind != types.CityTaxKind {
+			taxable = taxable.Add(amount)
+		}
+	}
+	for _, rule := range rules {
+		if rule.Kind != types.VATTaxKind {
+			continue
+		}
+		if rule.Inclusive {
+			charge(rule, taxable.MulRat(rule.Rate, fullTaxRate+rule.Rate))
+		} else {
+			charge(rule, taxable.MulRat(rule.Rate, fullTaxRate))
+		}
+	}
+	return charges, total
+}
+
+// Replaces taxes and fees of hotel. Bookings keep charges they were
+// made with, until their room or dates change. Admin only
+func (self *HotelController) SetTaxRulesByID(
+	ctx context.Context, id primitive.ObjectID, version int64, rules []*types.TaxRule,
+) (*types.HotelWithRooms, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = RequireHotelAccess(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	hotelBefore, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hotelBefore == nil {
+		return nil, NotFoundError{Entity: "Hotel"}
+	}
+	err = CheckVersion("Hotel", version, hotelBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	err = validateTaxRules(rules, HotelCurrency(hotelBefore))
+	if err != nil {
+		return nil, err
+	}
+	hotel := *hotelBefore
+	hotel.TaxRules = rules
+	var updated *types.Hotel
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Hotels, "Hotel", id, hotelBefore.Version, hotelBefore, &hotel,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Hotel", id, hotelBefore, updated,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return self.HotelToWIthRooms(ctx, updated)
+}
@@ -2,8 +2,14 @@ package controllers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hotel/lockout"
+	"hotel/mailer"
 	"hotel/types"
+	"log"
 	"os"
 	"time"
 
@@ -16,9 +22,9 @@ import (
 const (
 	bcryptCost = 12
 
-	minUserFirstNameLen = 2
-	minUserLastNameLen  = 2
-	minUserPasswordLen  = 7
+	userTokenBytesLen         = 32
+	emailVerificationTokenTTL = 24 * time.Hour
+	passwordResetTokenTTL     = time.Hour
 )
 
 type UserController struct {
@@ -31,21 +37,55 @@ func (self *UserController) CheckPasswordValid(user *types.User, password string
 
 func (self *UserController) Login(
 	ctx context.Context, params *types.LoginUserParams,
-) (string, *types.User, error) {
-	query, err := bson.Marshal(bson.M{"email": params.Email})
+) (*types.LoginResult, error) {
+	err := self.checkLoginLocked(ctx, params)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	result, err := self.Store.DB.Users.GetOne(ctx, query, &types.User{})
+
+	user, err := self.GetByEmail(ctx, params.Email)
 	if err != nil {
-		return "", nil, err
+		return nil, err
 	}
-	user := CastPtrInterface[types.User](result)
 
 	if user == nil || !self.CheckPasswordValid(user, params.Password) {
-		return "", nil, fmt.Errorf("Invalid credentials")
+		err = self.registerLoginFailure(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if !user.IsVerified {
+		return nil, ErrEmailNotVerified
+	}
+
+	// Admins must use second factor, even if they haven't enrolled yet
+	if user.TOTPEnabled || user.IsAdmin {
+		challengeToken, err := self.issueToken(
+			ctx, user.ID, types.OTPChallengeTokenKind, otpChallengeTokenTTL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &types.LoginResult{
+			OTPRequired:       true,
+			EnrolmentRequired: !user.TOTPEnabled,
+			ChallengeToken:    challengeToken,
+		}, nil
 	}
 
+	err = self.Store.LoginAttempts.Reset(ctx, lockout.AccountKey(user.Email))
+	if err != nil {
+		return nil, err
+	}
+	token, err := self.IssueAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+	return &types.LoginResult{Token: token, User: user}, nil
+}
+
+func (self *UserController) IssueAccessToken(user *types.User) (string, error) {
 	claims := jwt.MapClaims{
 		"id":    user.ID,
 		"email": user.Email,
@@ -56,10 +96,10 @@ func (self *UserController) Login(
 
 	tokenStr, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
 	if err != nil {
-		return "", nil, fmt.Errorf("Failed to sign token: %s", err.Error())
+		return "", fmt.Errorf("Failed to sign token: %s", err.Error())
 	}
 
-	return tokenStr, user, nil
+	return tokenStr, nil
 }
 
 func (self *UserController) GetByID(
@@ -72,6 +112,20 @@ func (self *UserController) GetByID(
 	return CastPtrInterface[types.User](result), nil
 }
 
+func (self *UserController) GetByEmail(
+	ctx context.Context, email string,
+) (*types.User, error) {
+	query, err := bson.Marshal(bson.M{"email": email})
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.Users.GetOne(ctx, query, &types.User{})
+	if err != nil {
+		return nil, err
+	}
+	return CastPtrInterface[types.User](result), nil
+}
+
 func (self *UserController) Get(ctx context.Context) ([]*types.User, error) {
 	result, err := self.Store.DB.Users.Get(ctx, bson.M{}, []*types.User{})
 	if err != nil {
@@ -80,65 +134,355 @@ func (self *UserController) Get(ctx context.Context) ([]*types.User, error) {
 	return CastInterface[[]*types.User](result), nil
 }
 
-func (self *UserController) Validate(user *types.User, userBefore *types.User) map[string]string {
+// Field formats are checked by params validation,
+// here go only checks which need database
+func (self *UserController) Validate(
+	ctx context.Context, user *types.User, userBefore *types.User,
+) (map[string]string, error) {
 	errors := map[string]string{}
-	if len(user.FirstName) < minUserFirstNameLen {
-		errors["firstName"] = fmt.Sprintf(
-			"First name length should be at least %d characters", minUserFirstNameLen,
-		)
-	}
-
-	if len(user.LastName) < minUserLastNameLen {
-		errors["lastName"] = fmt.Sprintf(
-			"Last name length should be at least %d characters", minUserLastNameLen,
-		)
+	sameEmailUser, err := self.GetByEmail(ctx, user.Email)
+	if err != nil {
+		return errors, err
 	}
-
-	if !IsEmailValid(user.Email) {
+	if sameEmailUser != nil && (userBefore == nil || sameEmailUser.ID != userBefore.ID) {
 		errors["email"] = fmt.Sprintf(
-			"Email \"%s\" is invalid", user.Email,
+			"Email \"%s\" is already taken", user.Email,
 		)
 	}
+	return errors, nil
+}
 
-	if userBefore == nil {
-		if len(user.Password) < minUserPasswordLen {
-			errors["password"] = fmt.Sprintf(
-				"Password length should be at least %d characters", minUserPasswordLen,
-			)
-		}
+func (self *UserController) EncryptPassword(password string) (string, error) {
+	encryptedPassword, err := bcrypt.GenerateFromPassword(
+		[]byte(password), bcryptCost,
+	)
+	if err != nil {
+		return "", err
 	}
-	return errors
+	return string(encryptedPassword), nil
 }
 
 func (self *UserController) Evaluate(user *types.User, userBefore *types.User) error {
 	if userBefore == nil {
-		encryptedPassword, err := bcrypt.GenerateFromPassword(
-			[]byte(user.Password), bcryptCost,
-		)
+		encryptedPassword, err := self.EncryptPassword(user.Password)
 		if err != nil {
 			return err
 		}
-		user.EncryptedPassword = string(encryptedPassword)
+		user.EncryptedPassword = encryptedPassword
+	} else {
+		user.IsVerified = userBefore.IsVerified
+		user.IsAdmin = userBefore.IsAdmin
 	}
 	return nil
 }
 
-func (self *UserController) Create(
+func (self *UserController) create(
 	ctx context.Context, user *types.User,
 ) (*types.User, error) {
-	errs := self.Validate(user, nil)
+	errs, err := self.Validate(ctx, user, nil)
+	if err != nil {
+		return nil, err
+	}
 	if len(errs) != 0 {
 		return nil, ValidationError{Fields: errs}
 	}
-	err := self.Evaluate(user, nil)
+	err = self.Evaluate(user, nil)
 	if err != nil {
 		return nil, err
 	}
-	id, err := self.Store.DB.Users.Create(ctx, user)
+	user.Version = 1
+	var created *types.User
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.Users.Create(ctx, user)
+		if err != nil {
+			return err
+		}
+		created, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "User", id, nil, created)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return self.GetByID(ctx, id)
+	return created, nil
+}
+
+// Users created by another (logged in) user are trusted,
+// so email verification is skipped
+func (self *UserController) Create(
+	ctx context.Context, user *types.User,
+) (*types.User, error) {
+	user.IsVerified = true
+	return self.create(ctx, user)
+}
+
+// Public sign up. User can't log in until email is verified.
+// Failed email doesn't fail sign up, it can be sent again
+func (self *UserController) Register(
+	ctx context.Context, user *types.User,
+) (*types.User, error) {
+	user.IsVerified = false
+	var created *types.User
+	var token string
+	err := self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = self.create(ctx, user)
+		if err != nil {
+			return err
+		}
+		token, err = self.issueToken(
+			ctx, created.ID, types.EmailVerificationTokenKind, emailVerificationTokenTTL,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = self.sendVerificationEmail(ctx, created, token)
+	if err != nil {
+		log.Printf("Failed to send verification email to user %s: %s\n", created.ID.Hex(), err.Error())
+	}
+	return created, nil
+}
+
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (self *UserController) issueToken(
+	ctx context.Context, userID primitive.ObjectID,
+	kind types.UserTokenKind, ttl time.Duration,
+) (string, error) {
+	tokenBytes := make([]byte, userTokenBytesLen)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	_, err = self.Store.DB.UserTokens.Create(ctx, &types.UserToken{
+		UserID:    userID,
+		Kind:      kind,
+		TokenHash: hashUserToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func validUserTokenQuery(
+	token string, kind types.UserTokenKind, now time.Time,
+) bson.M {
+	return bson.M{
+		"tokenHash": hashUserToken(token),
+		"kind":      kind,
+		"usedAt":    nil,
+		"expiresAt": bson.M{"$gt": now},
+	}
+}
+
+var errInvalidUserToken = NewFieldError("token", "Token is invalid or expired")
+
+// Returns token without using it up
+func (self *UserController) findToken(
+	ctx context.Context, token string, kind types.UserTokenKind,
+) (*types.UserToken, error) {
+	result, err := self.Store.DB.UserTokens.GetOne(
+		ctx, validUserTokenQuery(token, kind, time.Now()), &types.UserToken{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	userToken := CastPtrInterface[types.UserToken](result)
+	if userToken == nil {
+		return nil, errInvalidUserToken
+	}
+	return userToken, nil
+}
+
+// Marks token as used and returns it. Token can be consumed only once,
+// so concurrent requests with the same token can't both succeed
+func (self *UserController) consumeToken(
+	ctx context.Context, token string, kind types.UserTokenKind,
+) (*types.UserToken, error) {
+	now := time.Now()
+	result, err := self.Store.DB.UserTokens.GetOneAndUpdate(
+		ctx,
+		validUserTokenQuery(token, kind, now),
+		bson.M{"$set": bson.M{"usedAt": now}},
+		&types.UserToken{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	userToken := CastPtrInterface[types.UserToken](result)
+	if userToken == nil {
+		return nil, errInvalidUserToken
+	}
+	return userToken, nil
+}
+
+func (self *UserController) sendMail(ctx context.Context, msg *mailer.Message) error {
+	err := self.Store.Mailer.Send(ctx, msg)
+	if err != nil {
+		return UpstreamUnavailableError{Service: "mailer", Err: err}
+	}
+	return nil
+}
+
+func (self *UserController) sendVerificationEmail(
+	ctx context.Context, user *types.User, token string,
+) error {
+	return self.sendMail(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hello, %s!\n\nFollow the link to confirm your email:\n%s/verify-email?token=%s\n",
+			user.FirstName, os.Getenv("APP_PUBLIC_URL"), token,
+		),
+	})
+}
+
+// Sends new verification token to unverified user. Doesn't report
+// whether user exists, so emails can't be enumerated
+func (self *UserController) ResendVerification(
+	ctx context.Context, params *types.ResendVerificationParams,
+) error {
+	user, err := self.GetByEmail(ctx, params.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsVerified {
+		return nil
+	}
+	token, err := self.issueToken(
+		ctx, user.ID, types.EmailVerificationTokenKind, emailVerificationTokenTTL,
+	)
+	if err != nil {
+		return err
+	}
+	return self.sendVerificationEmail(ctx, user, token)
+}
+
+func (self *UserController) VerifyEmail(
+	ctx context.Context, params *types.VerifyEmailParams,
+) (*types.User, error) {
+	userToken, err := self.consumeToken(
+		ctx, params.Token, types.EmailVerificationTokenKind,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Users.UpdateByID(
+			ctx, userToken.UserID, 0, bson.M{"isVerified": true},
+		)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "User", userToken.UserID,
+			bson.M{"isVerified": false}, bson.M{"isVerified": true},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return self.GetByID(ctx, userToken.UserID)
+}
+
+// Doesn't report whether user exists, so emails can't be enumerated
+func (self *UserController) ForgotPassword(
+	ctx context.Context, params *types.ForgotPasswordParams,
+) error {
+	user, err := self.GetByEmail(ctx, params.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	token, err := self.issueToken(
+		ctx, user.ID, types.PasswordResetTokenKind, passwordResetTokenTTL,
+	)
+	if err != nil {
+		return err
+	}
+	return self.sendMail(ctx, &mailer.Message{
+		To:      user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello, %s!\n\nFollow the link to reset your password:\n%s/reset-password?token=%s\n\n"+
+				"If you didn't request password reset, ignore this email.\n",
+			user.FirstName, os.Getenv("APP_PUBLIC_URL"), token,
+		),
+	})
+}
+
+func (self *UserController) setPassword(
+	ctx context.Context, userID primitive.ObjectID, password string,
+) error {
+	encryptedPassword, err := self.EncryptPassword(password)
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Users.UpdateByID(
+			ctx, userID, 0, bson.M{"encryptedPassword": encryptedPassword},
+		)
+		if err != nil {
+			return err
+		}
+		// Previous hash isn't known here, but it's redacted anyway
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "User", userID,
+			nil, bson.M{"encryptedPassword": encryptedPassword},
+		)
+	})
+}
+
+func (self *UserController) ResetPassword(
+	ctx context.Context, params *types.ResetPasswordParams,
+) error {
+	userToken, err := self.consumeToken(
+		ctx, params.Token, types.PasswordResetTokenKind,
+	)
+	if err != nil {
+		return err
+	}
+	err = self.setPassword(ctx, userToken.UserID, params.Password)
+	if err != nil {
+		return err
+	}
+	// Other reset links sent to the same user are no longer needed
+	_, err = self.Store.DB.UserTokens.Update(
+		ctx,
+		bson.M{
+			"userID": userToken.UserID,
+			"kind":   types.PasswordResetTokenKind,
+			"usedAt": nil,
+		},
+		bson.M{"$set": bson.M{"usedAt": time.Now()}},
+	)
+	return err
+}
+
+func (self *UserController) ChangePassword(
+	ctx context.Context, params *types.ChangePasswordParams,
+) error {
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return err
+	}
+	if !self.CheckPasswordValid(user, params.OldPassword) {
+		return NewFieldError("oldPassword", "Password is incorrect")
+	}
+	return self.setPassword(ctx, user.ID, params.NewPassword)
 }
 
 func (self *UserController) UpdateByID(
@@ -148,8 +492,18 @@ func (self *UserController) UpdateByID(
 	if err != nil {
 		return nil, err
 	}
+	if userBefore == nil {
+		return nil, NotFoundError{Entity: "User"}
+	}
+	err = CheckVersion("User", user.Version, userBefore.Version)
+	if err != nil {
+		return nil, err
+	}
 
-	errs := self.Validate(user, userBefore)
+	errs, err := self.Validate(ctx, user, userBefore)
+	if err != nil {
+		return nil, err
+	}
 	if len(errs) != 0 {
 		return nil, ValidationError{Fields: errs}
 	}
@@ -158,15 +512,47 @@ func (self *UserController) UpdateByID(
 		return nil, err
 	}
 
-	err = self.Store.DB.Users.UpdateByID(ctx, id, user)
+	var updated *types.User
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Users, "User", id, userBefore.Version, userBefore, user,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "User", id, userBefore, updated,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return self.GetByID(ctx, id)
+	return updated, nil
 }
 
 func (self *UserController) DeleteByID(
-	ctx context.Context, id primitive.ObjectID,
+	ctx context.Context, id primitive.ObjectID, version int64,
 ) error {
-	return self.Store.DB.Users.DeleteByID(ctx, id)
+	user, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return NotFoundError{Entity: "User"}
+	}
+	err = CheckVersion("User", version, user.Version)
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := DeleteVersionedByID(ctx, self.Store.DB.Users, "User", id, user.Version)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "User", id, user, nil)
+	})
 }
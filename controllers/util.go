@@ -2,28 +2,28 @@ package controllers
 
 import (
 	"context"
-	"fmt"
 	"hotel/db"
 	"hotel/types"
-	"regexp"
+	"reflect"
 
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-type ValidationError struct {
-	Fields map[string]string
-}
+// Set by request id middleware
+const RequestIDContextKey = "requestid"
 
-func (self ValidationError) Error() string {
-	errStr := "ValidationError:"
-	for k, v := range self.Fields {
-		errStr += fmt.Sprintf("\n%s: %s", k, v)
-	}
-	return errStr
+func GetRequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(RequestIDContextKey).(string)
+	return requestID
 }
 
 func GetUserIDFromContext(dbStore *db.DB, ctx context.Context) (primitive.ObjectID, error) {
+	apiKey := GetAPIKeyFromContext(ctx)
+	if apiKey != nil {
+		return apiKey.UserID, nil
+	}
 	ctxVal := ctx.Value("user")
 	if ctxVal == nil {
 		return primitive.ObjectID{}, nil
@@ -49,13 +49,120 @@ func GetUserFromContext(dbStore *db.DB, ctx context.Context) (*types.User, error
 	if err != nil {
 		return nil, err
 	}
-	return user.(*types.User), nil
+	return CastPtrInterface[types.User](user), nil
+}
+
+// Entities owned by users are visible to their owners and admins only.
+// Others get not found, so existence of the entity isn't disclosed
+func RequireOwnerOrAdmin(
+	dbStore *db.DB, ctx context.Context, ownerID primitive.ObjectID, entity string,
+) error {
+	user, err := GetUserFromContext(dbStore, ctx)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrNotAuthenticated
+	}
+	if user.ID != ownerID && (!user.IsAdmin || GetAPIKeyFromContext(ctx) != nil) {
+		return NotFoundError{Entity: entity}
+	}
+	return nil
 }
 
-func IsEmailValid(e string) bool {
-	// Sourced from https://stackoverflow.com/a/67686133
-	emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-	return emailRegex.MatchString(e)
+// API keys never grant admin rights, even if their owner is admin
+func RequireAdmin(dbStore *db.DB, ctx context.Context) (*types.User, error) {
+	if GetAPIKeyFromContext(ctx) != nil {
+		return nil, ErrAdminOnly
+	}
+	user, err := GetUserFromContext(dbStore, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsAdmin {
+		return nil, ErrAdminOnly
+	}
+	return user, nil
+}
+
+func toDocument(obj interface{}) (bson.M, error) {
+	data, err := bson.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	err = bson.Unmarshal(data, &doc)
+	return doc, err
+}
+
+// Returns fields of after which differ from before. Fields missing
+// in after (e.g. omitempty ones) are left untouched
+func ChangedFields(before interface{}, after interface{}) (bson.M, error) {
+	beforeDoc, err := toDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	afterDoc, err := toDocument(after)
+	if err != nil {
+		return nil, err
+	}
+	changed := bson.M{}
+	for key, value := range afterDoc {
+		if key == "_id" || key == "version" {
+			continue
+		}
+		if !reflect.DeepEqual(beforeDoc[key], value) {
+			changed[key] = value
+		}
+	}
+	return changed, nil
+}
+
+// Compares version client's change is based on with the stored one.
+// Zero expected version means client doesn't care (internal callers)
+func CheckVersion(entity string, expected int64, actual int64) error {
+	if expected != 0 && expected != actual {
+		return PreconditionFailedError{Entity: entity}
+	}
+	return nil
+}
+
+// Writes only changed fields, and only if entity is still at the version
+// before was read at. Otherwise concurrent write has won
+func UpdateChangedByID(
+	ctx context.Context, store *db.MongoStore, entity string,
+	id primitive.ObjectID, version int64, before interface{}, after interface{},
+) error {
+	changed, err := ChangedFields(before, after)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	updated, err := store.UpdateByID(ctx, id, version, changed)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return PreconditionFailedError{Entity: entity}
+	}
+	return nil
+}
+
+// Deletes entity only if it's still at given version
+func DeleteVersionedByID(
+	ctx context.Context, store *db.MongoStore, entity string,
+	id primitive.ObjectID, version int64,
+) error {
+	deleted, err := store.DeleteByID(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return PreconditionFailedError{Entity: entity}
+	}
+	return nil
 }
 
 func CastPtrInterface[T any](i interface{}) *T {
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hotel/db"
+	"hotel/validate"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Collections referenced by `exists` validation rule
+func existsStores(dbStore *db.DB) map[string]*db.MongoStore {
+	return map[string]*db.MongoStore{
+		"User":     dbStore.Users,
+		"Hotel":    dbStore.Hotels,
+		"Room":     dbStore.Rooms,
+		"RatePlan": dbStore.RatePlans,
+	}
+}
+
+func newValidator(dbStore *db.DB) *validate.Validator {
+	stores := existsStores(dbStore)
+	return &validate.Validator{
+		Exists: func(
+			ctx context.Context, entity string, id primitive.ObjectID,
+		) (bool, error) {
+			store, ok := stores[entity]
+			if !ok {
+				return false, fmt.Errorf("Unknown entity %s in exists rule", entity)
+			}
+			count, err := store.GetCount(ctx, bson.M{"_id": id})
+			if err != nil {
+				return false, err
+			}
+			return count != 0, nil
+		},
+	}
+}
+
+// Checks request params against their `validate` struct tags
+func (self *Store) ValidateParams(ctx context.Context, params interface{}) error {
+	errs, err := self.Validator.Struct(ctx, params)
+	if err != nil {
+		return err
+	}
+	if len(errs) != 0 {
+		return ValidationError{Fields: errs}
+	}
+	return nil
+}
@@ -0,0 +1,454 @@
+package controllers
+
+import (
+	"context"
+	"hotel/notifications"
+	"hotel/types"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultWaitlistOfferMinutes = 60
+
+// Length of holds offered to waitlisted guests, WAITLIST_OFFER_MINUTES
+func WaitlistOfferTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("WAITLIST_OFFER_MINUTES"))
+	if err != nil || minutes <= 0 {
+		minutes = defaultWaitlistOfferMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+type WaitlistController struct {
+	Store *Store
+}
+
+func (self *WaitlistController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.WaitlistEntry, error) {
+	result, err := self.Store.DB.Waitlist.GetOneByID(ctx, id, &types.WaitlistEntry{})
+	if err != nil {
+		return nil, err
+	}
+	entry := CastPtrInterface[types.WaitlistEntry](result)
+	if entry == nil {
+		return nil, NotFoundError{Entity: "Waitlist entry"}
+	}
+	err = RequireOwnerOrAdmin(self.Store.DB, ctx, entry.UserID, "Waitlist entry")
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+type WaitlistGetQueryParams struct {
+	HotelID primitive.ObjectID   `json:"hotelID"`
+	Status  types.WaitlistStatus `json:"status" validate:"enum"`
+}
+
+// Users see their own entries, admins see everyone's. Entries are in
+// order they're offered rooms
+func (self *WaitlistController) Get(
+	ctx context.Context, params *WaitlistGetQueryParams,
+) ([]*types.WaitlistEntry, error) {
+	if params == nil {
+		params = &WaitlistGetQueryParams{}
+	}
+	user, err := GetUserFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotAuthenticated
+	}
+	query := bson.M{}
+	if !user.IsAdmin || GetAPIKeyFromContext(ctx) != nil {
+		query["userID"] = user.ID
+	}
+	if !params.HotelID.IsZero() {
+		query["hotelID"] = params.HotelID
+	}
+	if len(params.Status) != 0 {
+		query["status"] = params.Status
+	}
+	result, err := self.Store.DB.Waitlist.GetSorted(
+		ctx, query, bson.D{{Key: "createdAt", Value: 1}}, 0, []*types.WaitlistEntry{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.WaitlistEntry](result), nil
+}
+
+// Room of entry's type free for its stay and allowed by stay
+// restrictions, nil if there's none
+func (self *WaitlistController) findFreeRoom(
+	ctx context.Context, entry *types.WaitlistEntry,
+) (*types.Room, error) {
+	result, err := self.Store.DB.Rooms.Get(
+		ctx, notDeleted(bson.M{"hotelID": entry.HotelID, "type": entry.RoomType}), []*types.Room{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, room := range CastInterface[[]*types.Room](result) {
+		isRoomFree, err := self.Store.CT.Bookings.IsRoomFreeForDate(
+			ctx, primitive.ObjectID{}, primitive.ObjectID{}, room.ID, entry.DateFrom, entry.DateTo,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if !isRoomFree {
+			continue
+		}
+		restrictionErrors, err := self.Store.CT.Restrictions.stayErrors(
+			ctx, room, entry.DateFrom, entry.DateTo,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(restrictionErrors) == 0 {
+			return room, nil
+		}
+	}
+	return nil, nil
+}
+
+// Offer is booked with entry's rate plan, so it should be one the guest
+// can book the stay with. Once hotel has rate plans, one of them is required
+func (self *WaitlistController) checkRatePlan(
+	ctx context.Context, entry *types.WaitlistEntry, userID primitive.ObjectID,
+) error {
+	if entry.RatePlanID.IsZero() {
+		count, err := self.Store.DB.RatePlans.GetCount(ctx, bson.M{"hotelID": entry.HotelID})
+		if err != nil {
+			return err
+		}
+		if count != 0 {
+			return NewFieldError("ratePlanID", "Choose one of hotel's rate plans")
+		}
+		return nil
+	}
+	ratePlan, err := self.Store.CT.RatePlans.getByID(ctx, entry.RatePlanID)
+	if err != nil {
+		return err
+	}
+	if ratePlan == nil || ratePlan.HotelID != entry.HotelID {
+		return NewFieldError("ratePlanID", "Rate plan isn't sold by this hotel")
+	}
+	mismatch := RatePlanMismatch(
+		ratePlan, entry.RoomType, entry.DateFrom, entry.DateTo, civil.DateOf(time.Now()), true,
+	)
+	if len(mismatch) == 0 {
+		mismatch, err = self.Store.CT.RatePlans.memberMismatch(ctx, ratePlan, userID)
+		if err != nil {
+			return err
+		}
+	}
+	if len(mismatch) != 0 {
+		return NewFieldError("ratePlanID", mismatch)
+	}
+	return nil
+}
+
+// Puts guest on waitlist. Guests can't wait for rooms free already
+func (self *WaitlistController) Create(
+	ctx context.Context, entry *types.WaitlistEntry,
+) (*types.WaitlistEntry, error) {
+	userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = RequireHotelAccess(ctx, entry.HotelID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.DateFrom.Before(civil.DateOf(time.Now())) {
+		return nil, NewFieldError("dateFrom", "Date from can't be in the past")
+	}
+	if entry.Guests < 0 {
+		return nil, NewFieldError("guests", "Number of guests can't be negative")
+	}
+	err = self.checkRatePlan(ctx, entry, userID)
+	if err != nil {
+		return nil, err
+	}
+	count, err := self.Store.DB.Waitlist.GetCount(ctx, bson.M{
+		"userID":   userID,
+		"hotelID":  entry.HotelID,
+		"roomType": entry.RoomType,
+		"dateFrom": entry.DateFrom,
+		"dateTo":   entry.DateTo,
+		"status": bson.M{"$in": []types.WaitlistStatus{
+			types.WaitingWaitlistStatus, types.OfferedWaitlistStatus,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count != 0 {
+		return nil, NewFieldError("dateFrom", "You're already waiting for these dates")
+	}
+	room, err := self.findFreeRoom(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	if room != nil {
+		return nil, NewFieldError("roomType", "Rooms of this type are free for these dates, book one instead")
+	}
+
+	entry.UserID = userID
+	entry.Status = types.WaitingWaitlistStatus
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.Waitlist.Create(ctx, entry)
+		if err != nil {
+			return err
+		}
+		entry.ID = id
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "WaitlistEntry", id, nil, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Takes guest off waitlist. Room offered to them passes to the next guest
+func (self *WaitlistController) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
+	entry, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		_, err := self.Store.DB.Waitlist.Delete(ctx, bson.M{"_id": id})
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "WaitlistEntry", id, entry, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if entry.Status != types.OfferedWaitlistStatus {
+		return nil
+	}
+	released, err := self.Store.CT.Holds.finish(ctx, entry.HoldID, types.ReleasedHoldStatus)
+	if err != nil || released == nil {
+		return err
+	}
+	_, err = self.offerFreed(ctx, entry.HotelID, entry.RoomType, entry.DateFrom, entry.DateTo, time.Now())
+	return err
+}
+
+// Claims waiting entry and holds free room for its guest, under room's
+// lock like other holds. Returns false if there's no free room or entry
+// isn't waiting anymore
+func (self *WaitlistController) offer(
+	ctx context.Context, entry *types.WaitlistEntry, now time.Time,
+) (bool, error) {
+	now = now.UTC().Truncate(time.Millisecond)
+	hold := &types.Hold{
+		ID:              primitive.NewObjectID(),
+		UserID:          entry.UserID,
+		DateFrom:        entry.DateFrom,
+		DateTo:          entry.DateTo,
+		Guests:          entry.Guests,
+		RatePlanID:      entry.RatePlanID,
+		Status:          types.ActiveHoldStatus,
+		ExpiresAt:       now.Add(WaitlistOfferTTL()),
+		WaitlistEntryID: entry.ID,
+		CreatedAt:       now,
+	}
+	var room *types.Room
+	var offered *types.WaitlistEntry
+	err := self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		offered = nil
+		var err error
+		room, err = self.findFreeRoom(ctx, entry)
+		if err != nil || room == nil {
+			return err
+		}
+		err = self.Store.CT.Bookings.reserveRoom(
+			ctx, primitive.ObjectID{}, primitive.ObjectID{}, room.ID, entry.DateFrom, entry.DateTo,
+		)
+		if err != nil {
+			return err
+		}
+		hold.RoomID = room.ID
+		result, err := self.Store.DB.Waitlist.GetOneAndUpdate(
+			ctx,
+			bson.M{"_id": entry.ID, "status": types.WaitingWaitlistStatus},
+			bson.M{"$set": bson.M{
+				"status":         types.OfferedWaitlistStatus,
+				"holdID":         hold.ID,
+				"offerExpiresAt": hold.ExpiresAt,
+			}},
+			&types.WaitlistEntry{},
+		)
+		if err != nil {
+			return err
+		}
+		offered = CastPtrInterface[types.WaitlistEntry](result)
+		if offered == nil {
+			return nil
+		}
+		_, err = self.Store.DB.Holds.Create(ctx, hold)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Hold", hold.ID, nil, hold)
+	})
+	if err != nil || offered == nil {
+		return false, err
+	}
+	self.Store.Notifier.Go(func() {
+		err := self.notifyOffer(context.Background(), offered, room, hold)
+		if err != nil {
+			log.Printf("Failed to notify about waitlist offer %s: %s\n", entry.ID.Hex(), err.Error())
+		}
+	})
+	return true, nil
+}
+
+// Offers rooms freed for dates to guests waiting for overlapping stays,
+// in order they joined. Stays overlap by the same inclusive rule as in
+// IsRoomFreeForDate. Returns number of offers made
+func (self *WaitlistController) offerFreed(
+	ctx context.Context, hotelID primitive.ObjectID, roomType types.RoomType,
+	dateFrom civil.Date, dateTo civil.Date, now time.Time,
+) (int64, error) {
+	result, err := self.Store.DB.Waitlist.GetSorted(
+		ctx,
+		bson.M{
+			"hotelID":  hotelID,
+			"roomType": roomType,
+			"status":   types.WaitingWaitlistStatus,
+			"dateFrom": bson.M{"$lte": dateTo, "$gte": civil.DateOf(now)},
+			"dateTo":   bson.M{"$gte": dateFrom},
+		},
+		bson.D{{Key: "createdAt", Value: 1}},
+		0,
+		[]*types.WaitlistEntry{},
+	)
+	if err != nil {
+		return 0, err
+	}
+	offers := int64(0)
+	for _, entry := range CastInterface[[]*types.WaitlistEntry](result) {
+		offered, err := self.offer(ctx, entry, now)
+		if err != nil {
+			return offers, err
+		}
+		if offered {
+			offers++
+		}
+	}
+	return offers, nil
+}
+
+// Offers don't fail cancellations which trigger them
+func (self *WaitlistController) offerFreedAndLog(ctx context.Context, booking *types.BookingUnfolded) {
+	if booking.Room == nil {
+		return
+	}
+	_, err := self.offerFreed(
+		ctx, booking.Room.HotelID, booking.Room.Type, booking.DateFrom, booking.DateTo, time.Now(),
+	)
+	if err != nil {
+		log.Printf("Failed to offer room of booking %s: %s\n", booking.ID.Hex(), err.Error())
+	}
+}
+
+// Marks offers past their expiry as expired and passes rooms to the next
+// guests. Returns number of expired offers
+func (self *WaitlistController) ExpireOffers(ctx context.Context, now time.Time) (int64, error) {
+	expired := int64(0)
+	for {
+		result, err := self.Store.DB.Waitlist.GetOneAndUpdate(
+			ctx,
+			bson.M{"status": types.OfferedWaitlistStatus, "offerExpiresAt": bson.M{"$lte": now}},
+			bson.M{"$set": bson.M{"status": types.ExpiredWaitlistStatus}},
+			&types.WaitlistEntry{},
+		)
+		if err != nil {
+			return expired, err
+		}
+		entry := CastPtrInterface[types.WaitlistEntry](result)
+		if entry == nil {
+			return expired, nil
+		}
+		expired++
+		_, err = self.offerFreed(ctx, entry.HotelID, entry.RoomType, entry.DateFrom, entry.DateTo, now)
+		if err != nil {
+			return expired, err
+		}
+	}
+}
+
+// Records outcome of offer hold, after its conversion or release.
+// Released offers pass to the next guest
+func (self *WaitlistController) finishOffer(
+	ctx context.Context, hold *types.Hold, bookingID primitive.ObjectID,
+) error {
+	if hold.WaitlistEntryID.IsZero() {
+		return nil
+	}
+	set := bson.M{"status": types.BookedWaitlistStatus, "bookingID": bookingID}
+	if bookingID.IsZero() {
+		set = bson.M{"status": types.DeclinedWaitlistStatus}
+	}
+	result, err := self.Store.DB.Waitlist.GetOneAndUpdate(
+		ctx,
+		bson.M{"_id": hold.WaitlistEntryID, "status": types.OfferedWaitlistStatus},
+		bson.M{"$set": set},
+		&types.WaitlistEntry{},
+	)
+	if err != nil {
+		return err
+	}
+	entry := CastPtrInterface[types.WaitlistEntry](result)
+	if entry == nil || !bookingID.IsZero() {
+		return nil
+	}
+	_, err = self.offerFreed(ctx, entry.HotelID, entry.RoomType, entry.DateFrom, entry.DateTo, time.Now())
+	return err
+}
+
+func (self *WaitlistController) notifyOffer(
+	ctx context.Context, entry *types.WaitlistEntry, room *types.Room, hold *types.Hold,
+) error {
+	result, err := self.Store.DB.Users.GetOneByID(ctx, entry.UserID, &types.User{})
+	if err != nil {
+		return err
+	}
+	user := CastPtrInterface[types.User](result)
+	if user == nil {
+		return nil
+	}
+	data := &notifications.WaitlistOfferData{
+		GuestName: user.FirstName,
+		RoomType:  room.Type,
+		HoldID:    hold.ID.Hex(),
+		DateFrom:  entry.DateFrom,
+		DateTo:    entry.DateTo,
+		Nights:    entry.DateTo.DaysSince(entry.DateFrom),
+		ExpiresAt: hold.ExpiresAt,
+	}
+	result, err = self.Store.DB.Hotels.GetOneByID(ctx, entry.HotelID, &types.Hotel{})
+	if err != nil {
+		return err
+	}
+	if hotel := CastPtrInterface[types.Hotel](result); hotel != nil {
+		data.HotelName = hotel.Name
+	}
+	return self.Store.Notifier.Notify(
+		ctx, types.WaitlistOfferNotificationKind, user, primitive.ObjectID{}, data,
+	)
+}
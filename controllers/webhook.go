@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	webhookSecretPrefix    = "whsec"
+	webhookSecretBytesLen  = 32
+	webhookDeliveriesLimit = 100
+)
+
+type WebhookController struct {
+	Store *Store
+}
+
+// Webhooks receive bookings of every guest of hotel, so only admins
+// manage them
+func (self *WebhookController) GetByID(
+	ctx context.Context, id primitive.ObjectID,
+) (*types.Webhook, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.Webhooks.GetOneByID(ctx, id, &types.Webhook{})
+	if err != nil {
+		return nil, err
+	}
+	webhook := CastPtrInterface[types.Webhook](result)
+	if webhook == nil {
+		return nil, NotFoundError{Entity: "Webhook"}
+	}
+	return webhook, nil
+}
+
+type WebhookGetQueryParams struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+}
+
+func (self *WebhookController) Get(
+	ctx context.Context, params *WebhookGetQueryParams,
+) ([]*types.Webhook, error) {
+	if params == nil {
+		params = &WebhookGetQueryParams{}
+	}
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{}
+	if !params.HotelID.IsZero() {
+		query["hotelID"] = params.HotelID
+	}
+	result, err := self.Store.DB.Webhooks.Get(ctx, query, []*types.Webhook{})
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.Webhook](result), nil
+}
+
+func generateWebhookSecret() (string, error) {
+	secretBytes := make([]byte, webhookSecretBytesLen)
+	_, err := rand.Read(secretBytes)
+	if err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + "_" + hex.EncodeToString(secretBytes), nil
+}
+
+func (self *WebhookController) Create(
+	ctx context.Context, webhook *types.Webhook,
+) (*types.WebhookWithSecret, error) {
+	_, err := RequireAdmin(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := GetUserIDFromContext(self.Store.DB, ctx)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret, err = generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook.CreatedBy = userID
+	webhook.CreatedAt = time.Now()
+
+	webhook.Version = 1
+	var created *types.Webhook
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		id, err := self.Store.DB.Webhooks.Create(ctx, webhook)
+		if err != nil {
+			return err
+		}
+		created, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.CreateAuditAction, "Webhook", id, nil, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.WebhookWithSecret{Webhook: created, Secret: created.Secret}, nil
+}
+
+// Hotel and secret stay as they are, webhook for another hotel
+// should be created anew
+func (self *WebhookController) UpdateByID(
+	ctx context.Context, id primitive.ObjectID, webhook *types.Webhook,
+) (*types.Webhook, error) {
+	webhookBefore, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = CheckVersion("Webhook", webhook.Version, webhookBefore.Version)
+	if err != nil {
+		return nil, err
+	}
+	webhook.ID = id
+	webhook.HotelID = webhookBefore.HotelID
+	webhook.Secret = webhookBefore.Secret
+	webhook.CreatedBy = webhookBefore.CreatedBy
+	webhook.CreatedAt = webhookBefore.CreatedAt
+
+	var updated *types.Webhook
+	err = self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := UpdateChangedByID(
+			ctx, self.Store.DB.Webhooks, "Webhook", id, webhookBefore.Version,
+			webhookBefore, webhook,
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = self.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(
+			ctx, types.UpdateAuditAction, "Webhook", id, webhookBefore, updated,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Pending deliveries of deleted webhook become dead. Delivery log is kept
+func (self *WebhookController) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) error {
+	webhook, err := self.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = CheckVersion("Webhook", version, webhook.Version)
+	if err != nil {
+		return err
+	}
+	return self.Store.DB.WithTransaction(ctx, func(ctx context.Context) error {
+		err := DeleteVersionedByID(ctx, self.Store.DB.Webhooks, "Webhook", id, webhook.Version)
+		if err != nil {
+			return err
+		}
+		return self.Store.CT.Audit.Record(ctx, types.DeleteAuditAction, "Webhook", id, webhook, nil)
+	})
+}
+
+type WebhookDeliveryGetQueryParams struct {
+	Status types.WebhookDeliveryStatus `json:"status" validate:"enum"`
+}
+
+// Delivery log of webhook, newest first
+func (self *WebhookController) GetDeliveries(
+	ctx context.Context, id primitive.ObjectID, params *WebhookDeliveryGetQueryParams,
+) ([]*types.WebhookDelivery, error) {
+	_, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{"webhookID": id}
+	if params != nil && len(params.Status) != 0 {
+		query["status"] = params.Status
+	}
+	result, err := self.Store.DB.WebhookDeliveries.GetSorted(
+		ctx, query, bson.D{{Key: "createdAt", Value: -1}}, webhookDeliveriesLimit,
+		[]*types.WebhookDelivery{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return CastInterface[[]*types.WebhookDelivery](result), nil
+}
+
+// Schedules delivery to be sent again as soon as possible, with
+// fresh retry budget. Works for delivered ones too, e.g. if partner
+// lost the data
+func (self *WebhookController) Redeliver(
+	ctx context.Context, id primitive.ObjectID, deliveryID primitive.ObjectID,
+) (*types.WebhookDelivery, error) {
+	_, err := self.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Store.DB.WebhookDeliveries.GetOneAndUpdate(
+		ctx,
+		bson.M{"_id": deliveryID, "webhookID": id},
+		bson.M{"$set": bson.M{
+			"status":        types.PendingWebhookDeliveryStatus,
+			"failures":      0,
+			"nextAttemptAt": time.Now(),
+		}},
+		&types.WebhookDelivery{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery := CastPtrInterface[types.WebhookDelivery](result)
+	if delivery == nil {
+		return nil, NotFoundError{Entity: "Webhook delivery"}
+	}
+	return delivery, nil
+}
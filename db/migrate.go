@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"hotel/types"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Brings documents written by older versions up to date and creates
+// indexes code relies on. Every step is idempotent, so migrations run on
+// every start. Amounts stored before currencies were introduced get
+// currency, which hotels without their own use
+func (self *DB) Migrate(ctx context.Context, currency types.Currency) error {
+	steps := []func(ctx context.Context) error{
+		self.backfillVersions,
+		func(ctx context.Context) error {
+			return self.convertLegacyAmounts(ctx, currency)
+		},
+		self.verifyLegacyUsers,
+		self.createInvoiceIndexes,
+		self.createPromotionIndexes,
+	}
+	for _, step := range steps {
+		err := step(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Entities written before versioning have no version. Their ETag "0"
+// couldn't be sent back in If-Match, so they start at version 1
+func (self *DB) backfillVersions(ctx context.Context) error {
+	stores := []*MongoStore{
+		self.Users, self.Hotels, self.Rooms, self.Bookings, self.APIKeys,
+		self.Webhooks, self.Promotions, self.RatePlans, self.StayRestrictions,
+	}
+	for _, store := range stores {
+		_, err := store.Update(
+			ctx,
+			bson.M{"version": bson.M{"$in": bson.A{nil, 0}}},
+			bson.M{"$set": bson.M{"version": 1}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Users registered before email verification have no isVerified field.
+// They're trusted, so login doesn't lock them out. Users registered since
+// always have the field, so they aren't touched
+func (self *DB) verifyLegacyUsers(ctx context.Context) error {
+	_, err := self.Users.Update(
+		ctx,
+		bson.M{"isVerified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"isVerified": true}},
+	)
+	return err
+}
+
+// Prices and costs stored before currencies were introduced are plain
+// doubles in major units. They become money in minor units of currency
+func (self *DB) convertLegacyAmounts(ctx context.Context, currency types.Currency) error {
+	fields := []struct {
+		store *MongoStore
+		field string
+	}{
+		{self.Rooms, "price"},
+		{self.Bookings, "totalCost"},
+	}
+	unit := math.Pow10(currency.Exponent())
+	for _, item := range fields {
+		_, err := item.store.Coll.UpdateMany(
+			ctx,
+			bson.M{item.field: bson.M{"$type": "double"}},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{item.field: bson.M{
+				"minor": bson.M{"$toLong": bson.M{
+					"$round": bson.A{bson.M{"$multiply": bson.A{"$" + item.field, unit}}, 0},
+				}},
+				"currency": currency,
+			}}}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Booking has one invoice, until it's fully credited and invoiced again
+func (self *DB) createInvoiceIndexes(ctx context.Context) error {
+	_, err := self.OpenInvoices.Coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "bookingID", Value: 1}},
+		Options: options.Index().SetName("openInvoiceOfBooking").SetUnique(true),
+	})
+	return err
+}
+
+// Codes are unique, automatic promotions have none
+func (self *DB) createPromotionIndexes(ctx context.Context) error {
+	_, err := self.Promotions.Coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "code", Value: 1}},
+		Options: options.Index().
+			SetName("promotionCode").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"code": bson.M{"$gt": ""}}),
+	})
+	return err
+}
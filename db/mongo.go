@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoStore struct {
@@ -43,6 +45,27 @@ func (self *MongoStore) Get(ctx context.Context, query interface{}, castTo inter
 	return objs, nil
 }
 
+// Same as Get, but sorted and limited. Zero limit means no limit
+func (self *MongoStore) GetSorted(
+	ctx context.Context, query interface{}, sort interface{}, limit int64,
+	castTo interface{},
+) (interface{}, error) {
+	cursor, err := self.Coll.Find(
+		ctx, query, options.Find().SetSort(sort).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	objs := castTo
+
+	err = cursor.All(ctx, &objs)
+	if err != nil {
+		return nil, err
+	}
+
+	return objs, nil
+}
+
 func (self *MongoStore) GetCount(ctx context.Context, query interface{}) (int64, error) {
 	return self.Coll.CountDocuments(ctx, query)
 }
@@ -81,23 +104,121 @@ func (self *MongoStore) GetOneByID(
 	return obj, nil
 }
 
+// Filters by version, so only one of concurrent writers based
+// on the same version wins. Zero version skips the check
+func versionFilter(id primitive.ObjectID, version int64) bson.M {
+	filter := bson.M{"_id": id}
+	if version != 0 {
+		filter["version"] = version
+	}
+	return filter
+}
+
+// Sets fields and increments version. Returns false if document
+// with given id and version wasn't found
 func (self *MongoStore) UpdateByID(
-	ctx context.Context, id primitive.ObjectID, objs interface{},
-) error {
-	_, err := self.Coll.UpdateByID(
-		ctx, id, bson.M{"$set": objs},
+	ctx context.Context, id primitive.ObjectID, version int64, objs interface{},
+) (bool, error) {
+	result, err := self.Coll.UpdateOne(
+		ctx, versionFilter(id, version),
+		bson.M{"$set": objs, "$inc": bson.M{"version": 1}},
 	)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return nil
+	return result.MatchedCount != 0, nil
+}
+
+func (self *MongoStore) Update(
+	ctx context.Context, query interface{}, update interface{},
+) (int64, error) {
+	result, err := self.Coll.UpdateMany(ctx, query, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// Atomically applies update to the first document matching query.
+// Returns updated document or nil if nothing matched
+func (self *MongoStore) GetOneAndUpdate(
+	ctx context.Context, query interface{}, update interface{}, castTo interface{},
+) (interface{}, error) {
+	obj := castTo
+
+	err := self.Coll.FindOneAndUpdate(
+		ctx, query, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(obj)
+
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// Applies update to document matching query, inserts it if there's none
+func (self *MongoStore) Upsert(
+	ctx context.Context, query interface{}, update interface{},
+) error {
+	_, err := self.Coll.UpdateOne(ctx, query, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// Increments counter with given id and returns its new value, the first
+// one is 1. Within transaction, aborted increments are rolled back, so
+// sequence has no gaps
+func (self *MongoStore) NextSequence(ctx context.Context, id string) (int64, error) {
+	var counter struct {
+		Value int64 `bson:"value"`
+	}
+	err := self.Coll.FindOneAndUpdate(
+		ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"value": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+	return counter.Value, nil
+}
+
+// Opens change stream of documents inserted after resume token
+// (or from now, if it's empty) and matching filter. Filter applies to
+// change events, so document fields are prefixed with "fullDocument."
+func (self *MongoStore) WatchInserts(
+	ctx context.Context, filter bson.M, resumeAfter string, maxAwaitTime time.Duration,
+) (*mongo.ChangeStream, error) {
+	match := bson.M{"operationType": "insert"}
+	for key, value := range filter {
+		match[key] = value
+	}
+	opts := options.ChangeStream().SetMaxAwaitTime(maxAwaitTime)
+	if len(resumeAfter) != 0 {
+		opts = opts.SetResumeAfter(bson.M{"_data": resumeAfter})
+	}
+	return self.Coll.Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}}, opts)
+}
+
+func (self *MongoStore) Delete(ctx context.Context, query interface{}) (int64, error) {
+	result, err := self.Coll.DeleteMany(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
 }
 
-func (self *MongoStore) DeleteByID(ctx context.Context, id primitive.ObjectID) error {
-	_, err := self.Coll.DeleteOne(ctx, bson.M{"_id": id})
+// Returns false if there was nothing to delete at given version
+func (self *MongoStore) DeleteByID(
+	ctx context.Context, id primitive.ObjectID, version int64,
+) (bool, error) {
+	result, err := self.Coll.DeleteOne(ctx, versionFilter(id, version))
 	if err != nil {
-		return err
+		return false, err
 	}
-	return nil
+	return result.DeletedCount != 0, nil
 }
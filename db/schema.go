@@ -12,10 +12,32 @@ import (
 )
 
 const (
-	mongoUserColl     = "users"
-	mongoHotelsColl   = "hotels"
-	mongoRoomsColl    = "rooms"
-	mongoBookingsColl = "bookings"
+	mongoUserColl              = "users"
+	mongoHotelsColl            = "hotels"
+	mongoRoomsColl             = "rooms"
+	mongoBookingsColl          = "bookings"
+	mongoUserTokensColl        = "userTokens"
+	mongoLoginAttemptsColl     = "loginAttempts"
+	mongoAuthEventsColl        = "authEvents"
+	mongoAPIKeysColl           = "apiKeys"
+	mongoAuditColl             = "audit"
+	mongoOutboxColl            = "outbox"
+	mongoWebhooksColl          = "webhooks"
+	mongoWebhookDeliveriesColl = "webhookDeliveries"
+	mongoHoldsColl             = "holds"
+	mongoSchedulesColl         = "schedules"
+	mongoJobRunsColl           = "jobRuns"
+	mongoLeasesColl            = "leases"
+	mongoNotificationsColl     = "notifications"
+	mongoInvoicesColl          = "invoices"
+	mongoOpenInvoicesColl      = "openInvoices"
+	mongoCountersColl          = "counters"
+	mongoPromotionsColl        = "promotions"
+	mongoRedemptionsColl       = "promotionRedemptions"
+	mongoLoyaltyLedgerColl     = "loyaltyLedger"
+	mongoRatePlansColl         = "ratePlans"
+	mongoStayRestrictionsColl  = "stayRestrictions"
+	mongoWaitlistColl          = "waitlist"
 )
 
 func GetMongoDBClient() *mongo.Client {
@@ -36,37 +58,122 @@ func GetMongoDBClient() *mongo.Client {
 }
 
 type DB struct {
-	mongoDBConn *mongo.Database
-	Users       *MongoStore
-	Hotels      *MongoStore
-	Rooms       *MongoStore
-	Bookings    *MongoStore
+	mongoDBConn   *mongo.Database
+	Users         *MongoStore
+	Hotels        *MongoStore
+	Rooms         *MongoStore
+	Bookings      *MongoStore
+	UserTokens    *MongoStore
+	LoginAttempts *MongoStore
+	AuthEvents    *MongoStore
+	APIKeys       *MongoStore
+	// Append-only, written by controllers on every mutation
+	Audit *MongoStore
+	// Domain events waiting for delivery, see events.Dispatcher
+	Outbox            *MongoStore
+	Webhooks          *MongoStore
+	WebhookDeliveries *MongoStore
+	Holds             *MongoStore
+	// Background jobs, see scheduler.Scheduler
+	Schedules *MongoStore
+	JobRuns   *MongoStore
+	Leases    *MongoStore
+	// Sent and failed messages, see notifications.Notifier
+	Notifications *MongoStore
+	// Insert only, issued invoices never change
+	Invoices *MongoStore
+	// Invoices not fully credited yet, one per booking
+	OpenInvoices *MongoStore
+	// Sequences, see MongoStore.NextSequence
+	Counters   *MongoStore
+	Promotions *MongoStore
+	// Redeemed promotions, see BookingController.redeemPromotion
+	PromotionRedemptions *MongoStore
+	// Append-only points of users, see LoyaltyController
+	LoyaltyLedger *MongoStore
+	RatePlans     *MongoStore
+	// Revenue rules on stays, see StayRestrictionController
+	StayRestrictions *MongoStore
+	// Guests waiting for rooms, see WaitlistController
+	Waitlist *MongoStore
 }
 
-func GetDatabase() *DB {
-	mongoDB := GetMongoDBClient().Database(os.Getenv("MONGO_DB_NAME"))
-	db := &DB{
-		mongoDBConn: mongoDB,
-		Users:       &MongoStore{Coll: mongoDB.Collection(mongoUserColl)},
-		Hotels:      &MongoStore{Coll: mongoDB.Collection(mongoHotelsColl)},
-		Rooms:       &MongoStore{Coll: mongoDB.Collection(mongoRoomsColl)},
-		Bookings:    &MongoStore{Coll: mongoDB.Collection(mongoBookingsColl)},
+func newDatabase(mongoDB *mongo.Database) *DB {
+	return &DB{
+		mongoDBConn:   mongoDB,
+		Users:         &MongoStore{Coll: mongoDB.Collection(mongoUserColl)},
+		Hotels:        &MongoStore{Coll: mongoDB.Collection(mongoHotelsColl)},
+		Rooms:         &MongoStore{Coll: mongoDB.Collection(mongoRoomsColl)},
+		Bookings:      &MongoStore{Coll: mongoDB.Collection(mongoBookingsColl)},
+		UserTokens:    &MongoStore{Coll: mongoDB.Collection(mongoUserTokensColl)},
+		LoginAttempts: &MongoStore{Coll: mongoDB.Collection(mongoLoginAttemptsColl)},
+		AuthEvents:    &MongoStore{Coll: mongoDB.Collection(mongoAuthEventsColl)},
+		APIKeys:       &MongoStore{Coll: mongoDB.Collection(mongoAPIKeysColl)},
+		Audit:         &MongoStore{Coll: mongoDB.Collection(mongoAuditColl)},
+		Outbox:        &MongoStore{Coll: mongoDB.Collection(mongoOutboxColl)},
+		Webhooks:      &MongoStore{Coll: mongoDB.Collection(mongoWebhooksColl)},
+		WebhookDeliveries: &MongoStore{
+			Coll: mongoDB.Collection(mongoWebhookDeliveriesColl),
+		},
+		Holds:     &MongoStore{Coll: mongoDB.Collection(mongoHoldsColl)},
+		Schedules: &MongoStore{Coll: mongoDB.Collection(mongoSchedulesColl)},
+		JobRuns:   &MongoStore{Coll: mongoDB.Collection(mongoJobRunsColl)},
+		Leases:    &MongoStore{Coll: mongoDB.Collection(mongoLeasesColl)},
+		Notifications: &MongoStore{
+			Coll: mongoDB.Collection(mongoNotificationsColl),
+		},
+		Invoices: &MongoStore{Coll: mongoDB.Collection(mongoInvoicesColl)},
+		OpenInvoices: &MongoStore{
+			Coll: mongoDB.Collection(mongoOpenInvoicesColl),
+		},
+		Counters: &MongoStore{Coll: mongoDB.Collection(mongoCountersColl)},
+		Promotions: &MongoStore{
+			Coll: mongoDB.Collection(mongoPromotionsColl),
+		},
+		PromotionRedemptions: &MongoStore{
+			Coll: mongoDB.Collection(mongoRedemptionsColl),
+		},
+		LoyaltyLedger: &MongoStore{
+			Coll: mongoDB.Collection(mongoLoyaltyLedgerColl),
+		},
+		RatePlans: &MongoStore{Coll: mongoDB.Collection(mongoRatePlansColl)},
+		StayRestrictions: &MongoStore{
+			Coll: mongoDB.Collection(mongoStayRestrictionsColl),
+		},
+		Waitlist: &MongoStore{Coll: mongoDB.Collection(mongoWaitlistColl)},
 	}
-	return db
+}
+
+func GetDatabase() *DB {
+	return newDatabase(GetMongoDBClient().Database(os.Getenv("MONGO_DB_NAME")))
 }
 
 func GetTestDatabase() *DB {
-	mongoDB := GetMongoDBClient().Database(os.Getenv("MONGO_DB_TEST_NAME"))
-	db := &DB{
-		mongoDBConn: mongoDB,
-		Users:       &MongoStore{Coll: mongoDB.Collection(mongoUserColl)},
-		Hotels:      &MongoStore{Coll: mongoDB.Collection(mongoHotelsColl)},
-		Rooms:       &MongoStore{Coll: mongoDB.Collection(mongoRoomsColl)},
-		Bookings:    &MongoStore{Coll: mongoDB.Collection(mongoBookingsColl)},
-	}
-	return db
+	return newDatabase(GetMongoDBClient().Database(os.Getenv("MONGO_DB_TEST_NAME")))
 }
 
 func (self *DB) Drop(ctx context.Context) error {
 	return self.mongoDBConn.Drop(ctx)
 }
+
+// Runs fn in transaction. Stores must be called with ctx passed to fn,
+// otherwise they write outside of transaction. Requires replica set
+func (self *DB) WithTransaction(
+	ctx context.Context, fn func(ctx context.Context) error,
+) error {
+	// Nested calls join the outer transaction
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	session, err := self.mongoDBConn.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+	_, err = session.WithTransaction(
+		ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sessionCtx)
+		},
+	)
+	return err
+}
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"context"
+	"hotel/types"
+	"sync"
+)
+
+type Handler func(ctx context.Context, event *types.Event) error
+
+// In-process subscribers. Failed handler causes redelivery of event
+// to all handlers of its type, so handlers should be idempotent
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[types.EventType][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: map[types.EventType][]Handler{}}
+}
+
+func (self *Bus) Subscribe(eventType types.EventType, handler Handler) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.handlers[eventType] = append(self.handlers[eventType], handler)
+}
+
+func (self *Bus) Name() string {
+	return "bus"
+}
+
+func (self *Bus) Deliver(ctx context.Context, event *types.Event) error {
+	self.mu.RLock()
+	handlers := self.handlers[event.Type]
+	self.mu.RUnlock()
+	for _, handler := range handlers {
+		err := handler(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
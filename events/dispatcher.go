@@ -0,0 +1,138 @@
+package events
+
+import (
+	"context"
+	"hotel/db"
+	"hotel/types"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Delivers events from outbox to sinks. Event stays in outbox until
+// every sink has accepted it, failed sinks are retried with backoff.
+// Several instances may run at once, each event is claimed by one
+type Dispatcher struct {
+	Store *db.MongoStore
+	Sinks []Sink
+	// How often outbox is polled once it's drained
+	PollInterval time.Duration
+	// Claimed event is skipped by others for this long, so it's
+	// picked up again if instance dies during delivery
+	ClaimTimeout time.Duration
+	// Delay before the first retry, doubles with each next one
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+func NewDispatcher(dbStore *db.DB, sinks []Sink) *Dispatcher {
+	return &Dispatcher{
+		Store:        dbStore.Outbox,
+		Sinks:        sinks,
+		PollInterval: time.Second,
+		ClaimTimeout: time.Minute,
+		BaseBackoff:  5 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+func (self *Dispatcher) Run(ctx context.Context) {
+	for {
+		_, err := self.DispatchPending(ctx)
+		if err != nil {
+			log.Printf("Failed to dispatch events: %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(self.PollInterval):
+		}
+	}
+}
+
+// Delivers due events until there are none left. Returns number of
+// processed events, including ones which failed and will be retried
+func (self *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	processed := 0
+	for ctx.Err() == nil {
+		event, err := self.claim(ctx, time.Now())
+		if err != nil {
+			return processed, err
+		}
+		if event == nil {
+			break
+		}
+		err = self.dispatch(ctx, event)
+		if err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (self *Dispatcher) claim(ctx context.Context, now time.Time) (*types.Event, error) {
+	result, err := self.Store.GetOneAndUpdate(
+		ctx,
+		bson.M{"deliveredAt": nil, "nextAttemptAt": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"nextAttemptAt": now.Add(self.ClaimTimeout)}},
+		&types.Event{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	event, _ := result.(*types.Event)
+	return event, nil
+}
+
+func (self *Dispatcher) backoff(attempts int) time.Duration {
+	delay := self.BaseBackoff
+	for i := 1; i < attempts && delay < self.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > self.MaxBackoff {
+		delay = self.MaxBackoff
+	}
+	return delay
+}
+
+func isDeliveredTo(event *types.Event, sink Sink) bool {
+	for _, name := range event.DeliveredTo {
+		if name == sink.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *Dispatcher) dispatch(ctx context.Context, event *types.Event) error {
+	var lastErr error
+	for _, sink := range self.Sinks {
+		if isDeliveredTo(event, sink) {
+			continue
+		}
+		deliverCtx, cancel := context.WithTimeout(ctx, self.ClaimTimeout/2)
+		err := sink.Deliver(deliverCtx, event)
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		event.DeliveredTo = append(event.DeliveredTo, sink.Name())
+	}
+
+	now := time.Now()
+	set := bson.M{"deliveredTo": event.DeliveredTo}
+	update := bson.M{"$set": set}
+	if lastErr == nil {
+		set["deliveredAt"] = now
+	} else {
+		event.Attempts++
+		set["lastError"] = lastErr.Error()
+		set["nextAttemptAt"] = now.Add(self.backoff(event.Attempts))
+		update["$inc"] = bson.M{"attempts": 1}
+	}
+	_, err := self.Store.Update(ctx, bson.M{"_id": event.ID}, update)
+	return err
+}
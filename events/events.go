@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"hotel/types"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Destination of domain events. Delivery is at least once, so sinks
+// and their consumers should tolerate duplicates, e.g. by event id
+type Sink interface {
+	// Unique and stable, used to track delivery to the sink
+	Name() string
+	Deliver(ctx context.Context, event *types.Event) error
+}
+
+// Picks sinks based on comma separated EVENTS_SINKS env variable.
+// Bus is always included, so in-process subscribers get every event
+func GetSinks(bus *Bus) []Sink {
+	sinks := []Sink{bus}
+	for _, name := range strings.Split(os.Getenv("EVENTS_SINKS"), ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "webhook":
+			sinks = append(sinks, &WebhookSink{
+				URL:    os.Getenv("EVENTS_WEBHOOK_URL"),
+				Client: &http.Client{Timeout: 10 * time.Second},
+			})
+		case "stream":
+			dir := os.Getenv("EVENTS_STREAM_DIR")
+			if len(dir) == 0 {
+				dir = "eventstream"
+			}
+			sinks = append(sinks, &StreamSink{Dir: dir})
+		default:
+			log.Fatalf("Unknown events sink: %s\n", name)
+		}
+	}
+	return sinks
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"hotel/types"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Local stand-in for NATS or Kafka. Appends every event as JSON line
+// to a file per subject (event type), which consumers can tail
+type StreamSink struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func (self *StreamSink) Name() string {
+	return "stream"
+}
+
+func (self *StreamSink) Deliver(ctx context.Context, event *types.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	err = os.MkdirAll(self.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(
+		filepath.Join(self.Dir, string(event.Type)+".jsonl"),
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644,
+	)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
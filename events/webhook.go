@@ -0,0 +1,48 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hotel/types"
+	"net/http"
+)
+
+const (
+	EventIDHeader   = "X-Event-ID"
+	EventTypeHeader = "X-Event-Type"
+)
+
+// Posts every event as JSON to URL. Any non 2xx response is a failure
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (self *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (self *WebhookSink) Deliver(ctx context.Context, event *types.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", self.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, event.ID.Hex())
+	req.Header.Set(EventTypeHeader, string(event.Type))
+	resp, err := self.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Webhook %s responded with %d", self.URL, resp.StatusCode)
+	}
+	return nil
+}
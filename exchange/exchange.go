@@ -0,0 +1,104 @@
+// Converts money between currencies for display. Rates are loaded from
+// a file and never used for amounts that are charged
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"hotel/types"
+	"log"
+	"math/big"
+	"os"
+)
+
+// Units of every currency per one unit of base currency
+type Rates struct {
+	Base  types.Currency
+	rates map[types.Currency]*big.Rat
+}
+
+type ratesFile struct {
+	Base types.Currency `json:"base"`
+	// Decimal strings, so rates aren't rounded through float
+	Rates map[types.Currency]string `json:"rates"`
+}
+
+// Reads rates from JSON, e.g. {"base": "EUR", "rates": {"USD": "1.0845"}}
+func Load(path string) (*Rates, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file ratesFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, err
+	}
+	if !file.Base.IsValid() {
+		return nil, fmt.Errorf("Unknown base currency %q", file.Base)
+	}
+	rates := &Rates{
+		Base:  file.Base,
+		rates: map[types.Currency]*big.Rat{file.Base: big.NewRat(1, 1)},
+	}
+	for currency, value := range file.Rates {
+		rate, ok := new(big.Rat).SetString(value)
+		if !currency.IsValid() || !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("Invalid rate %q of %q", value, currency)
+		}
+		rates.rates[currency] = rate
+	}
+	return rates, nil
+}
+
+// Rates from EXCHANGE_RATES_FILE, nil if it isn't set
+func GetRates() *Rates {
+	path := os.Getenv("EXCHANGE_RATES_FILE")
+	if len(path) == 0 {
+		return nil
+	}
+	rates, err := Load(path)
+	if err != nil {
+		log.Fatalf("Failed to load exchange rates: %s\n", err.Error())
+	}
+	return rates
+}
+
+func (self *Rates) Has(currency types.Currency) bool {
+	_, ok := self.rates[currency]
+	return ok
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+// Converts through base currency, rounding half away from zero
+// to minor units of target currency
+func (self *Rates) Convert(money types.Money, to types.Currency) (types.Money, error) {
+	if money.Currency == to {
+		return money, nil
+	}
+	fromRate, ok := self.rates[money.Currency]
+	if !ok {
+		return types.Money{}, fmt.Errorf("No exchange rate for %q", money.Currency)
+	}
+	toRate, ok := self.rates[to]
+	if !ok {
+		return types.Money{}, fmt.Errorf("No exchange rate for %q", to)
+	}
+	value := new(big.Rat).SetFrac(
+		big.NewInt(money.Minor), pow10(money.Currency.Exponent()),
+	)
+	value.Quo(value, fromRate).Mul(value, toRate)
+	value.Mul(value, new(big.Rat).SetInt(pow10(to.Exponent())))
+
+	// Rounds half away from zero: (2*num + sign*den) / (2*den), truncated
+	num := new(big.Int).Mul(value.Num(), big.NewInt(2))
+	num.Add(num, new(big.Int).Mul(value.Denom(), big.NewInt(int64(value.Sign()))))
+	minor := new(big.Int).Quo(num, new(big.Int).Mul(value.Denom(), big.NewInt(2)))
+	if !minor.IsInt64() {
+		return types.Money{}, fmt.Errorf("Converted amount %s overflows", value.FloatString(0))
+	}
+	return types.NewMoney(minor.Int64(), to), nil
+}
@@ -0,0 +1,84 @@
+package lockout
+
+import (
+	"context"
+	"hotel/db"
+	"hotel/types"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Storage of failed login attempts
+type Counter interface {
+	Get(ctx context.Context, key string) (*types.LoginAttempts, error)
+	// Adds failure to key. Failures older than window are forgotten
+	Increment(
+		ctx context.Context, key string, now time.Time, window time.Duration,
+	) (*types.LoginAttempts, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, key string) error
+	GetLocked(ctx context.Context, now time.Time) ([]*types.LoginAttempts, error)
+}
+
+// Picks counter implementation based on LOGIN_ATTEMPTS_BACKEND env variable
+func GetCounter(dbStore *db.DB) Counter {
+	switch strings.ToLower(os.Getenv("LOGIN_ATTEMPTS_BACKEND")) {
+	case "mongo":
+		return &MongoCounter{Store: dbStore.LoginAttempts}
+	case "memory", "":
+		return NewMemoryCounter()
+	}
+	log.Fatalf("Unknown login attempts backend: %s\n", os.Getenv("LOGIN_ATTEMPTS_BACKEND"))
+	return nil
+}
+
+type Policy struct {
+	// Failures allowed before lock kicks in
+	FreeAttempts int
+	// Lock duration after first failure over FreeAttempts, doubles with each next one
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Failures older than this are forgotten
+	Window time.Duration
+}
+
+func (self Policy) LockDuration(failures int) time.Duration {
+	over := failures - self.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+	delay := self.BaseDelay
+	for i := 1; i < over && delay < self.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > self.MaxDelay {
+		delay = self.MaxDelay
+	}
+	return delay
+}
+
+var (
+	AccountPolicy = Policy{
+		FreeAttempts: 5,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		Window:       24 * time.Hour,
+	}
+	// Many users may share one address, so limits are looser than per account
+	IPPolicy = Policy{
+		FreeAttempts: 20,
+		BaseDelay:    30 * time.Second,
+		MaxDelay:     time.Hour,
+		Window:       time.Hour,
+	}
+)
+
+func AccountKey(email string) string {
+	return "account:" + strings.ToLower(email)
+}
+
+func IPKey(ip string) string {
+	return "ip:" + ip
+}
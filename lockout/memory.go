@@ -0,0 +1,83 @@
+package lockout
+
+import (
+	"context"
+	"hotel/types"
+	"sync"
+	"time"
+)
+
+// Keeps attempts in process memory. Suitable for single instance deployments
+type MemoryCounter struct {
+	mu       sync.Mutex
+	attempts map[string]*types.LoginAttempts
+}
+
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{attempts: map[string]*types.LoginAttempts{}}
+}
+
+func (self *MemoryCounter) Get(
+	ctx context.Context, key string,
+) (*types.LoginAttempts, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	attempts, ok := self.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *attempts
+	return &copied, nil
+}
+
+func (self *MemoryCounter) Increment(
+	ctx context.Context, key string, now time.Time, window time.Duration,
+) (*types.LoginAttempts, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	attempts, ok := self.attempts[key]
+	if !ok || now.Sub(attempts.LastFailureAt) > window {
+		attempts = &types.LoginAttempts{Key: key}
+		self.attempts[key] = attempts
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = now
+	copied := *attempts
+	return &copied, nil
+}
+
+func (self *MemoryCounter) Lock(
+	ctx context.Context, key string, until time.Time,
+) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	attempts, ok := self.attempts[key]
+	if !ok {
+		attempts = &types.LoginAttempts{Key: key}
+		self.attempts[key] = attempts
+	}
+	attempts.LockedUntil = until
+	return nil
+}
+
+func (self *MemoryCounter) Reset(ctx context.Context, key string) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	delete(self.attempts, key)
+	return nil
+}
+
+func (self *MemoryCounter) GetLocked(
+	ctx context.Context, now time.Time,
+) ([]*types.LoginAttempts, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	locked := []*types.LoginAttempts{}
+	for _, attempts := range self.attempts {
+		if attempts.IsLocked(now) {
+			copied := *attempts
+			locked = append(locked, &copied)
+		}
+	}
+	return locked, nil
+}
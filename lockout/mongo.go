@@ -0,0 +1,93 @@
+package lockout
+
+import (
+	"context"
+	"hotel/db"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Keeps attempts in Mongo, so they are shared between API instances
+type MongoCounter struct {
+	Store *db.MongoStore
+}
+
+func (self *MongoCounter) Get(
+	ctx context.Context, key string,
+) (*types.LoginAttempts, error) {
+	result, err := self.Store.GetOne(ctx, bson.M{"_id": key}, &types.LoginAttempts{})
+	if err != nil {
+		return nil, err
+	}
+	attempts, _ := result.(*types.LoginAttempts)
+	return attempts, nil
+}
+
+// Counts failure in one upsert. Failures older than window keep their
+// record in upsert's way, so it's restarted and failure counted again
+func (self *MongoCounter) Increment(
+	ctx context.Context, key string, now time.Time, window time.Duration,
+) (*types.LoginAttempts, error) {
+	since := now.Add(-window)
+	for {
+		attempts := &types.LoginAttempts{}
+		err := self.Store.Coll.FindOneAndUpdate(
+			ctx,
+			bson.M{"_id": key, "lastFailureAt": bson.M{"$gte": since}},
+			bson.M{
+				"$inc": bson.M{"failures": 1},
+				"$set": bson.M{"lastFailureAt": now},
+			},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(attempts)
+		if !mongo.IsDuplicateKeyError(err) {
+			if err != nil {
+				return nil, err
+			}
+			return attempts, nil
+		}
+
+		// No recent failures, start counting from scratch
+		_, err = self.Store.Coll.UpdateOne(
+			ctx,
+			bson.M{"_id": key, "lastFailureAt": bson.M{"$not": bson.M{"$gte": since}}},
+			bson.M{"$set": bson.M{"failures": 0, "lastFailureAt": now}},
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (self *MongoCounter) Lock(
+	ctx context.Context, key string, until time.Time,
+) error {
+	_, err := self.Store.Coll.UpdateOne(
+		ctx, bson.M{"_id": key},
+		bson.M{"$set": bson.M{"lockedUntil": until}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (self *MongoCounter) Reset(ctx context.Context, key string) error {
+	_, err := self.Store.Coll.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
+
+func (self *MongoCounter) GetLocked(
+	ctx context.Context, now time.Time,
+) ([]*types.LoginAttempts, error) {
+	result, err := self.Store.Get(
+		ctx, bson.M{"lockedUntil": bson.M{"$gt": now}}, []*types.LoginAttempts{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	attempts, _ := result.([]*types.LoginAttempts)
+	return attempts, nil
+}
@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Writes every message as .eml file into Dir instead of sending it.
+// Used for local development and tests
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (self *FileMailer) Send(ctx context.Context, msg *Message) error {
+	err := os.MkdirAll(self.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), msg.To)
+	return os.WriteFile(
+		filepath.Join(self.Dir, name), FormatMessage(self.From, msg), 0o644,
+	)
+}
@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Picks mailer implementation based on MAILER_BACKEND env variable.
+// Falls back to FileMailer, so local development doesn't require SMTP server
+func GetMailer() Mailer {
+	switch strings.ToLower(os.Getenv("MAILER_BACKEND")) {
+	case "smtp":
+		return &SMTPMailer{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAILER_FROM"),
+		}
+	case "file", "":
+		dir := os.Getenv("MAILER_FILE_DIR")
+		if len(dir) == 0 {
+			dir = "mail"
+		}
+		return &FileMailer{Dir: dir, From: os.Getenv("MAILER_FROM")}
+	}
+	log.Fatalf("Unknown mailer backend: %s\n", os.Getenv("MAILER_BACKEND"))
+	return nil
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (self *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	var auth smtp.Auth
+	if len(self.Username) != 0 {
+		auth = smtp.PlainAuth("", self.Username, self.Password, self.Host)
+	}
+	err := smtp.SendMail(
+		fmt.Sprintf("%s:%s", self.Host, self.Port), auth,
+		self.From, []string{msg.To}, FormatMessage(self.From, msg),
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to send mail to %s: %s", msg.To, err.Error())
+	}
+	return nil
+}
+
+func FormatMessage(from string, msg *Message) []byte {
+	lines := []string{
+		fmt.Sprintf("From: %s", from),
+		fmt.Sprintf("To: %s", msg.To),
+		fmt.Sprintf("Subject: %s", msg.Subject),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"utf-8\"",
+		"",
+		msg.Body,
+	}
+	return []byte(strings.Join(lines, "\r\n"))
+}
@@ -5,13 +5,21 @@ import (
 	"hotel/api"
 	"hotel/controllers"
 	"hotel/db"
+	"hotel/events"
+	"hotel/mailer"
+	"hotel/scheduler"
+	"hotel/types"
+	"hotel/webhooks"
 	"log"
+	"strings"
 	"time"
 
 	"os"
 
 	jwtware "github.com/gofiber/contrib/jwt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/joho/godotenv"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -42,59 +50,227 @@ func main() {
 		},
 	)
 
+	// Safety net, panics become internal errors instead of crashing server
+	app.Use(recover.New())
+	app.Use(requestid.New(requestid.Config{
+		ContextKey: controllers.RequestIDContextKey,
+	}))
+
 	roompricesConn := getRoompricesConn()
 	defer roompricesConn.Close()
 
-	CTStore := controllers.NewStore(db.GetDatabase(), roompricesConn)
+	CTStore := controllers.NewStore(
+		db.GetDatabase(), roompricesConn, mailer.GetMailer(),
+	)
+	if err := CTStore.DB.Migrate(context.Background(), controllers.DefaultCurrency()); err != nil {
+		log.Fatal(err)
+	}
+
+	eventBus := events.NewBus()
+	sinks := append(events.GetSinks(eventBus), webhooks.NewSink(CTStore.DB))
+	dispatcher := events.NewDispatcher(CTStore.DB, sinks)
+	go dispatcher.Run(context.Background())
+	go webhooks.NewWorker(CTStore.DB).Run(context.Background())
+	// Jobs can run in a separate worker instead, see cmd/worker
+	if strings.ToLower(os.Getenv("SCHEDULER_ENABLED")) != "false" {
+		go scheduler.NewScheduler(
+			CTStore.DB, scheduler.DefaultJobs(CTStore),
+		).Run(context.Background())
+	}
 
 	userHandler := api.NewUserHandler(
 		&controllers.UserController{Store: CTStore},
 	)
 
-	apiv1 := app.Group("/api/v1")
+	apiv1 := app.Group(api.APIPrefix)
+	apiv1.Get("/openapi.json", api.HandleOpenAPISpec)
+	apiv1.Get("/docs", api.HandleDocs)
 	apiv1.Post("/login", userHandler.HandleLogin)
+	apiv1.Post("/login/otp", userHandler.HandleLoginOTP)
+	apiv1.Post("/login/otp/enrol", userHandler.HandleLoginEnrolOTP)
+	apiv1.Post("/register", userHandler.HandleRegister)
+	apiv1.Post("/verify-email", userHandler.HandleVerifyEmail)
+	apiv1.Post("/resend-verification", userHandler.HandleResendVerification)
+	apiv1.Post("/forgot-password", userHandler.HandleForgotPassword)
+	apiv1.Post("/reset-password", userHandler.HandleResetPassword)
+
+	apiKeyHandler := api.NewAPIKeyHandler(
+		&controllers.APIKeyController{Store: CTStore},
+	)
 
 	secret := os.Getenv("JWT_SECRET")
+	app.Use(api.StreamTokenFromQuery)
 	app.Use(jwtware.New(jwtware.Config{
-		SigningKey:  jwtware.SigningKey{Key: []byte(secret)},
-		TokenLookup: "header:Authorization",
+		SigningKey:   jwtware.SigningKey{Key: []byte(secret)},
+		TokenLookup:  "header:Authorization",
+		Filter:       api.HasAPIKey,
+		ErrorHandler: api.HandleJWTError,
 	}))
+	app.Use(apiKeyHandler.Authenticate)
 
-	apiv1.Post("/user", userHandler.HandleCreateUser)
-	apiv1.Get("/user", userHandler.HandleListUsers)
-	apiv1.Get("/user/:id", userHandler.HandleGetUser)
-	apiv1.Put("/user/:id", userHandler.HandleUpdateUser)
-	apiv1.Delete("/user/:id", userHandler.HandleDeleteUser)
+	apiv1.Post("/change-password", api.DenyAPIKey, userHandler.HandleChangePassword)
+	apiv1.Post("/otp/enrol", api.DenyAPIKey, userHandler.HandleEnrolOTP)
+	apiv1.Post("/otp/confirm", api.DenyAPIKey, userHandler.HandleConfirmOTP)
+	apiv1.Post("/otp/disable", api.DenyAPIKey, userHandler.HandleDisableOTP)
+	apiv1.Post("/otp/recovery-codes", api.DenyAPIKey, userHandler.HandleRegenerateRecoveryCodes)
+
+	usersRead := api.RequirePermission(types.UsersReadPermission)
+	usersWrite := api.RequirePermission(types.UsersWritePermission)
+	apiv1.Post("/user", usersWrite, userHandler.HandleCreateUser)
+	apiv1.Get("/user", usersRead, userHandler.HandleListUsers)
+	apiv1.Get("/user/:id", usersRead, userHandler.HandleGetUser)
+	apiv1.Put("/user/:id", usersWrite, userHandler.HandleUpdateUser)
+	apiv1.Patch("/user/:id", usersWrite, userHandler.HandlePatchUser)
+	apiv1.Delete("/user/:id", usersWrite, userHandler.HandleDeleteUser)
+	apiv1.Post("/user/:id/unlock", userHandler.HandleUnlockUser)
+	loyaltyHandler := api.NewLoyaltyHandler(
+		&controllers.LoyaltyController{Store: CTStore},
+	)
+	apiv1.Get("/user/:id/loyalty", usersRead, loyaltyHandler.HandleGetLoyaltyAccount)
+	apiv1.Get("/lockout", userHandler.HandleListLocked)
+	apiv1.Post("/lockout/unlock-ip", userHandler.HandleUnlockIP)
+
+	apiv1.Post("/apikey", apiKeyHandler.HandleCreateAPIKey)
+	apiv1.Get("/apikey", apiKeyHandler.HandleListAPIKeys)
+	apiv1.Delete("/apikey/:id", apiKeyHandler.HandleRevokeAPIKey)
+
+	auditHandler := api.NewAuditHandler(
+		&controllers.AuditController{Store: CTStore},
+	)
+	apiv1.Get("/audit", auditHandler.HandleListAudit)
+
+	schedulerHandler := api.NewSchedulerHandler(
+		&controllers.SchedulerController{Store: CTStore},
+	)
+	apiv1.Get("/scheduler/schedule", schedulerHandler.HandleListSchedules)
+	apiv1.Get("/scheduler/run", schedulerHandler.HandleListRuns)
 
 	hotelHandler := api.NewHotelHandler(
 		&controllers.HotelController{Store: CTStore},
 	)
 
-	apiv1.Post("/hotel", hotelHandler.HandleCreateHotel)
-	apiv1.Get("/hotel", hotelHandler.HandleListHotels)
-	apiv1.Get("/hotel/:id", hotelHandler.HandleGetHotel)
-	apiv1.Put("/hotel/:id", hotelHandler.HandleUpdateHotel)
-	apiv1.Delete("/hotel/:id", hotelHandler.HandleDeleteHotel)
+	hotelsRead := api.RequirePermission(types.HotelsReadPermission)
+	hotelsWrite := api.RequirePermission(types.HotelsWritePermission)
+	apiv1.Post("/hotel", hotelsWrite, hotelHandler.HandleCreateHotel)
+	apiv1.Get("/hotel", hotelsRead, hotelHandler.HandleListHotels)
+	apiv1.Get("/hotel/:id", hotelsRead, hotelHandler.HandleGetHotel)
+	apiv1.Put("/hotel/:id", hotelsWrite, hotelHandler.HandleUpdateHotel)
+	apiv1.Patch("/hotel/:id", hotelsWrite, hotelHandler.HandlePatchHotel)
+	apiv1.Delete("/hotel/:id", hotelsWrite, hotelHandler.HandleDeleteHotel)
+	apiv1.Post("/hotel/:id/restore", hotelsWrite, hotelHandler.HandleRestoreHotel)
+	apiv1.Delete("/hotel/:id/purge", hotelsWrite, hotelHandler.HandlePurgeHotel)
+	apiv1.Put("/hotel/:id/tax-rules", hotelsWrite, hotelHandler.HandleSetTaxRules)
+
+	webhookHandler := api.NewWebhookHandler(
+		&controllers.WebhookController{Store: CTStore},
+	)
+	apiv1.Post("/webhook", hotelsWrite, webhookHandler.HandleCreateWebhook)
+	apiv1.Get("/webhook", hotelsWrite, webhookHandler.HandleListWebhooks)
+	apiv1.Get("/webhook/:id", hotelsWrite, webhookHandler.HandleGetWebhook)
+	apiv1.Put("/webhook/:id", hotelsWrite, webhookHandler.HandleUpdateWebhook)
+	apiv1.Delete("/webhook/:id", hotelsWrite, webhookHandler.HandleDeleteWebhook)
+	apiv1.Get("/webhook/:id/delivery", hotelsWrite, webhookHandler.HandleListDeliveries)
+	apiv1.Post(
+		"/webhook/:id/delivery/:deliveryID/redeliver",
+		hotelsWrite, webhookHandler.HandleRedeliver,
+	)
 
 	roomHandler := api.NewRoomHandler(
 		&controllers.RoomController{Store: CTStore},
 	)
 
-	apiv1.Post("/room", roomHandler.HandleCreateRoom)
-	apiv1.Get("/room", roomHandler.HandleListRooms)
-	apiv1.Get("/room/:id", roomHandler.HandleGetRoom)
-	apiv1.Put("/room/:id", roomHandler.HandleUpdateRoom)
-	apiv1.Delete("/room/:id", roomHandler.HandleDeleteRoom)
+	roomsRead := api.RequirePermission(types.RoomsReadPermission)
+	roomsWrite := api.RequirePermission(types.RoomsWritePermission)
+	apiv1.Post("/room", roomsWrite, roomHandler.HandleCreateRoom)
+	apiv1.Get("/room", roomsRead, roomHandler.HandleListRooms)
+	apiv1.Get("/room/:id", roomsRead, roomHandler.HandleGetRoom)
+	apiv1.Put("/room/:id", roomsWrite, roomHandler.HandleUpdateRoom)
+	apiv1.Patch("/room/:id", roomsWrite, roomHandler.HandlePatchRoom)
+	apiv1.Delete("/room/:id", roomsWrite, roomHandler.HandleDeleteRoom)
+	apiv1.Post("/room/:id/restore", roomsWrite, roomHandler.HandleRestoreRoom)
+	apiv1.Delete("/room/:id/purge", roomsWrite, roomHandler.HandlePurgeRoom)
+
+	availabilityHandler := api.NewAvailabilityHandler(
+		&controllers.AvailabilityController{Store: CTStore},
+	)
+	apiv1.Get("/room/:id/availability/stream", roomsRead, availabilityHandler.HandleStreamRoom)
+	apiv1.Get("/hotel/:id/availability/stream", hotelsRead, availabilityHandler.HandleStreamHotel)
+	apiv1.Get("/hotel/:id/availability", hotelsRead, availabilityHandler.HandleSearchHotel)
+
+	ratePlanHandler := api.NewRatePlanHandler(
+		&controllers.RatePlanController{Store: CTStore},
+	)
+	apiv1.Post("/rate-plan", hotelsWrite, ratePlanHandler.HandleCreateRatePlan)
+	apiv1.Get("/rate-plan", hotelsRead, ratePlanHandler.HandleListRatePlans)
+	apiv1.Get("/rate-plan/:id", hotelsRead, ratePlanHandler.HandleGetRatePlan)
+	apiv1.Put("/rate-plan/:id", hotelsWrite, ratePlanHandler.HandleUpdateRatePlan)
+	apiv1.Delete("/rate-plan/:id", hotelsWrite, ratePlanHandler.HandleDeleteRatePlan)
+
+	restrictionHandler := api.NewStayRestrictionHandler(
+		&controllers.StayRestrictionController{Store: CTStore},
+	)
+	apiv1.Post("/stay-restriction", hotelsWrite, restrictionHandler.HandleCreateStayRestriction)
+	apiv1.Get("/stay-restriction", hotelsRead, restrictionHandler.HandleListStayRestrictions)
+	apiv1.Get("/stay-restriction/:id", hotelsRead, restrictionHandler.HandleGetStayRestriction)
+	apiv1.Put("/stay-restriction/:id", hotelsWrite, restrictionHandler.HandleUpdateStayRestriction)
+	apiv1.Delete("/stay-restriction/:id", hotelsWrite, restrictionHandler.HandleDeleteStayRestriction)
 
 	bookingHandler := api.NewBookingHandler(
 		&controllers.BookingController{Store: CTStore},
 	)
 
-	apiv1.Post("/booking", bookingHandler.HandleCreateBooking)
-	apiv1.Get("/booking", bookingHandler.HandleListBookings)
-	apiv1.Get("/booking/:id", bookingHandler.HandleGetBooking)
-	apiv1.Put("/booking/:id", bookingHandler.HandleUpdateBooking)
-	apiv1.Delete("/booking/:id", bookingHandler.HandleDeleteBooking)
+	bookingsRead := api.RequirePermission(types.BookingsReadPermission)
+	bookingsWrite := api.RequirePermission(types.BookingsWritePermission)
+	apiv1.Post("/booking", bookingsWrite, bookingHandler.HandleCreateBooking)
+	apiv1.Post("/booking/quote", bookingsRead, bookingHandler.HandleQuoteBooking)
+	apiv1.Get("/booking", bookingsRead, bookingHandler.HandleListBookings)
+	apiv1.Get("/booking/:id", bookingsRead, bookingHandler.HandleGetBooking)
+	apiv1.Put("/booking/:id", bookingsWrite, bookingHandler.HandleUpdateBooking)
+	apiv1.Patch("/booking/:id", bookingsWrite, bookingHandler.HandlePatchBooking)
+	apiv1.Delete("/booking/:id", bookingsWrite, bookingHandler.HandleDeleteBooking)
+	apiv1.Post("/booking/:id/restore", bookingsWrite, bookingHandler.HandleRestoreBooking)
+	apiv1.Delete("/booking/:id/purge", bookingsWrite, bookingHandler.HandlePurgeBooking)
+	apiv1.Post("/booking/:id/check-in", bookingsWrite, bookingHandler.HandleCheckInBooking)
+	apiv1.Post("/booking/:id/check-out", bookingsWrite, bookingHandler.HandleCheckOutBooking)
+
+	holdHandler := api.NewHoldHandler(
+		&controllers.HoldController{Store: CTStore},
+	)
+	apiv1.Post("/hold", bookingsWrite, holdHandler.HandleCreateHold)
+	apiv1.Get("/hold/:id", bookingsRead, holdHandler.HandleGetHold)
+	apiv1.Delete("/hold/:id", bookingsWrite, holdHandler.HandleReleaseHold)
+	apiv1.Post("/hold/:id/book", bookingsWrite, holdHandler.HandleConvertHold)
+
+	waitlistHandler := api.NewWaitlistHandler(
+		&controllers.WaitlistController{Store: CTStore},
+	)
+	apiv1.Post("/waitlist", bookingsWrite, waitlistHandler.HandleJoinWaitlist)
+	apiv1.Get("/waitlist", bookingsRead, waitlistHandler.HandleListWaitlist)
+	apiv1.Get("/waitlist/:id", bookingsRead, waitlistHandler.HandleGetWaitlistEntry)
+	apiv1.Delete("/waitlist/:id", bookingsWrite, waitlistHandler.HandleLeaveWaitlist)
+
+	invoiceHandler := api.NewInvoiceHandler(
+		&controllers.InvoiceController{Store: CTStore},
+	)
+	apiv1.Post("/booking/:id/invoice", bookingsWrite, invoiceHandler.HandleIssueInvoice)
+	apiv1.Get("/invoice", bookingsRead, invoiceHandler.HandleListInvoices)
+	apiv1.Get("/invoice/:id", bookingsRead, invoiceHandler.HandleGetInvoice)
+	apiv1.Get("/invoice/:id/pdf", bookingsRead, invoiceHandler.HandleGetInvoicePDF)
+	apiv1.Post("/invoice/:id/credit-note", bookingsWrite, invoiceHandler.HandleIssueCreditNote)
+
+	promotionHandler := api.NewPromotionHandler(
+		&controllers.PromotionController{Store: CTStore},
+	)
+	apiv1.Post("/promotion", hotelsWrite, promotionHandler.HandleCreatePromotion)
+	apiv1.Get("/promotion", hotelsWrite, promotionHandler.HandleListPromotions)
+	apiv1.Get("/promotion/:id", hotelsWrite, promotionHandler.HandleGetPromotion)
+	apiv1.Put("/promotion/:id", hotelsWrite, promotionHandler.HandleUpdatePromotion)
+	apiv1.Delete("/promotion/:id", hotelsWrite, promotionHandler.HandleDeletePromotion)
+
+	notificationHandler := api.NewNotificationHandler(
+		&controllers.NotificationController{Store: CTStore},
+	)
+	apiv1.Get("/notification", bookingsRead, notificationHandler.HandleListNotifications)
 
 	app.Listen(os.Getenv("APP_LISTEN_URL"))
 }
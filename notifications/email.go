@@ -0,0 +1,25 @@
+package notifications
+
+import (
+	"context"
+	"hotel/mailer"
+	"hotel/types"
+)
+
+type EmailChannel struct {
+	Mailer mailer.Mailer
+}
+
+func (self *EmailChannel) Name() string {
+	return "email"
+}
+
+func (self *EmailChannel) Address(user *types.User) string {
+	return user.Email
+}
+
+func (self *EmailChannel) Send(ctx context.Context, msg *Message) error {
+	return self.Mailer.Send(ctx, &mailer.Message{
+		To: msg.To, Subject: msg.Subject, Body: msg.Body,
+	})
+}
@@ -0,0 +1,55 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"hotel/types"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Appends every message as JSON line to Dir/notifications.jsonl instead
+// of sending it. Used for local development and tests
+type LogChannel struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func (self *LogChannel) Name() string {
+	return "log"
+}
+
+// Messages are only logged, so everyone can be reached
+func (self *LogChannel) Address(user *types.User) string {
+	return user.ID.Hex()
+}
+
+func (self *LogChannel) Send(ctx context.Context, msg *Message) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"at":      time.Now(),
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	err = os.MkdirAll(self.Dir, 0o755)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(
+		filepath.Join(self.Dir, "notifications.jsonl"),
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644,
+	)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
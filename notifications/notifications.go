@@ -0,0 +1,167 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"hotel/db"
+	"hotel/mailer"
+	"hotel/types"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Rendered notification, ready to be sent through a channel
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Way of reaching user, e.g. email or SMS
+type Channel interface {
+	// Unique, recorded with every notification
+	Name() string
+	// Address of user in this channel, empty if user can't be reached
+	Address(user *types.User) string
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Picks channels based on comma separated NOTIFICATIONS_CHANNELS env
+// variable, email by default. Email goes through mailer, so it's SMTP or
+// file by MAILER_BACKEND
+func GetChannels(emailMailer mailer.Mailer) []Channel {
+	names := os.Getenv("NOTIFICATIONS_CHANNELS")
+	if len(strings.TrimSpace(names)) == 0 {
+		names = "email"
+	}
+	channels := []Channel{}
+	for _, name := range strings.Split(names, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "":
+		case "email":
+			channels = append(channels, &EmailChannel{Mailer: emailMailer})
+		case "sms":
+			channels = append(channels, &SMSChannel{
+				URL:    os.Getenv("SMS_PROVIDER_URL"),
+				APIKey: os.Getenv("SMS_PROVIDER_API_KEY"),
+				From:   os.Getenv("SMS_FROM"),
+				Client: &http.Client{Timeout: 10 * time.Second},
+			})
+		case "log":
+			dir := os.Getenv("NOTIFICATIONS_LOG_DIR")
+			if len(dir) == 0 {
+				dir = "notificationlog"
+			}
+			channels = append(channels, &LogChannel{Dir: dir})
+		default:
+			log.Fatalf("Unknown notifications channel: %s\n", name)
+		}
+	}
+	return channels
+}
+
+// Renders notifications in user's language and sends them through every
+// channel user can be reached by. Every attempt is recorded
+type Notifier struct {
+	Notifications *db.MongoStore
+	Channels      []Channel
+	Templates     *Templates
+	// Notifications being sent in background
+	pending sync.WaitGroup
+}
+
+// Reported when user couldn't be reached through any channel
+var ErrNotDelivered = errors.New("Notification wasn't delivered through any channel")
+
+func NewNotifier(dbStore *db.DB, channels []Channel) *Notifier {
+	return &Notifier{
+		Notifications: dbStore.Notifications,
+		Channels:      channels,
+		Templates:     DefaultTemplates,
+	}
+}
+
+// Returns errors of channels which failed to send, after recording them,
+// or ErrNotDelivered if no channel reached user
+func (self *Notifier) Notify(
+	ctx context.Context, kind types.NotificationKind, user *types.User,
+	bookingID primitive.ObjectID, data interface{},
+) error {
+	locale := user.Locale
+	if !locale.IsValid() {
+		locale = types.DefaultLocale
+	}
+	var errs []error
+	delivered := false
+	for _, channel := range self.Channels {
+		notification := &types.Notification{
+			UserID:    user.ID,
+			BookingID: bookingID,
+			Kind:      kind,
+			Channel:   channel.Name(),
+			Locale:    locale,
+			To:        channel.Address(user),
+			CreatedAt: time.Now(),
+		}
+		err := self.send(ctx, channel, notification, data)
+		if err != nil {
+			notification.Status = types.FailedNotificationStatus
+			notification.Error = err.Error()
+			errs = append(errs, err)
+		}
+		delivered = delivered || notification.Status == types.SentNotificationStatus
+		_, err = self.Notifications.Create(ctx, notification)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if !delivered && len(errs) == 0 {
+		return ErrNotDelivered
+	}
+	return errors.Join(errs...)
+}
+
+// Runs fn in background, so operations triggering notifications aren't
+// delayed by slow channels
+func (self *Notifier) Go(fn func()) {
+	self.pending.Add(1)
+	go func() {
+		defer self.pending.Done()
+		fn()
+	}()
+}
+
+// Waits for notifications being sent in background
+func (self *Notifier) Wait() {
+	self.pending.Wait()
+}
+
+func (self *Notifier) send(
+	ctx context.Context, channel Channel, notification *types.Notification,
+	data interface{},
+) error {
+	if len(notification.To) == 0 {
+		notification.Status = types.SkippedNotificationStatus
+		return nil
+	}
+	subject, body, err := self.Templates.Render(
+		notification.Kind, notification.Locale, channel.Name(), data,
+	)
+	if err != nil {
+		return err
+	}
+	notification.Subject = subject
+	notification.Body = body
+	err = channel.Send(ctx, &Message{To: notification.To, Subject: subject, Body: body})
+	if err != nil {
+		return err
+	}
+	notification.Status = types.SentNotificationStatus
+	return nil
+}
@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hotel/types"
+	"net/http"
+)
+
+// Sends text messages through HTTP API of SMS provider. Message is
+// posted as JSON with from, to and text fields, authorized by API key
+type SMSChannel struct {
+	URL    string
+	APIKey string
+	From   string
+	Client *http.Client
+}
+
+func (self *SMSChannel) Name() string {
+	return "sms"
+}
+
+func (self *SMSChannel) Address(user *types.User) string {
+	return user.Phone
+}
+
+func (self *SMSChannel) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(map[string]string{
+		"from": self.From,
+		"to":   msg.To,
+		"text": msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", self.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+self.APIKey)
+	resp, err := self.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("SMS provider responded with %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"hotel/types"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// Templates are stored as templates/<locale>/<kind>.tmpl. Each defines
+// "subject" and "email" templates, and optionally shorter ones for other
+// channels by channel name, e.g. "sms"
+//
+//go:embed templates
+var templateFiles embed.FS
+
+var DefaultTemplates = mustParseTemplates(templateFiles)
+
+// Data available to booking templates
+type BookingData struct {
+	GuestName string
+	HotelName string
+	RoomType  types.RoomType
+	BookingID string
+	DateFrom  civil.Date
+	DateTo    civil.Date
+	Nights    int
+	TotalCost types.Money
+}
+
+// Data available to waitlist offer templates
+type WaitlistOfferData struct {
+	GuestName string
+	HotelName string
+	RoomType  types.RoomType
+	HoldID    string
+	DateFrom  civil.Date
+	DateTo    civil.Date
+	Nights    int
+	ExpiresAt time.Time
+}
+
+type Templates struct {
+	byLocale map[types.Locale]map[types.NotificationKind]*template.Template
+}
+
+func ParseTemplates(files fs.FS) (*Templates, error) {
+	templates := &Templates{
+		byLocale: map[types.Locale]map[types.NotificationKind]*template.Template{},
+	}
+	paths, err := fs.Glob(files, "templates/*/*.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	for _, filePath := range paths {
+		locale := types.Locale(path.Base(path.Dir(filePath)))
+		kind := types.NotificationKind(strings.TrimSuffix(path.Base(filePath), ".tmpl"))
+		tmpl, err := template.ParseFS(files, filePath)
+		if err != nil {
+			return nil, err
+		}
+		if tmpl.Lookup("subject") == nil || tmpl.Lookup("email") == nil {
+			return nil, fmt.Errorf("Template %s should define subject and email", filePath)
+		}
+		if templates.byLocale[locale] == nil {
+			templates.byLocale[locale] = map[types.NotificationKind]*template.Template{}
+		}
+		templates.byLocale[locale][kind] = tmpl
+	}
+	return templates, nil
+}
+
+func mustParseTemplates(files fs.FS) *Templates {
+	templates, err := ParseTemplates(files)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+// Renders subject and body for channel. Falls back to DefaultLocale if
+// there's no template in locale, and to email body if there's none for channel
+func (self *Templates) Render(
+	kind types.NotificationKind, locale types.Locale, channel string, data interface{},
+) (string, string, error) {
+	tmpl := self.byLocale[locale][kind]
+	if tmpl == nil {
+		tmpl = self.byLocale[types.DefaultLocale][kind]
+	}
+	if tmpl == nil {
+		return "", "", fmt.Errorf("No template for %s notification", kind)
+	}
+	bodyName := channel
+	if tmpl.Lookup(bodyName) == nil {
+		bodyName = "email"
+	}
+	var subject, body bytes.Buffer
+	err := tmpl.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return "", "", err
+	}
+	err = tmpl.ExecuteTemplate(&body, bodyName, data)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()) + "\n", nil
+}
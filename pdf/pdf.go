@@ -0,0 +1,128 @@
+// Minimal PDF writer for text-only documents, such as invoices. Pages
+// are A4, text is set in standard Helvetica fonts, so nothing is embedded
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+type text struct {
+	x, y float64
+	size float64
+	bold bool
+	str  string
+}
+
+type Document struct {
+	pages [][]*text
+}
+
+func New() *Document {
+	return &Document{pages: [][]*text{{}}}
+}
+
+func (self *Document) AddPage() {
+	self.pages = append(self.pages, []*text{})
+}
+
+// Places text at x, y from the top left corner of the current page
+func (self *Document) Text(x float64, y float64, size float64, bold bool, str string) {
+	page := len(self.pages) - 1
+	self.pages[page] = append(self.pages[page], &text{
+		x: x, y: PageHeight - y, size: size, bold: bold, str: str,
+	})
+}
+
+// Approximate width of text, used for right alignment. Helvetica digits
+// are 0.556 em wide, most other glyphs are close to that
+func TextWidth(str string, size float64) float64 {
+	return float64(len([]rune(str))) * size * 0.556
+}
+
+// Converts text to WinAnsi string literal. Runes outside Latin-1 are
+// replaced with question marks
+func literal(str string) string {
+	var builder strings.Builder
+	builder.WriteByte('(')
+	for _, r := range str {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			builder.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&builder, "\\%03o", r)
+		default:
+			builder.WriteByte('?')
+		}
+	}
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+func (self *Document) content(page []*text) []byte {
+	var buf bytes.Buffer
+	for _, t := range page {
+		font := "F1"
+		if t.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(
+			&buf, "BT /%s %.1f Tf %.2f %.2f Td %s Tj ET\n",
+			font, t.size, t.x, t.y, literal(t.str),
+		)
+	}
+	return buf.Bytes()
+}
+
+// Serializes document. Objects are numbered as catalog, page tree,
+// two fonts, then page and its content stream for every page
+func (self *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+	kids := []string{}
+	for i := range self.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+2*i))
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf(
+		"<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(self.pages),
+	))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range self.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 6+2*i,
+		))
+		content := self.content(page)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(
+		&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, xref,
+	)
+	return buf.Bytes()
+}
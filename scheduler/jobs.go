@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"hotel/controllers"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+const (
+	defaultReminderDaysBefore = 1
+	defaultPurgeAfterDays     = 30
+)
+
+func envDays(name string, fallback int) int {
+	days, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || days < 0 {
+		return fallback
+	}
+	return days
+}
+
+// Time-based processing of bookings and cleanup. Every job changes only
+// entities still in the state it looks for, so repeated runs are no-ops
+func DefaultJobs(store *controllers.Store) []*Job {
+	reminderDaysBefore := envDays("REMINDER_DAYS_BEFORE", defaultReminderDaysBefore)
+	purgeAfterDays := envDays("PURGE_AFTER_DAYS", defaultPurgeAfterDays)
+	return []*Job{
+		{
+			Name:     "markNoShows",
+			Interval: time.Hour,
+			Run: func(ctx context.Context, at time.Time) (int64, error) {
+				return store.CT.Bookings.MarkNoShows(ctx, civil.DateOf(at))
+			},
+		},
+		{
+			Name:     "checkOutEnded",
+			Interval: time.Hour,
+			Run: func(ctx context.Context, at time.Time) (int64, error) {
+				return store.CT.Bookings.CheckOutEnded(ctx, civil.DateOf(at))
+			},
+		},
+		{
+			Name:     "expireHolds",
+			Interval: time.Minute,
+			Run:      store.CT.Holds.ExpireStale,
+		},
+		{
+			Name:     "expireWaitlistOffers",
+			Interval: time.Minute,
+			Run:      store.CT.Waitlist.ExpireOffers,
+		},
+		{
+			Name:     "sendReminders",
+			Interval: time.Hour,
+			Run: func(ctx context.Context, at time.Time) (int64, error) {
+				return store.CT.Bookings.SendReminders(ctx, at, reminderDaysBefore)
+			},
+		},
+		{
+			Name:     "purgeDeleted",
+			Interval: 24 * time.Hour,
+			Run: func(ctx context.Context, at time.Time) (int64, error) {
+				return controllers.PurgeDeletedBefore(ctx, store, at.AddDate(0, 0, -purgeAfterDays))
+			},
+		},
+	}
+}
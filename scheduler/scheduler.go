@@ -0,0 +1,190 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"hotel/db"
+	"hotel/types"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const leaseID = "scheduler"
+
+// Periodic task. Run gets the time of the tick and returns number of
+// entities it has changed. It must be idempotent, since instance may
+// die mid-run and the next run repeats the work
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, at time.Time) (int64, error)
+}
+
+// Runs jobs by persistent schedules. Any number of instances may run,
+// only the one holding the lease executes jobs, others stand by
+type Scheduler struct {
+	Schedules *db.MongoStore
+	Runs      *db.MongoStore
+	Leases    *db.MongoStore
+	Jobs      []*Job
+	// Identifies this instance in lease and runs
+	Instance string
+	// Lease expires if holder doesn't renew it in time, e.g. if it died
+	LeaseTTL     time.Duration
+	PollInterval time.Duration
+}
+
+func NewScheduler(dbStore *db.DB, jobs []*Job) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		Schedules:    dbStore.Schedules,
+		Runs:         dbStore.JobRuns,
+		Leases:       dbStore.Leases,
+		Jobs:         jobs,
+		Instance:     fmt.Sprintf("%s-%s", hostname, primitive.NewObjectID().Hex()),
+		LeaseTTL:     30 * time.Second,
+		PollInterval: 5 * time.Second,
+	}
+}
+
+func (self *Scheduler) Run(ctx context.Context) {
+	for {
+		_, err := self.Tick(ctx, time.Now())
+		if err != nil {
+			log.Printf("Failed to run scheduled jobs: %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			// Lets standby instance take over without waiting for expiry
+			_, err = self.Leases.Delete(
+				context.Background(), bson.M{"_id": leaseID, "holder": self.Instance},
+			)
+			if err != nil {
+				log.Printf("Failed to release scheduler lease: %s", err.Error())
+			}
+			return
+		case <-time.After(self.PollInterval):
+		}
+	}
+}
+
+// Runs due jobs, if this instance holds the lease. Returns number of runs
+func (self *Scheduler) Tick(ctx context.Context, now time.Time) (int, error) {
+	isLeader, err := self.acquireLease(ctx, now)
+	if err != nil || !isLeader {
+		return 0, err
+	}
+	ran := 0
+	for _, job := range self.Jobs {
+		slot, err := self.claim(ctx, job, now)
+		if err != nil {
+			return ran, err
+		}
+		if slot == nil {
+			continue
+		}
+		err = self.runJob(ctx, job, *slot, now)
+		if err != nil {
+			return ran, err
+		}
+		ran++
+	}
+	return ran, nil
+}
+
+// Takes the lease if it's free or expired, or renews own one
+func (self *Scheduler) acquireLease(ctx context.Context, now time.Time) (bool, error) {
+	err := self.Leases.Upsert(
+		ctx,
+		bson.M{
+			"_id": leaseID,
+			"$or": bson.A{
+				bson.M{"holder": self.Instance},
+				bson.M{"expiresAt": bson.M{"$lte": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"holder": self.Instance, "expiresAt": now.Add(self.LeaseTTL)}},
+	)
+	// Lease exists and isn't ours, so upsert tried to insert it again
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Moves job's schedule to the next slot, if the current one is due.
+// Returns claimed slot, nil if job isn't due or was claimed by other instance
+func (self *Scheduler) claim(
+	ctx context.Context, job *Job, now time.Time,
+) (*time.Time, error) {
+	err := self.Schedules.Upsert(
+		ctx,
+		bson.M{"_id": job.Name},
+		bson.M{
+			"$set":         bson.M{"intervalSeconds": int64(job.Interval.Seconds())},
+			"$setOnInsert": bson.M{"nextRunAt": now},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	result, err := self.Schedules.GetOne(ctx, bson.M{"_id": job.Name}, &types.Schedule{})
+	if err != nil {
+		return nil, err
+	}
+	schedule, _ := result.(*types.Schedule)
+	if schedule == nil || schedule.NextRunAt.After(now) {
+		return nil, nil
+	}
+
+	// Slots missed while no instance was running are skipped
+	next := schedule.NextRunAt.Add(job.Interval)
+	for !next.After(now) {
+		next = next.Add(job.Interval)
+	}
+	result, err = self.Schedules.GetOneAndUpdate(
+		ctx,
+		bson.M{"_id": job.Name, "nextRunAt": schedule.NextRunAt},
+		bson.M{"$set": bson.M{"nextRunAt": next, "lastRunAt": now}},
+		&types.Schedule{},
+	)
+	if err != nil || result == nil {
+		return nil, err
+	}
+	return &schedule.NextRunAt, nil
+}
+
+// Runs job and records outcome. Job's failure is recorded, not returned
+func (self *Scheduler) runJob(
+	ctx context.Context, job *Job, slot time.Time, now time.Time,
+) error {
+	id, err := self.Runs.Create(ctx, &types.JobRun{
+		Job:         job.Name,
+		ScheduledAt: slot,
+		Instance:    self.Instance,
+		Status:      types.RunningJobRunStatus,
+		StartedAt:   now,
+	})
+	if err != nil {
+		return err
+	}
+
+	processed, jobErr := job.Run(ctx, now)
+	set := bson.M{
+		"status":     types.SucceededJobRunStatus,
+		"processed":  processed,
+		"finishedAt": time.Now(),
+	}
+	if jobErr != nil {
+		set["status"] = types.FailedJobRunStatus
+		set["error"] = jobErr.Error()
+		log.Printf("Job %s failed: %s", job.Name, jobErr.Error())
+	}
+	_, err = self.Runs.Update(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	return err
+}
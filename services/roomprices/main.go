@@ -11,6 +11,9 @@ import (
 	"google.golang.org/grpc"
 )
 
+// Currencies without minor units, others have cents
+var zeroDecimalCurrencies = map[string]bool{"ISK": true, "JPY": true}
+
 type RoomPriceServer struct {
 	rpc.UnimplementedRoomPricesServiceServer
 }
@@ -19,8 +22,16 @@ func (self *RoomPriceServer) GetRoomPrice(
 	ctx context.Context, request *rpc.RoomPriceRequest,
 ) (*rpc.RoomPriceResponse, error) {
 	roomType := request.GetType()
+	currency := request.GetCurrency()
+	if len(currency) == 0 {
+		currency = "EUR"
+	}
+	minor := roomType * 2
+	if !zeroDecimalCurrencies[currency] {
+		minor *= 100
+	}
 	return &rpc.RoomPriceResponse{
-		Price: float64(roomType * 2),
+		Price: &rpc.Money{Minor: minor, Currency: currency},
 	}, nil
 }
 
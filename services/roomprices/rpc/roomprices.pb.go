@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
+// 	protoc-gen-go v1.30.0
 // 	protoc        v4.23.1
 // source: roomprices.proto
 
@@ -20,18 +20,77 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Exact amount in minor units of currency, e.g. cents
+type Money struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Minor int64 `protobuf:"varint,1,opt,name=minor,proto3" json:"minor,omitempty"`
+	// ISO 4217 code
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
+}
+
+func (x *Money) Reset() {
+	*x = Money{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_roomprices_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Money) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Money) ProtoMessage() {}
+
+func (x *Money) ProtoReflect() protoreflect.Message {
+	mi := &file_roomprices_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Money.ProtoReflect.Descriptor instead.
+func (*Money) Descriptor() ([]byte, []int) {
+	return file_roomprices_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Money) GetMinor() int64 {
+	if x != nil {
+		return x.Minor
+	}
+	return 0
+}
+
+func (x *Money) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
 type RoomPriceRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	Type int64 `protobuf:"varint,1,opt,name=type,proto3" json:"type,omitempty"`
+	// Currency price is quoted in, hotel's base currency
+	Currency string `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"`
 }
 
 func (x *RoomPriceRequest) Reset() {
 	*x = RoomPriceRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_roomprices_proto_msgTypes[0]
+		mi := &file_roomprices_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -44,7 +103,7 @@ func (x *RoomPriceRequest) String() string {
 func (*RoomPriceRequest) ProtoMessage() {}
 
 func (x *RoomPriceRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_roomprices_proto_msgTypes[0]
+	mi := &file_roomprices_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -57,7 +116,7 @@ func (x *RoomPriceRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RoomPriceRequest.ProtoReflect.Descriptor instead.
 func (*RoomPriceRequest) Descriptor() ([]byte, []int) {
-	return file_roomprices_proto_rawDescGZIP(), []int{0}
+	return file_roomprices_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *RoomPriceRequest) GetType() int64 {
@@ -67,18 +126,25 @@ func (x *RoomPriceRequest) GetType() int64 {
 	return 0
 }
 
+func (x *RoomPriceRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
 type RoomPriceResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Price float64 `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Price *Money `protobuf:"bytes,2,opt,name=price,proto3" json:"price,omitempty"`
 }
 
 func (x *RoomPriceResponse) Reset() {
 	*x = RoomPriceResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_roomprices_proto_msgTypes[1]
+		mi := &file_roomprices_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -91,7 +157,7 @@ func (x *RoomPriceResponse) String() string {
 func (*RoomPriceResponse) ProtoMessage() {}
 
 func (x *RoomPriceResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_roomprices_proto_msgTypes[1]
+	mi := &file_roomprices_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -104,14 +170,14 @@ func (x *RoomPriceResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RoomPriceResponse.ProtoReflect.Descriptor instead.
 func (*RoomPriceResponse) Descriptor() ([]byte, []int) {
-	return file_roomprices_proto_rawDescGZIP(), []int{1}
+	return file_roomprices_proto_rawDescGZIP(), []int{2}
 }
 
-func (x *RoomPriceResponse) GetPrice() float64 {
+func (x *RoomPriceResponse) GetPrice() *Money {
 	if x != nil {
 		return x.Price
 	}
-	return 0
+	return nil
 }
 
 var File_roomprices_proto protoreflect.FileDescriptor
@@ -119,21 +185,28 @@ var File_roomprices_proto protoreflect.FileDescriptor
 var file_roomprices_proto_rawDesc = []byte{
 	0x0a, 0x10, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f,
 	0x74, 0x6f, 0x12, 0x0e, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x5f, 0x72,
-	0x70, 0x63, 0x22, 0x26, 0x0a, 0x10, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0x29, 0x0a, 0x11, 0x52, 0x6f,
-	0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
-	0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05,
-	0x70, 0x72, 0x69, 0x63, 0x65, 0x32, 0x68, 0x0a, 0x11, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69,
-	0x63, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x53, 0x0a, 0x0c, 0x47, 0x65,
-	0x74, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x20, 0x2e, 0x72, 0x6f, 0x6f,
-	0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x5f, 0x72, 0x70, 0x63, 0x2e, 0x52, 0x6f, 0x6f, 0x6d,
-	0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x72,
-	0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x5f, 0x72, 0x70, 0x63, 0x2e, 0x52, 0x6f,
-	0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x42,
-	0x1f, 0x5a, 0x1d, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x2f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x73, 0x2f, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x70, 0x63,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x70, 0x63, 0x22, 0x39, 0x0a, 0x05, 0x4d, 0x6f, 0x6e, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x6d,
+	0x69, 0x6e, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6d, 0x69, 0x6e, 0x6f,
+	0x72, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63, 0x79, 0x22, 0x42, 0x0a,
+	0x10, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63,
+	0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x63, 0x75, 0x72, 0x72, 0x65, 0x6e, 0x63,
+	0x79, 0x22, 0x46, 0x0a, 0x11, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63,
+	0x65, 0x73, 0x5f, 0x72, 0x70, 0x63, 0x2e, 0x4d, 0x6f, 0x6e, 0x65, 0x79, 0x52, 0x05, 0x70, 0x72,
+	0x69, 0x63, 0x65, 0x4a, 0x04, 0x08, 0x01, 0x10, 0x02, 0x32, 0x68, 0x0a, 0x11, 0x52, 0x6f, 0x6f,
+	0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x53,
+	0x0a, 0x0c, 0x47, 0x65, 0x74, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x20,
+	0x2e, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x5f, 0x72, 0x70, 0x63, 0x2e,
+	0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x21, 0x2e, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73, 0x5f, 0x72, 0x70,
+	0x63, 0x2e, 0x52, 0x6f, 0x6f, 0x6d, 0x50, 0x72, 0x69, 0x63, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x42, 0x1f, 0x5a, 0x1d, 0x68, 0x6f, 0x74, 0x65, 0x6c, 0x2f, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x73, 0x2f, 0x72, 0x6f, 0x6f, 0x6d, 0x70, 0x72, 0x69, 0x63, 0x65, 0x73,
+	0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -148,19 +221,21 @@ func file_roomprices_proto_rawDescGZIP() []byte {
 	return file_roomprices_proto_rawDescData
 }
 
-var file_roomprices_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_roomprices_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_roomprices_proto_goTypes = []interface{}{
-	(*RoomPriceRequest)(nil),  // 0: roomprices_rpc.RoomPriceRequest
-	(*RoomPriceResponse)(nil), // 1: roomprices_rpc.RoomPriceResponse
+	(*Money)(nil),             // 0: roomprices_rpc.Money
+	(*RoomPriceRequest)(nil),  // 1: roomprices_rpc.RoomPriceRequest
+	(*RoomPriceResponse)(nil), // 2: roomprices_rpc.RoomPriceResponse
 }
 var file_roomprices_proto_depIdxs = []int32{
-	0, // 0: roomprices_rpc.RoomPricesService.GetRoomPrice:input_type -> roomprices_rpc.RoomPriceRequest
-	1, // 1: roomprices_rpc.RoomPricesService.GetRoomPrice:output_type -> roomprices_rpc.RoomPriceResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0, // 0: roomprices_rpc.RoomPriceResponse.price:type_name -> roomprices_rpc.Money
+	1, // 1: roomprices_rpc.RoomPricesService.GetRoomPrice:input_type -> roomprices_rpc.RoomPriceRequest
+	2, // 2: roomprices_rpc.RoomPricesService.GetRoomPrice:output_type -> roomprices_rpc.RoomPriceResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_roomprices_proto_init() }
@@ -170,7 +245,7 @@ func file_roomprices_proto_init() {
 	}
 	if !protoimpl.UnsafeEnabled {
 		file_roomprices_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*RoomPriceRequest); i {
+			switch v := v.(*Money); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -182,6 +257,18 @@ func file_roomprices_proto_init() {
 			}
 		}
 		file_roomprices_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RoomPriceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_roomprices_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*RoomPriceResponse); i {
 			case 0:
 				return &v.state
@@ -200,7 +287,7 @@ func file_roomprices_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_roomprices_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
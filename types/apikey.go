@@ -0,0 +1,96 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type APIKeyPermission string
+
+const (
+	HotelsReadPermission    APIKeyPermission = "hotels:read"
+	HotelsWritePermission   APIKeyPermission = "hotels:write"
+	RoomsReadPermission     APIKeyPermission = "rooms:read"
+	RoomsWritePermission    APIKeyPermission = "rooms:write"
+	BookingsReadPermission  APIKeyPermission = "bookings:read"
+	BookingsWritePermission APIKeyPermission = "bookings:write"
+	UsersReadPermission     APIKeyPermission = "users:read"
+	UsersWritePermission    APIKeyPermission = "users:write"
+)
+
+func (self APIKeyPermission) IsValid() bool {
+	switch self {
+	case
+		HotelsReadPermission, HotelsWritePermission,
+		RoomsReadPermission, RoomsWritePermission,
+		BookingsReadPermission, BookingsWritePermission,
+		UsersReadPermission, UsersWritePermission:
+		return true
+	}
+	return false
+}
+
+// Key for machine clients. Acts on behalf of UserID, limited by
+// Permissions and HotelIDs. Empty HotelIDs means access to all hotels
+type APIKey struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty"`
+	Version     int64                `bson:"version" json:"version"`
+	Name        string               `bson:"name" json:"name"`
+	Prefix      string               `bson:"prefix" json:"prefix"`
+	KeyHash     string               `bson:"keyHash" json:"-"`
+	UserID      primitive.ObjectID   `bson:"userID" json:"userID"`
+	HotelIDs    []primitive.ObjectID `bson:"hotelIDs" json:"hotelIDs"`
+	Permissions []APIKeyPermission   `bson:"permissions" json:"permissions"`
+	CreatedBy   primitive.ObjectID   `bson:"createdBy" json:"createdBy"`
+	CreatedAt   time.Time            `bson:"createdAt" json:"createdAt"`
+	LastUsedAt  *time.Time           `bson:"lastUsedAt" json:"lastUsedAt"`
+	RevokedAt   *time.Time           `bson:"revokedAt" json:"revokedAt"`
+}
+
+func (self *APIKey) HasPermission(permission APIKeyPermission) bool {
+	for _, granted := range self.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *APIKey) HasHotel(hotelID primitive.ObjectID) bool {
+	if len(self.HotelIDs) == 0 {
+		return true
+	}
+	for _, allowed := range self.HotelIDs {
+		if allowed == hotelID {
+			return true
+		}
+	}
+	return false
+}
+
+// Returned only once, right after creation
+type APIKeyWithSecret struct {
+	*APIKey
+	Key string `bson:"-" json:"key"`
+}
+
+type CreateAPIKeyParams struct {
+	Name        string               `json:"name" validate:"required,min=2,max=64"`
+	UserID      primitive.ObjectID   `json:"userID" validate:"required,exists=User"`
+	HotelIDs    []primitive.ObjectID `json:"hotelIDs" validate:"exists=Hotel"`
+	Permissions []APIKeyPermission   `json:"permissions" validate:"required,enum"`
+}
+
+func NewAPIKeyFromCreateParams(params CreateAPIKeyParams) (*APIKey, error) {
+	hotelIDs := params.HotelIDs
+	if hotelIDs == nil {
+		hotelIDs = []primitive.ObjectID{}
+	}
+	return &APIKey{
+		Name:        params.Name,
+		UserID:      params.UserID,
+		HotelIDs:    hotelIDs,
+		Permissions: params.Permissions,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditAction string
+
+const (
+	CreateAuditAction  AuditAction = "create"
+	UpdateAuditAction  AuditAction = "update"
+	DeleteAuditAction  AuditAction = "delete"
+	RestoreAuditAction AuditAction = "restore"
+	PurgeAuditAction   AuditAction = "purge"
+)
+
+func (self AuditAction) IsValid() bool {
+	switch self {
+	case
+		CreateAuditAction, UpdateAuditAction, DeleteAuditAction,
+		RestoreAuditAction, PurgeAuditAction:
+		return true
+	}
+	return false
+}
+
+// Value of a single field before and after mutation.
+// Nil means field wasn't set (e.g. on creation or deletion)
+type AuditChange struct {
+	Before interface{} `bson:"before" json:"before"`
+	After  interface{} `bson:"after" json:"after"`
+}
+
+// Single mutation of an entity. Records are never updated or deleted
+type AuditRecord struct {
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	// Empty for anonymous actions, e.g. sign up or password reset
+	ActorID   primitive.ObjectID     `bson:"actorID,omitempty" json:"actorID,omitempty"`
+	APIKeyID  primitive.ObjectID     `bson:"apiKeyID,omitempty" json:"apiKeyID,omitempty"`
+	Action    AuditAction            `bson:"action" json:"action"`
+	Entity    string                 `bson:"entity" json:"entity"`
+	EntityID  primitive.ObjectID     `bson:"entityID" json:"entityID"`
+	Changes   map[string]AuditChange `bson:"changes" json:"changes"`
+	RequestID string                 `bson:"requestID,omitempty" json:"requestID,omitempty"`
+	CreatedAt time.Time              `bson:"createdAt" json:"createdAt"`
+}
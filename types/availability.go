@@ -0,0 +1,12 @@
+package types
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Pushed to availability streams whenever room's booked dates change
+type AvailabilityChange struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+	RoomID  primitive.ObjectID `json:"roomID"`
+	// Type of event which caused the change
+	Reason      EventType       `json:"reason"`
+	BookedDates []*BookingDates `json:"bookedDates"`
+}
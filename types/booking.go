@@ -1,23 +1,83 @@
 package types
 
 import (
+	"time"
+
 	"cloud.google.com/go/civil"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type BookingStatus string
+
+const (
+	ConfirmedBookingStatus  BookingStatus = "confirmed"
+	CheckedInBookingStatus  BookingStatus = "checkedIn"
+	CheckedOutBookingStatus BookingStatus = "checkedOut"
+	// Guest didn't check in on arrival date
+	NoShowBookingStatus BookingStatus = "noShow"
+)
+
+func (self BookingStatus) IsValid() bool {
+	switch self {
+	case ConfirmedBookingStatus, CheckedInBookingStatus,
+		CheckedOutBookingStatus, NoShowBookingStatus:
+		return true
+	}
+	return false
+}
+
 type Booking struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	RoomID    primitive.ObjectID `bson:"roomID" json:"roomID"`
-	UserID    primitive.ObjectID `bson:"userID" json:"userID"`
-	DateFrom  civil.Date         `bson:"dateFrom" json:"dateFrom"`
-	DateTo    civil.Date         `bson:"dateTo" json:"dateTo"`
-	TotalCost float64            `bson:"totalCost" json:"totalCost"`
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version  int64              `bson:"version" json:"version"`
+	RoomID   primitive.ObjectID `bson:"roomID" json:"roomID"`
+	UserID   primitive.ObjectID `bson:"userID" json:"userID"`
+	DateFrom civil.Date         `bson:"dateFrom" json:"dateFrom"`
+	DateTo   civil.Date         `bson:"dateTo" json:"dateTo"`
+	// Zero means one, e.g. for bookings made before guests were counted
+	Guests int `bson:"guests" json:"guests"`
+	// Required once hotel has rate plans
+	RatePlanID primitive.ObjectID `bson:"ratePlanID,omitempty" json:"ratePlanID,omitempty"`
+	RatePlan   *BookingRatePlan   `bson:"ratePlan,omitempty" json:"ratePlan,omitempty"`
+	// Price of nights, without discount, taxes and fees
+	RoomCost Money `bson:"roomCost" json:"roomCost"`
+	// Taken off room cost before taxes and fees
+	Discount *BookingDiscount `bson:"discount,omitempty" json:"discount,omitempty"`
+	// Loyalty points taken off room cost after discount
+	RedeemedPoints *RedeemedPoints `bson:"redeemedPoints,omitempty" json:"redeemedPoints,omitempty"`
+	// Taxes and fees, exclusive ones are added to room cost in total
+	Charges   []*BookingCharge `bson:"charges" json:"charges"`
+	TotalCost Money            `bson:"totalCost" json:"totalCost"`
+	// Hold the booking was converted from
+	HoldID primitive.ObjectID `bson:"holdID,omitempty" json:"holdID,omitempty"`
+	Status BookingStatus      `bson:"status" json:"status"`
+	// Set once pre-arrival reminder is sent
+	ReminderSentAt *time.Time `bson:"reminderSentAt,omitempty" json:"reminderSentAt,omitempty"`
+	// Set when booking is deleted (cancelled)
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	// Code guest entered, applied one is kept in discount
+	PromoCode string `bson:"-" json:"-"`
+	// Points guest wants to spend, spent ones are kept in redeemed points
+	PointsToRedeem int64 `bson:"-" json:"-"`
+}
+
+func (self *Booking) GuestCount() int {
+	if self.Guests < 1 {
+		return 1
+	}
+	return self.Guests
+}
+
+// Bookings made before statuses were introduced have none
+func (self *Booking) IsConfirmed() bool {
+	return len(self.Status) == 0 || self.Status == ConfirmedBookingStatus
 }
 
 type BookingUnfolded struct {
 	*Booking
 	Room *Room `bson:"-" json:"room"`
 	User *User `bson:"-" json:"user"`
+	// Total cost converted to currency asked for
+	DisplayTotalCost *Money `bson:"-" json:"displayTotalCost,omitempty"`
 }
 
 type BookingDates struct {
@@ -26,31 +86,52 @@ type BookingDates struct {
 }
 
 type BaseBookingParams struct {
-	RoomID   primitive.ObjectID `json:"roomID"`
-	DateFrom civil.Date         `json:"dateFrom"`
-	DateTo   civil.Date         `json:"dateTo"`
+	RoomID   primitive.ObjectID `json:"roomID" validate:"required,exists=Room"`
+	DateFrom civil.Date         `json:"dateFrom" validate:"required"`
+	DateTo   civil.Date         `json:"dateTo" validate:"required,gtefield=dateFrom"`
+	// Defaults to 1
+	Guests int `json:"guests"`
+	// One of hotel's rate plans, if it has any
+	RatePlanID primitive.ObjectID `json:"ratePlanID" validate:"exists=RatePlan"`
 }
 
 type CreateBookingParams struct {
 	BaseBookingParams
+	// Without code, the best automatic promotion applies
+	PromoCode string `json:"promoCode" validate:"max=64"`
+	// Loyalty points to spend on the stay
+	LoyaltyPoints int64 `json:"loyaltyPoints"`
 }
 
 type UpdateBookingParams struct {
 	BaseBookingParams
 }
 
+func defaultGuests(guests int) int {
+	if guests == 0 {
+		return 1
+	}
+	return guests
+}
+
 func NewBookingFromCreateParams(params CreateBookingParams) (*Booking, error) {
 	return &Booking{
-		RoomID:   params.RoomID,
-		DateFrom: params.DateFrom,
-		DateTo:   params.DateTo,
+		RoomID:         params.RoomID,
+		DateFrom:       params.DateFrom,
+		DateTo:         params.DateTo,
+		Guests:         defaultGuests(params.Guests),
+		RatePlanID:     params.RatePlanID,
+		PromoCode:      params.PromoCode,
+		PointsToRedeem: params.LoyaltyPoints,
 	}, nil
 }
 
 func NewBookingFromUpdateParams(params UpdateBookingParams) (*Booking, error) {
 	return &Booking{
-		RoomID:   params.RoomID,
-		DateFrom: params.DateFrom,
-		DateTo:   params.DateTo,
+		RoomID:     params.RoomID,
+		DateFrom:   params.DateFrom,
+		DateTo:     params.DateTo,
+		Guests:     defaultGuests(params.Guests),
+		RatePlanID: params.RatePlanID,
 	}, nil
 }
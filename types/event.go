@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type EventType string
+
+const (
+	BookingCreatedEventType   EventType = "booking.created"
+	BookingChangedEventType   EventType = "booking.changed"
+	BookingCancelledEventType EventType = "booking.cancelled"
+	BookingRestoredEventType  EventType = "booking.restored"
+	RoomRepricedEventType     EventType = "room.repriced"
+)
+
+func (self EventType) IsValid() bool {
+	switch self {
+	case
+		BookingCreatedEventType, BookingChangedEventType,
+		BookingCancelledEventType, BookingRestoredEventType,
+		RoomRepricedEventType:
+		return true
+	}
+	return false
+}
+
+// Payload of event emitted by controllers
+type DomainEvent interface {
+	EventType() EventType
+	// Hotel event relates to, so subscribers can filter by it
+	EventHotelID() primitive.ObjectID
+}
+
+type BookingCreated struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+	Booking *Booking           `json:"booking"`
+}
+
+func (self *BookingCreated) EventType() EventType             { return BookingCreatedEventType }
+func (self *BookingCreated) EventHotelID() primitive.ObjectID { return self.HotelID }
+
+type BookingChanged struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+	Before  *Booking           `json:"before"`
+	After   *Booking           `json:"after"`
+}
+
+func (self *BookingChanged) EventType() EventType             { return BookingChangedEventType }
+func (self *BookingChanged) EventHotelID() primitive.ObjectID { return self.HotelID }
+
+type BookingCancelled struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+	Booking *Booking           `json:"booking"`
+}
+
+func (self *BookingCancelled) EventType() EventType             { return BookingCancelledEventType }
+func (self *BookingCancelled) EventHotelID() primitive.ObjectID { return self.HotelID }
+
+type BookingRestored struct {
+	HotelID primitive.ObjectID `json:"hotelID"`
+	Booking *Booking           `json:"booking"`
+}
+
+func (self *BookingRestored) EventType() EventType             { return BookingRestoredEventType }
+func (self *BookingRestored) EventHotelID() primitive.ObjectID { return self.HotelID }
+
+type RoomRepriced struct {
+	HotelID  primitive.ObjectID `json:"hotelID"`
+	RoomID   primitive.ObjectID `json:"roomID"`
+	OldPrice Money              `json:"oldPrice"`
+	NewPrice Money              `json:"newPrice"`
+}
+
+func (self *RoomRepriced) EventType() EventType             { return RoomRepricedEventType }
+func (self *RoomRepriced) EventHotelID() primitive.ObjectID { return self.HotelID }
+
+// Event as stored in outbox and delivered to sinks
+type Event struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type      EventType          `bson:"type" json:"type"`
+	HotelID   primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	Payload   json.RawMessage    `bson:"payload" json:"payload"`
+	RequestID string             `bson:"requestID,omitempty" json:"requestID,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+	// Delivery state. Sinks are tracked separately, so one failing
+	// sink doesn't cause redelivery to others
+	DeliveredTo   []string   `bson:"deliveredTo" json:"-"`
+	Attempts      int        `bson:"attempts" json:"-"`
+	NextAttemptAt time.Time  `bson:"nextAttemptAt" json:"-"`
+	LastError     string     `bson:"lastError,omitempty" json:"-"`
+	DeliveredAt   *time.Time `bson:"deliveredAt" json:"-"`
+}
+
+// Decodes payload into typed event, e.g. *BookingCreated
+func (self *Event) Decode(into DomainEvent) error {
+	return json.Unmarshal(self.Payload, into)
+}
@@ -0,0 +1,68 @@
+package types
+
+import (
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type HoldStatus string
+
+const (
+	// Room is unavailable for others until ExpiresAt
+	ActiveHoldStatus    HoldStatus = "active"
+	ConvertedHoldStatus HoldStatus = "converted"
+	ExpiredHoldStatus   HoldStatus = "expired"
+	// Released by guest before expiry
+	ReleasedHoldStatus HoldStatus = "released"
+)
+
+func (self HoldStatus) IsValid() bool {
+	switch self {
+	case ActiveHoldStatus, ConvertedHoldStatus, ExpiredHoldStatus, ReleasedHoldStatus:
+		return true
+	}
+	return false
+}
+
+// Temporary lock of room for dates, while guest completes checkout
+type Hold struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RoomID   primitive.ObjectID `bson:"roomID" json:"roomID"`
+	UserID   primitive.ObjectID `bson:"userID" json:"userID"`
+	DateFrom civil.Date         `bson:"dateFrom" json:"dateFrom"`
+	DateTo   civil.Date         `bson:"dateTo" json:"dateTo"`
+	Guests   int                `bson:"guests" json:"guests"`
+	// Rate plan booking is made with
+	RatePlanID primitive.ObjectID `bson:"ratePlanID,omitempty" json:"ratePlanID,omitempty"`
+	Status     HoldStatus         `bson:"status" json:"status"`
+	ExpiresAt  time.Time          `bson:"expiresAt" json:"expiresAt"`
+	// Set once hold is converted
+	BookingID primitive.ObjectID `bson:"bookingID,omitempty" json:"bookingID,omitempty"`
+	// Set for holds offered to waitlisted guests
+	WaitlistEntryID primitive.ObjectID `bson:"waitlistEntryID,omitempty" json:"waitlistEntryID,omitempty"`
+	CreatedAt       time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+// Active holds past ExpiresAt don't lock the room, even before
+// reaper marks them expired
+func (self *Hold) IsActiveAt(at time.Time) bool {
+	return self.Status == ActiveHoldStatus && at.Before(self.ExpiresAt)
+}
+
+type CreateHoldParams struct {
+	BaseBookingParams
+	// Defaults to and is limited by HOLD_TTL_MINUTES
+	Minutes int `json:"minutes"`
+}
+
+func NewHoldFromCreateParams(params CreateHoldParams) (*Hold, error) {
+	return &Hold{
+		RoomID:     params.RoomID,
+		DateFrom:   params.DateFrom,
+		DateTo:     params.DateTo,
+		Guests:     defaultGuests(params.Guests),
+		RatePlanID: params.RatePlanID,
+	}, nil
+}
@@ -1,13 +1,23 @@
 package types
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Hotel struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version  int64              `bson:"version" json:"version"`
 	Name     string             `bson:"name" json:"name"`
 	Location string             `bson:"location" json:"location"`
+	// Base currency rooms are priced in. Hotels created before
+	// currencies were introduced have none, see controllers.DefaultCurrency
+	Currency Currency `bson:"currency,omitempty" json:"currency,omitempty"`
+	// Taxes and fees applied to bookings, set with their own endpoint
+	TaxRules []*TaxRule `bson:"taxRules" json:"taxRules"`
+	// Set when hotel is deleted. Deleted hotels are hidden, but can be restored
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 }
 
 type HotelWithRooms struct {
@@ -16,8 +26,10 @@ type HotelWithRooms struct {
 }
 
 type BaseHotelParams struct {
-	Name     string `json:"name"`
-	Location string `json:"location"`
+	Name     string `json:"name" validate:"required,min=2,max=128"`
+	Location string `json:"location" validate:"required,min=2,max=256"`
+	// Defaults to DEFAULT_CURRENCY, can't change once hotel has rooms
+	Currency Currency `json:"currency" validate:"enum"`
 }
 
 type CreateHotelParams struct {
@@ -32,6 +44,7 @@ func NewHotelFromCreateParams(params CreateHotelParams) (*Hotel, error) {
 	return &Hotel{
 		Name:     params.Name,
 		Location: params.Location,
+		Currency: params.Currency,
 	}, nil
 }
 
@@ -39,5 +52,6 @@ func NewHotelFromUpdateParams(params UpdateHotelParams) (*Hotel, error) {
 	return &Hotel{
 		Name:     params.Name,
 		Location: params.Location,
+		Currency: params.Currency,
 	}, nil
 }
@@ -0,0 +1,123 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type InvoiceKind string
+
+const (
+	InvoiceInvoiceKind InvoiceKind = "invoice"
+	// Refunds part or all of an invoice, amounts are negative
+	CreditNoteInvoiceKind InvoiceKind = "creditNote"
+)
+
+func (self InvoiceKind) IsValid() bool {
+	switch self {
+	case InvoiceInvoiceKind, CreditNoteInvoiceKind:
+		return true
+	}
+	return false
+}
+
+type InvoiceLineKind string
+
+const (
+	NightInvoiceLineKind InvoiceLineKind = "night"
+	ExtraInvoiceLineKind InvoiceLineKind = "extra"
+	TaxInvoiceLineKind   InvoiceLineKind = "tax"
+	// Promotion taken off nights, amount is negative
+	DiscountInvoiceLineKind InvoiceLineKind = "discount"
+)
+
+func (self InvoiceLineKind) IsValid() bool {
+	switch self {
+	case NightInvoiceLineKind, ExtraInvoiceLineKind, TaxInvoiceLineKind,
+		DiscountInvoiceLineKind:
+		return true
+	}
+	return false
+}
+
+type InvoiceLine struct {
+	Kind        InvoiceLineKind `bson:"kind" json:"kind"`
+	Description string          `bson:"description" json:"description"`
+	Quantity    int             `bson:"quantity" json:"quantity"`
+	UnitPrice   Money           `bson:"unitPrice" json:"unitPrice"`
+	Amount      Money           `bson:"amount" json:"amount"`
+}
+
+type InvoiceParty struct {
+	Name    string `bson:"name" json:"name"`
+	Address string `bson:"address,omitempty" json:"address,omitempty"`
+	Email   string `bson:"email,omitempty" json:"email,omitempty"`
+}
+
+// Issued invoice or credit note. Never changes once issued, corrections
+// are made with credit notes
+type Invoice struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Kind      InvoiceKind        `bson:"kind" json:"kind"`
+	HotelID   primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	BookingID primitive.ObjectID `bson:"bookingID" json:"bookingID"`
+	UserID    primitive.ObjectID `bson:"userID" json:"userID"`
+	// Position in hotel's series of invoices or credit notes, gapless
+	Sequence int64  `bson:"sequence" json:"sequence"`
+	Number   string `bson:"number" json:"number"`
+	// Hotel's base currency, all amounts are in it
+	Currency Currency `bson:"currency" json:"currency"`
+	// Invoice credited by credit note
+	CreditedInvoiceID primitive.ObjectID `bson:"creditedInvoiceID,omitempty" json:"creditedInvoiceID,omitempty"`
+	Seller            InvoiceParty       `bson:"seller" json:"seller"`
+	BilledTo          InvoiceParty       `bson:"billedTo" json:"billedTo"`
+	Lines             []*InvoiceLine     `bson:"lines" json:"lines"`
+	Subtotal          Money              `bson:"subtotal" json:"subtotal"`
+	TaxTotal          Money              `bson:"taxTotal" json:"taxTotal"`
+	Total             Money              `bson:"total" json:"total"`
+	IssuedBy          primitive.ObjectID `bson:"issuedBy" json:"issuedBy"`
+	IssuedAt          time.Time          `bson:"issuedAt" json:"issuedAt"`
+}
+
+// Invoice of booking, which credit notes haven't cancelled out yet.
+// Kept apart from invoices, since issued ones never change
+type OpenInvoice struct {
+	InvoiceID primitive.ObjectID `bson:"_id"`
+	BookingID primitive.ObjectID `bson:"bookingID"`
+}
+
+type InvoiceLineParams struct {
+	Kind        InvoiceLineKind `json:"kind" validate:"required,enum"`
+	Description string          `json:"description" validate:"required,max=256"`
+	Quantity    int             `json:"quantity"`
+	// In hotel's base currency
+	UnitPrice Money `json:"unitPrice"`
+}
+
+type IssueInvoiceParams struct {
+	// Added to nights of the stay, e.g. minibar or taxes
+	Lines []*InvoiceLineParams `json:"lines" validate:"dive"`
+}
+
+type IssueCreditNoteParams struct {
+	// Lines to refund, whole invoice is credited if empty
+	Lines []*InvoiceLineParams `json:"lines" validate:"dive"`
+}
+
+func NewInvoiceLinesFromParams(params []*InvoiceLineParams) []*InvoiceLine {
+	lines := []*InvoiceLine{}
+	for _, line := range params {
+		quantity := line.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		lines = append(lines, &InvoiceLine{
+			Kind:        line.Kind,
+			Description: line.Description,
+			Quantity:    quantity,
+			UnitPrice:   line.UnitPrice,
+		})
+	}
+	return lines
+}
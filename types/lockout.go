@@ -0,0 +1,40 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Failed login attempts for a single key (account or IP address)
+type LoginAttempts struct {
+	Key           string    `bson:"_id" json:"key"`
+	Failures      int       `bson:"failures" json:"failures"`
+	LastFailureAt time.Time `bson:"lastFailureAt" json:"lastFailureAt"`
+	LockedUntil   time.Time `bson:"lockedUntil" json:"lockedUntil"`
+}
+
+func (self *LoginAttempts) IsLocked(now time.Time) bool {
+	return self != nil && self.LockedUntil.After(now)
+}
+
+type AuthEventKind string
+
+const (
+	LockoutAuthEventKind AuthEventKind = "lockout"
+	UnlockAuthEventKind  AuthEventKind = "unlock"
+)
+
+type AuthEvent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Kind        AuthEventKind      `bson:"kind" json:"kind"`
+	Key         string             `bson:"key" json:"key"`
+	Failures    int                `bson:"failures" json:"failures"`
+	LockedUntil time.Time          `bson:"lockedUntil,omitempty" json:"lockedUntil,omitempty"`
+	ActorID     primitive.ObjectID `bson:"actorID,omitempty" json:"actorID,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type UnlockIPParams struct {
+	IP string `json:"ip" validate:"required"`
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LoyaltyEntryKind string
+
+const (
+	// Points earned by checked out stay
+	AccrualLoyaltyEntryKind LoyaltyEntryKind = "accrual"
+	// Points spent as discount on booking
+	RedemptionLoyaltyEntryKind LoyaltyEntryKind = "redemption"
+	// Takes back points earned and spent by cancelled booking
+	ReversalLoyaltyEntryKind LoyaltyEntryKind = "reversal"
+)
+
+func (self LoyaltyEntryKind) IsValid() bool {
+	switch self {
+	case AccrualLoyaltyEntryKind, RedemptionLoyaltyEntryKind, ReversalLoyaltyEntryKind:
+		return true
+	}
+	return false
+}
+
+// Tier of member by nights stayed in the current year
+type LoyaltyTier string
+
+const (
+	MemberLoyaltyTier   LoyaltyTier = "member"
+	SilverLoyaltyTier   LoyaltyTier = "silver"
+	GoldLoyaltyTier     LoyaltyTier = "gold"
+	PlatinumLoyaltyTier LoyaltyTier = "platinum"
+)
+
+func (self LoyaltyTier) IsValid() bool {
+	switch self {
+	case MemberLoyaltyTier, SilverLoyaltyTier, GoldLoyaltyTier, PlatinumLoyaltyTier:
+		return true
+	}
+	return false
+}
+
+// Entry of user's points ledger. Entries are only appended, balance
+// is the sum of their points
+type LoyaltyEntry struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID primitive.ObjectID `bson:"userID" json:"userID"`
+	// Order of entry in user's ledger, starts with 1
+	Sequence  int64              `bson:"sequence" json:"sequence"`
+	BookingID primitive.ObjectID `bson:"bookingID" json:"bookingID"`
+	Kind      LoyaltyEntryKind   `bson:"kind" json:"kind"`
+	// Negative for redemptions and reversals of accruals
+	Points int64 `bson:"points" json:"points"`
+	// Nights counted towards tier, negative for reversals
+	Nights    int       `bson:"nights" json:"nights"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+type LoyaltyAccount struct {
+	UserID         primitive.ObjectID `json:"userID"`
+	Balance        int64              `json:"balance"`
+	Tier           LoyaltyTier        `json:"tier"`
+	NightsThisYear int                `json:"nightsThisYear"`
+	// Newest first
+	Entries []*LoyaltyEntry `json:"entries"`
+}
+
+// Points spent on booking and discount they gave
+type RedeemedPoints struct {
+	Points int64 `bson:"points" json:"points"`
+	Amount Money `bson:"amount" json:"amount"`
+}
@@ -0,0 +1,233 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ISO 4217 currency code
+type Currency string
+
+// Digits after decimal point of supported currencies
+var currencyExponents = map[Currency]int{
+	"AUD": 2, "CAD": 2, "CHF": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2,
+	"HUF": 2, "ISK": 0, "JPY": 0, "NOK": 2, "PLN": 2, "SEK": 2, "USD": 2,
+}
+
+// Supported currencies in alphabetical order
+func Currencies() []Currency {
+	currencies := []Currency{}
+	for currency := range currencyExponents {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i] < currencies[j]
+	})
+	return currencies
+}
+
+func (self Currency) IsValid() bool {
+	_, ok := currencyExponents[self]
+	return ok
+}
+
+// Amounts without currency were stored before currencies were
+// introduced and have cents
+func (self Currency) Exponent() int {
+	exponent, ok := currencyExponents[self]
+	if !ok {
+		return 2
+	}
+	return exponent
+}
+
+// Exact amount of money, kept in minor units of currency (e.g. cents),
+// so sums don't drift. Encoded in JSON with amount as decimal string
+type Money struct {
+	Minor    int64    `bson:"minor"`
+	Currency Currency `bson:"currency"`
+}
+
+func NewMoney(minor int64, currency Currency) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// Parses decimal amount, such as "-12.5". More fraction digits than
+// currency has are rejected rather than rounded
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exponent := currency.Exponent()
+	str := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(str, "-")
+	str = strings.TrimPrefix(strings.TrimPrefix(str, "-"), "+")
+	whole, fraction, hasPoint := strings.Cut(str, ".")
+	if len(whole) == 0 || (hasPoint && len(fraction) == 0) ||
+		strings.Trim(whole+fraction, "0123456789") != "" {
+		return Money{}, fmt.Errorf("Invalid amount %q", amount)
+	}
+	if len(fraction) > exponent {
+		return Money{}, fmt.Errorf(
+			"Amount %q has more than %d fraction digits of %s", amount, exponent, currency,
+		)
+	}
+	minor, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("Invalid amount %q", amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// Amount as decimal string with all fraction digits of currency
+func (self Money) Amount() string {
+	exponent := self.Currency.Exponent()
+	sign := ""
+	minor := self.Minor
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	digits := fmt.Sprintf("%0*d", exponent+1, minor)
+	if exponent == 0 {
+		return sign + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (self Money) String() string {
+	if len(self.Currency) == 0 {
+		return self.Amount()
+	}
+	return self.Amount() + " " + string(self.Currency)
+}
+
+func (self Money) IsZero() bool {
+	return self.Minor == 0
+}
+
+func (self Money) IsNegative() bool {
+	return self.Minor < 0
+}
+
+// Reports amounts which can't be added or compared. Money without
+// currency goes with any
+func CheckSameCurrency(amounts ...Money) error {
+	var currency Currency
+	for _, amount := range amounts {
+		if len(amount.Currency) == 0 {
+			continue
+		}
+		if len(currency) != 0 && amount.Currency != currency {
+			return fmt.Errorf("Currency mismatch: %s and %s", currency, amount.Currency)
+		}
+		currency = amount.Currency
+	}
+	return nil
+}
+
+// Currency of result of arithmetic. Zero money without currency takes
+// currency of the other operand. Callers check other mismatches with
+// CheckSameCurrency beforehand, so they're programming errors
+func (self Money) currencyWith(other Money) Currency {
+	err := CheckSameCurrency(self, other)
+	if err != nil {
+		panic(err.Error())
+	}
+	if len(self.Currency) == 0 {
+		return other.Currency
+	}
+	return self.Currency
+}
+
+func (self Money) Add(other Money) Money {
+	return Money{Minor: self.Minor + other.Minor, Currency: self.currencyWith(other)}
+}
+
+func (self Money) Sub(other Money) Money {
+	return Money{Minor: self.Minor - other.Minor, Currency: self.currencyWith(other)}
+}
+
+func (self Money) Neg() Money {
+	return Money{Minor: -self.Minor, Currency: self.Currency}
+}
+
+func (self Money) Mul(quantity int64) Money {
+	return Money{Minor: self.Minor * quantity, Currency: self.Currency}
+}
+
+// Multiplies by num/den, rounding half away from zero
+func (self Money) MulRat(num int64, den int64) Money {
+	product := self.Minor * num
+	quotient, remainder := product/den, product%den
+	if remainder != 0 && 2*abs(remainder) >= abs(den) {
+		if (product < 0) != (den < 0) {
+			quotient--
+		} else {
+			quotient++
+		}
+	}
+	return Money{Minor: quotient, Currency: self.Currency}
+}
+
+func (self Money) Div(divisor int64) Money {
+	return self.MulRat(1, divisor)
+}
+
+func (self Money) Cmp(other Money) int {
+	self.currencyWith(other)
+	switch {
+	case self.Minor < other.Minor:
+		return -1
+	case self.Minor > other.Minor:
+		return 1
+	}
+	return 0
+}
+
+func abs(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency Currency        `json:"currency"`
+}
+
+func (self Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{self.Amount(), self.Currency})
+}
+
+// Accepts amount both as decimal string and as number, number is
+// parsed from its literal, so it isn't rounded through float
+func (self *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var value moneyJSON
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	if !value.Currency.IsValid() {
+		return fmt.Errorf("Unknown currency %q", value.Currency)
+	}
+	amount := string(value.Amount)
+	if unquoted, err := strconv.Unquote(amount); err == nil {
+		amount = unquoted
+	}
+	money, err := ParseMoney(amount, value.Currency)
+	if err != nil {
+		return err
+	}
+	*self = money
+	return nil
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type NotificationKind string
+
+const (
+	BookingConfirmedNotificationKind NotificationKind = "bookingConfirmed"
+	BookingModifiedNotificationKind  NotificationKind = "bookingModified"
+	BookingCancelledNotificationKind NotificationKind = "bookingCancelled"
+	// Sent before arrival, see BookingController.SendReminders
+	BookingReminderNotificationKind NotificationKind = "bookingReminder"
+	BookingReceiptNotificationKind  NotificationKind = "bookingReceipt"
+	// Room freed for waitlisted guest, see WaitlistController
+	WaitlistOfferNotificationKind NotificationKind = "waitlistOffer"
+)
+
+func (self NotificationKind) IsValid() bool {
+	switch self {
+	case BookingConfirmedNotificationKind, BookingModifiedNotificationKind,
+		BookingCancelledNotificationKind, BookingReminderNotificationKind,
+		BookingReceiptNotificationKind, WaitlistOfferNotificationKind:
+		return true
+	}
+	return false
+}
+
+type NotificationStatus string
+
+const (
+	SentNotificationStatus   NotificationStatus = "sent"
+	FailedNotificationStatus NotificationStatus = "failed"
+	// Channel has no address of the recipient, e.g. user has no phone
+	SkippedNotificationStatus NotificationStatus = "skipped"
+)
+
+func (self NotificationStatus) IsValid() bool {
+	switch self {
+	case SentNotificationStatus, FailedNotificationStatus, SkippedNotificationStatus:
+		return true
+	}
+	return false
+}
+
+// Message sent to user through one channel
+type Notification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userID" json:"userID"`
+	BookingID primitive.ObjectID `bson:"bookingID,omitempty" json:"bookingID,omitempty"`
+	Kind      NotificationKind   `bson:"kind" json:"kind"`
+	Channel   string             `bson:"channel" json:"channel"`
+	Locale    Locale             `bson:"locale" json:"locale"`
+	// Email, phone number, etc. depending on channel
+	To        string             `bson:"to" json:"to"`
+	Subject   string             `bson:"subject" json:"subject"`
+	Body      string             `bson:"body" json:"body"`
+	Status    NotificationStatus `bson:"status" json:"status"`
+	Error     string             `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type Locale string
+
+const (
+	EnglishLocale Locale = "en"
+	GermanLocale  Locale = "de"
+
+	DefaultLocale = EnglishLocale
+)
+
+func (self Locale) IsValid() bool {
+	switch self {
+	case EnglishLocale, GermanLocale:
+		return true
+	}
+	return false
+}
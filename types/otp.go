@@ -0,0 +1,42 @@
+package types
+
+type LoginResult struct {
+	Token string `json:"token,omitempty"`
+	User  *User  `json:"user,omitempty"`
+	// Set instead of Token when second factor is required
+	OTPRequired       bool   `json:"otpRequired,omitempty"`
+	EnrolmentRequired bool   `json:"enrolmentRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+	// Returned once, when TOTP enrolment is completed during login
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type OTPChallengeParams struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+}
+
+type LoginOTPParams struct {
+	ChallengeToken string `json:"challengeToken" validate:"required"`
+	// Either TOTP code or one of the recovery codes
+	Code string `json:"code" validate:"required"`
+	// Filled from request, used to throttle failed attempts
+	IP string `json:"-"`
+}
+
+type OTPEnrolment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+type OTPCodeParams struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type DisableOTPParams struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
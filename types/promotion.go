@@ -0,0 +1,130 @@
+package types
+
+import (
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type DiscountKind string
+
+const (
+	PercentDiscountKind DiscountKind = "percent"
+	FixedDiscountKind   DiscountKind = "fixed"
+)
+
+func (self DiscountKind) IsValid() bool {
+	switch self {
+	case PercentDiscountKind, FixedDiscountKind:
+		return true
+	}
+	return false
+}
+
+// Discount on room cost. Promotions with code apply only when guest
+// enters it, ones without code apply automatically. Zero limits and
+// empty windows or scopes mean no limit
+type Promotion struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version int64              `bson:"version" json:"version"`
+	// Upper case, empty for automatic promotions
+	Code         string       `bson:"code" json:"code"`
+	Name         string       `bson:"name" json:"name"`
+	DiscountKind DiscountKind `bson:"discountKind" json:"discountKind"`
+	// Percent discounts only, in hundredths of percent: 1500 is 15%
+	Rate int64 `bson:"rate" json:"rate"`
+	// Fixed discounts only, per booking. Applies only to hotels
+	// with the same currency
+	Amount Money `bson:"amount" json:"amount"`
+	// When booking can be made
+	ValidFrom time.Time `bson:"validFrom" json:"validFrom"`
+	ValidTo   time.Time `bson:"validTo" json:"validTo"`
+	// Nights of the stay should fall within
+	StayFrom  civil.Date `bson:"stayFrom" json:"stayFrom"`
+	StayTo    civil.Date `bson:"stayTo" json:"stayTo"`
+	MinNights int        `bson:"minNights" json:"minNights"`
+	// Caps of active redemptions, cancelled bookings give theirs back
+	MaxRedemptions        int64 `bson:"maxRedemptions" json:"maxRedemptions"`
+	MaxRedemptionsPerUser int64 `bson:"maxRedemptionsPerUser" json:"maxRedemptionsPerUser"`
+	// Kept by redemptions, never set by clients
+	Redemptions int64                `bson:"redemptions" json:"redemptions"`
+	HotelIDs    []primitive.ObjectID `bson:"hotelIDs" json:"hotelIDs"`
+	RoomTypes   []RoomType           `bson:"roomTypes" json:"roomTypes"`
+	CreatedAt   time.Time            `bson:"createdAt" json:"createdAt"`
+}
+
+// Use of promotion by booking, released when booking is cancelled
+type PromotionRedemption struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	PromotionID primitive.ObjectID `bson:"promotionID" json:"promotionID"`
+	UserID      primitive.ObjectID `bson:"userID" json:"userID"`
+	BookingID   primitive.ObjectID `bson:"bookingID" json:"bookingID"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	ReleasedAt  *time.Time         `bson:"releasedAt" json:"releasedAt"`
+}
+
+// Promotion applied to booking
+type BookingDiscount struct {
+	PromotionID primitive.ObjectID `bson:"promotionID" json:"promotionID"`
+	Code        string             `bson:"code,omitempty" json:"code,omitempty"`
+	Name        string             `bson:"name" json:"name"`
+	Amount      Money              `bson:"amount" json:"amount"`
+}
+
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+type BasePromotionParams struct {
+	Code                  string               `json:"code" validate:"max=64"`
+	Name                  string               `json:"name" validate:"required,min=2,max=128"`
+	DiscountKind          DiscountKind         `json:"discountKind" validate:"required,enum"`
+	Rate                  int64                `json:"rate"`
+	Amount                Money                `json:"amount"`
+	ValidFrom             time.Time            `json:"validFrom"`
+	ValidTo               time.Time            `json:"validTo" validate:"gtfield=validFrom"`
+	StayFrom              civil.Date           `json:"stayFrom"`
+	StayTo                civil.Date           `json:"stayTo" validate:"gtfield=stayFrom"`
+	MinNights             int                  `json:"minNights"`
+	MaxRedemptions        int64                `json:"maxRedemptions"`
+	MaxRedemptionsPerUser int64                `json:"maxRedemptionsPerUser"`
+	HotelIDs              []primitive.ObjectID `json:"hotelIDs" validate:"exists=Hotel"`
+	RoomTypes             []RoomType           `json:"roomTypes" validate:"enum"`
+}
+
+type CreatePromotionParams struct {
+	BasePromotionParams
+}
+
+type UpdatePromotionParams struct {
+	BasePromotionParams
+}
+
+func newPromotionFromParams(params BasePromotionParams) *Promotion {
+	return &Promotion{
+		Code:                  NormalizePromoCode(params.Code),
+		Name:                  params.Name,
+		DiscountKind:          params.DiscountKind,
+		Rate:                  params.Rate,
+		Amount:                params.Amount,
+		ValidFrom:             params.ValidFrom,
+		ValidTo:               params.ValidTo,
+		StayFrom:              params.StayFrom,
+		StayTo:                params.StayTo,
+		MinNights:             params.MinNights,
+		MaxRedemptions:        params.MaxRedemptions,
+		MaxRedemptionsPerUser: params.MaxRedemptionsPerUser,
+		HotelIDs:              params.HotelIDs,
+		RoomTypes:             params.RoomTypes,
+	}
+}
+
+func NewPromotionFromCreateParams(params CreatePromotionParams) (*Promotion, error) {
+	return newPromotionFromParams(params.BasePromotionParams), nil
+}
+
+func NewPromotionFromUpdateParams(params UpdatePromotionParams) (*Promotion, error) {
+	return newPromotionFromParams(params.BasePromotionParams), nil
+}
@@ -0,0 +1,134 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RatePlanKind string
+
+const (
+	FlexibleRatePlanKind RatePlanKind = "flexible"
+	// Cheaper, but paid in full whenever cancelled
+	NonRefundableRatePlanKind     RatePlanKind = "nonRefundable"
+	BreakfastIncludedRatePlanKind RatePlanKind = "breakfastIncluded"
+	// Sold only to loyalty members of given tier, never through API keys
+	MemberOnlyRatePlanKind RatePlanKind = "memberOnly"
+)
+
+func (self RatePlanKind) IsValid() bool {
+	switch self {
+	case FlexibleRatePlanKind, NonRefundableRatePlanKind,
+		BreakfastIncludedRatePlanKind, MemberOnlyRatePlanKind:
+		return true
+	}
+	return false
+}
+
+// Guests can't cancel bookings against policy, admins can
+type CancellationPolicy struct {
+	Refundable bool `bson:"refundable" json:"refundable"`
+	// Days before arrival, until which refundable booking is cancelled
+	// free of charge
+	FreeCancellationDays int `bson:"freeCancellationDays" json:"freeCancellationDays"`
+}
+
+// Way of selling rooms of hotel. Once hotel has rate plans, its rooms
+// are booked only with one of them
+type RatePlan struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version int64              `bson:"version" json:"version"`
+	HotelID primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	Name    string             `bson:"name" json:"name"`
+	Kind    RatePlanKind       `bson:"kind" json:"kind"`
+	// Change of room price in hundredths of percent: -1000 is 10% off
+	PriceAdjustment int64 `bson:"priceAdjustment" json:"priceAdjustment"`
+	// Added per guest per night, e.g. for breakfast
+	GuestNightSupplement Money              `bson:"guestNightSupplement" json:"guestNightSupplement"`
+	Cancellation         CancellationPolicy `bson:"cancellation" json:"cancellation"`
+	// What's included, e.g. "breakfast"
+	Inclusions []string `bson:"inclusions" json:"inclusions"`
+	// Restrictions, zero means none. Empty room types mean all of them
+	MinNights           int        `bson:"minNights" json:"minNights"`
+	MaxNights           int        `bson:"maxNights" json:"maxNights"`
+	AdvancePurchaseDays int        `bson:"advancePurchaseDays" json:"advancePurchaseDays"`
+	RoomTypes           []RoomType `bson:"roomTypes" json:"roomTypes"`
+	// Member only plans, empty means any member
+	MinTier   LoyaltyTier `bson:"minTier,omitempty" json:"minTier,omitempty"`
+	CreatedAt time.Time   `bson:"createdAt" json:"createdAt"`
+}
+
+// Rate plan as it was when booking was made
+type BookingRatePlan struct {
+	Name         string             `bson:"name" json:"name"`
+	Kind         RatePlanKind       `bson:"kind" json:"kind"`
+	Inclusions   []string           `bson:"inclusions" json:"inclusions"`
+	Cancellation CancellationPolicy `bson:"cancellation" json:"cancellation"`
+}
+
+// Way room can be booked for the stay asked for
+type RateOption struct {
+	// Zero for hotels without rate plans
+	RatePlanID primitive.ObjectID `json:"ratePlanID"`
+	Name       string             `json:"name"`
+	Kind       RatePlanKind       `json:"kind,omitempty"`
+	Inclusions []string           `json:"inclusions"`
+	// Without taxes, fees and discounts
+	RoomCost     Money              `json:"roomCost"`
+	Cancellation CancellationPolicy `json:"cancellation"`
+}
+
+type RoomAvailability struct {
+	Room    *Room         `json:"room"`
+	Options []*RateOption `json:"options"`
+}
+
+type BaseRatePlanParams struct {
+	Name                 string             `json:"name" validate:"required,min=2,max=128"`
+	Kind                 RatePlanKind       `json:"kind" validate:"required,enum"`
+	PriceAdjustment      int64              `json:"priceAdjustment"`
+	GuestNightSupplement Money              `json:"guestNightSupplement"`
+	Cancellation         CancellationPolicy `json:"cancellation"`
+	Inclusions           []string           `json:"inclusions" validate:"max=20"`
+	MinNights            int                `json:"minNights"`
+	MaxNights            int                `json:"maxNights"`
+	AdvancePurchaseDays  int                `json:"advancePurchaseDays"`
+	RoomTypes            []RoomType         `json:"roomTypes" validate:"enum"`
+	MinTier              LoyaltyTier        `json:"minTier" validate:"enum"`
+}
+
+type CreateRatePlanParams struct {
+	HotelID primitive.ObjectID `json:"hotelID" validate:"required,exists=Hotel"`
+	BaseRatePlanParams
+}
+
+type UpdateRatePlanParams struct {
+	BaseRatePlanParams
+}
+
+func newRatePlanFromParams(params BaseRatePlanParams) *RatePlan {
+	return &RatePlan{
+		Name:                 params.Name,
+		Kind:                 params.Kind,
+		PriceAdjustment:      params.PriceAdjustment,
+		GuestNightSupplement: params.GuestNightSupplement,
+		Cancellation:         params.Cancellation,
+		Inclusions:           params.Inclusions,
+		MinNights:            params.MinNights,
+		MaxNights:            params.MaxNights,
+		AdvancePurchaseDays:  params.AdvancePurchaseDays,
+		RoomTypes:            params.RoomTypes,
+		MinTier:              params.MinTier,
+	}
+}
+
+func NewRatePlanFromCreateParams(params CreateRatePlanParams) (*RatePlan, error) {
+	ratePlan := newRatePlanFromParams(params.BaseRatePlanParams)
+	ratePlan.HotelID = params.HotelID
+	return ratePlan, nil
+}
+
+func NewRatePlanFromUpdateParams(params UpdateRatePlanParams) (*RatePlan, error) {
+	return newRatePlanFromParams(params.BaseRatePlanParams), nil
+}
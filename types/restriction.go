@@ -0,0 +1,95 @@
+package types
+
+import (
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RestrictionKind string
+
+const (
+	// Stays arriving on covered dates should last at least given nights
+	MinLOSRestrictionKind RestrictionKind = "minLOS"
+	// Stays arriving on covered dates should last at most given nights
+	MaxLOSRestrictionKind RestrictionKind = "maxLOS"
+	// No arrivals on covered dates
+	ClosedToArrivalRestrictionKind RestrictionKind = "closedToArrival"
+	// No departures on covered dates
+	ClosedToDepartureRestrictionKind RestrictionKind = "closedToDeparture"
+	// No nights sold on covered dates
+	StopSellRestrictionKind RestrictionKind = "stopSell"
+)
+
+func (self RestrictionKind) IsValid() bool {
+	switch self {
+	case MinLOSRestrictionKind, MaxLOSRestrictionKind, ClosedToArrivalRestrictionKind,
+		ClosedToDepartureRestrictionKind, StopSellRestrictionKind:
+		return true
+	}
+	return false
+}
+
+// Whether rule limits length of stay and needs nights
+func (self RestrictionKind) HasNights() bool {
+	return self == MinLOSRestrictionKind || self == MaxLOSRestrictionKind
+}
+
+// Rule revenue managers put on stays of hotel, e.g. at least 3 nights
+// on festival weekends or no arrivals on Sunday
+type StayRestriction struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version int64              `bson:"version" json:"version"`
+	HotelID primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	// Zero means all rooms of hotel
+	RoomType RoomType        `bson:"roomType,omitempty" json:"roomType,omitempty"`
+	Kind     RestrictionKind `bson:"kind" json:"kind"`
+	// Covered dates, both inclusive
+	DateFrom civil.Date `bson:"dateFrom" json:"dateFrom"`
+	DateTo   civil.Date `bson:"dateTo" json:"dateTo"`
+	// Days of week covered within dates, 0 is Sunday. Empty means all
+	Weekdays []time.Weekday `bson:"weekdays" json:"weekdays"`
+	// Length of stay for min and max LOS rules
+	Nights    int       `bson:"nights,omitempty" json:"nights,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+type BaseStayRestrictionParams struct {
+	RoomType RoomType        `json:"roomType" validate:"enum"`
+	Kind     RestrictionKind `json:"kind" validate:"required,enum"`
+	DateFrom civil.Date      `json:"dateFrom" validate:"required"`
+	DateTo   civil.Date      `json:"dateTo" validate:"required,gtefield=dateFrom"`
+	Weekdays []time.Weekday  `json:"weekdays" validate:"max=7"`
+	Nights   int             `json:"nights"`
+}
+
+type CreateStayRestrictionParams struct {
+	HotelID primitive.ObjectID `json:"hotelID" validate:"required,exists=Hotel"`
+	BaseStayRestrictionParams
+}
+
+type UpdateStayRestrictionParams struct {
+	BaseStayRestrictionParams
+}
+
+func newStayRestrictionFromParams(params BaseStayRestrictionParams) *StayRestriction {
+	return &StayRestriction{
+		RoomType: params.RoomType,
+		Kind:     params.Kind,
+		DateFrom: params.DateFrom,
+		DateTo:   params.DateTo,
+		Weekdays: params.Weekdays,
+		Nights:   params.Nights,
+	}
+}
+
+func NewStayRestrictionFromCreateParams(params CreateStayRestrictionParams) (*StayRestriction, error) {
+	restriction := newStayRestrictionFromParams(params.BaseStayRestrictionParams)
+	restriction.HotelID = params.HotelID
+	return restriction, nil
+}
+
+func NewStayRestrictionFromUpdateParams(params UpdateStayRestrictionParams) (*StayRestriction, error) {
+	return newStayRestrictionFromParams(params.BaseStayRestrictionParams), nil
+}
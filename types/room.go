@@ -1,6 +1,9 @@
 package types
 
 import (
+	"fmt"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -23,22 +26,42 @@ func (self RoomType) IsValid() bool {
 	return false
 }
 
+func (self RoomType) String() string {
+	switch self {
+	case SingleRoomType:
+		return "Single"
+	case DoubleRoomType:
+		return "Double"
+	case SeaSideRoomType:
+		return "Sea side"
+	case DeluxeRoomType:
+		return "Deluxe"
+	}
+	return fmt.Sprintf("RoomType(%d)", int(self))
+}
+
 type Room struct {
 	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version int64              `bson:"version" json:"version"`
 	Type    RoomType           `bson:"type" json:"type"`
-	Price   float64            `bson:"price" json:"price"`
+	// Quoted in hotel's base currency
+	Price   Money              `bson:"price" json:"price"`
 	HotelID primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	// Set when room is deleted or archived together with its hotel
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 }
 
 type RoomUnfolded struct {
 	*Room
 	Hotel       *Hotel          `bson:"-" json:"hotel"`
 	BookedDates []*BookingDates `bson:"-" json:"bookedDates"`
+	// Price converted to currency asked for
+	DisplayPrice *Money `bson:"-" json:"displayPrice,omitempty"`
 }
 
 type BaseRoomParams struct {
-	Type    RoomType           `json:"type"`
-	HotelID primitive.ObjectID `json:"hotelID"`
+	Type    RoomType           `json:"type" validate:"required,enum"`
+	HotelID primitive.ObjectID `json:"hotelID" validate:"required,exists=Hotel"`
 }
 
 type CreateRoomParams struct {
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Persistent state of periodic job, so runs survive restarts and
+// aren't repeated by other instances
+type Schedule struct {
+	// Job name
+	ID              string     `bson:"_id" json:"id"`
+	IntervalSeconds int64      `bson:"intervalSeconds" json:"intervalSeconds"`
+	NextRunAt       time.Time  `bson:"nextRunAt" json:"nextRunAt"`
+	LastRunAt       *time.Time `bson:"lastRunAt,omitempty" json:"lastRunAt,omitempty"`
+}
+
+type JobRunStatus string
+
+const (
+	RunningJobRunStatus   JobRunStatus = "running"
+	SucceededJobRunStatus JobRunStatus = "succeeded"
+	FailedJobRunStatus    JobRunStatus = "failed"
+)
+
+func (self JobRunStatus) IsValid() bool {
+	switch self {
+	case RunningJobRunStatus, SucceededJobRunStatus, FailedJobRunStatus:
+		return true
+	}
+	return false
+}
+
+type JobRun struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Job string             `bson:"job" json:"job"`
+	// Slot of the schedule this run covers, each slot runs once
+	ScheduledAt time.Time    `bson:"scheduledAt" json:"scheduledAt"`
+	Instance    string       `bson:"instance" json:"instance"`
+	Status      JobRunStatus `bson:"status" json:"status"`
+	// Number of entities job has changed
+	Processed  int64      `bson:"processed" json:"processed"`
+	Error      string     `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt  time.Time  `bson:"startedAt" json:"startedAt"`
+	FinishedAt *time.Time `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+}
+
+// Held by one instance at a time, renewed until it stops or dies
+type Lease struct {
+	ID        string    `bson:"_id" json:"id"`
+	Holder    string    `bson:"holder" json:"holder"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
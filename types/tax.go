@@ -0,0 +1,70 @@
+package types
+
+type TaxKind string
+
+const (
+	// Percentage of room cost and fees other than city tax
+	VATTaxKind TaxKind = "vat"
+	// Per person per night
+	CityTaxKind TaxKind = "cityTax"
+	// Per stay
+	CleaningFeeTaxKind TaxKind = "cleaningFee"
+	// Per night
+	ResortFeeTaxKind TaxKind = "resortFee"
+)
+
+func (self TaxKind) IsValid() bool {
+	switch self {
+	case VATTaxKind, CityTaxKind, CleaningFeeTaxKind, ResortFeeTaxKind:
+		return true
+	}
+	return false
+}
+
+// Tax or fee of hotel's jurisdiction, applied to every booking
+type TaxRule struct {
+	Kind TaxKind `bson:"kind" json:"kind"`
+	// Shown to guests, e.g. "VAT 7%"
+	Name string `bson:"name" json:"name"`
+	// VAT only, in hundredths of percent: 1900 is 19%
+	Rate int64 `bson:"rate" json:"rate"`
+	// Fees only, in hotel's base currency
+	Amount Money `bson:"amount" json:"amount"`
+	// Already contained in room price, so not added to total
+	Inclusive bool `bson:"inclusive" json:"inclusive"`
+}
+
+// Tax or fee applied to booking, kept for reporting
+type BookingCharge struct {
+	Kind      TaxKind `bson:"kind" json:"kind"`
+	Name      string  `bson:"name" json:"name"`
+	Amount    Money   `bson:"amount" json:"amount"`
+	Inclusive bool    `bson:"inclusive" json:"inclusive"`
+}
+
+type TaxRuleParams struct {
+	Kind      TaxKind `json:"kind" validate:"required,enum"`
+	Name      string  `json:"name" validate:"max=64"`
+	Rate      int64   `json:"rate"`
+	Amount    Money   `json:"amount"`
+	Inclusive bool    `json:"inclusive"`
+}
+
+type SetTaxRulesParams struct {
+	// Replace all rules of hotel, at most one of each kind
+	Rules []*TaxRuleParams `json:"rules" validate:"dive"`
+}
+
+func NewTaxRulesFromParams(params SetTaxRulesParams) []*TaxRule {
+	rules := []*TaxRule{}
+	for _, rule := range params.Rules {
+		rules = append(rules, &TaxRule{
+			Kind:      rule.Kind,
+			Name:      rule.Name,
+			Rate:      rule.Rate,
+			Amount:    rule.Amount,
+			Inclusive: rule.Inclusive,
+		})
+	}
+	return rules
+}
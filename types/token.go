@@ -0,0 +1,26 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserTokenKind string
+
+const (
+	EmailVerificationTokenKind UserTokenKind = "emailVerification"
+	PasswordResetTokenKind     UserTokenKind = "passwordReset"
+	// Issued after correct password when second factor is required
+	OTPChallengeTokenKind UserTokenKind = "otpChallenge"
+)
+
+// Single-use token sent to user by email. Only hash of the token is stored
+type UserToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID    primitive.ObjectID `bson:"userID" json:"userID"`
+	Kind      UserTokenKind      `bson:"kind" json:"kind"`
+	TokenHash string             `bson:"tokenHash" json:"-"`
+	ExpiresAt time.Time          `bson:"expiresAt" json:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt" json:"usedAt"`
+}
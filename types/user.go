@@ -5,40 +5,80 @@ import (
 )
 
 type User struct {
-	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	FirstName         string             `bson:"firstName" json:"firstName"`
-	LastName          string             `bson:"lastName" json:"lastName"`
-	Email             string             `bson:"email" json:"email"`
-	IsAdmin           bool               `bson:"isAdmin" json:"-"`
-	Password          string             `bson:"-" json:"-"`
-	EncryptedPassword string             `bson:"encryptedPassword,omitempty" json:"-"`
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version   int64              `bson:"version" json:"version"`
+	FirstName string             `bson:"firstName" json:"firstName"`
+	LastName  string             `bson:"lastName" json:"lastName"`
+	Email     string             `bson:"email" json:"email"`
+	// Used for SMS notifications, optional
+	Phone string `bson:"phone" json:"phone"`
+	// Language of notifications, DefaultLocale if empty
+	Locale            Locale `bson:"locale" json:"locale"`
+	IsAdmin           bool   `bson:"isAdmin" json:"-"`
+	IsVerified        bool   `bson:"isVerified" json:"isVerified"`
+	Password          string `bson:"-" json:"-"`
+	EncryptedPassword string `bson:"encryptedPassword,omitempty" json:"-"`
+	// Two-factor auth. Fields are only changed explicitly, hence omitempty
+	TOTPEnabled bool   `bson:"totpEnabled,omitempty" json:"totpEnabled"`
+	TOTPSecret  string `bson:"totpSecret,omitempty" json:"-"`
+	// Last used TOTP step, so the same code can't be used twice
+	TOTPLastStep  int64    `bson:"totpLastStep,omitempty" json:"-"`
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty" json:"-"`
 }
 
 type LoginUserParams struct {
-	Email    string `bson:"email" json:"email"`
-	Password string `bson:"-" json:"password"`
+	Email    string `bson:"email" json:"email" validate:"required"`
+	Password string `bson:"-" json:"password" validate:"required"`
+	// Filled from request, used to throttle failed attempts
+	IP string `bson:"-" json:"-"`
 }
 
 type BaseUserParams struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
+	FirstName string `json:"firstName" validate:"required,min=2,max=64"`
+	LastName  string `json:"lastName" validate:"required,min=2,max=64"`
+	Email     string `json:"email" validate:"required,email"`
+	Phone     string `json:"phone" validate:"max=32"`
+	Locale    Locale `json:"locale" validate:"enum"`
 }
 
 type CreateUserParams struct {
 	BaseUserParams
-	Password string `json:"password"`
+	Password string `json:"password" validate:"required,min=7,max=72"`
 }
 
 type UpdateUserParams struct {
 	BaseUserParams
 }
 
+type VerifyEmailParams struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationParams struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPasswordParams struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordParams struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=7,max=72"`
+}
+
+type ChangePasswordParams struct {
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required,min=7,max=72"`
+}
+
 func NewUserFromCreateParams(params CreateUserParams) (*User, error) {
 	return &User{
 		FirstName: params.FirstName,
 		LastName:  params.LastName,
 		Email:     params.Email,
+		Phone:     params.Phone,
+		Locale:    params.Locale,
 		Password:  params.Password,
 	}, nil
 }
@@ -48,5 +88,7 @@ func NewUserFromUpdateParams(params UpdateUserParams) (*User, error) {
 		FirstName: params.FirstName,
 		LastName:  params.LastName,
 		Email:     params.Email,
+		Phone:     params.Phone,
+		Locale:    params.Locale,
 	}, nil
 }
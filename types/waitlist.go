@@ -0,0 +1,75 @@
+package types
+
+import (
+	"time"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WaitlistStatus string
+
+const (
+	// Waits for room to be freed
+	WaitingWaitlistStatus WaitlistStatus = "waiting"
+	// Guest has offer hold until OfferExpiresAt
+	OfferedWaitlistStatus WaitlistStatus = "offered"
+	// Offer hold was converted into booking
+	BookedWaitlistStatus WaitlistStatus = "booked"
+	// Offer expired, it passed to the next guest
+	ExpiredWaitlistStatus WaitlistStatus = "expired"
+	// Guest released offer hold, it passed to the next guest
+	DeclinedWaitlistStatus WaitlistStatus = "declined"
+)
+
+func (self WaitlistStatus) IsValid() bool {
+	switch self {
+	case WaitingWaitlistStatus, OfferedWaitlistStatus, BookedWaitlistStatus,
+		ExpiredWaitlistStatus, DeclinedWaitlistStatus:
+		return true
+	}
+	return false
+}
+
+// Guest waiting for room of type in hotel for dates. Once cancellation
+// frees such room, guests are offered it in order they joined
+type WaitlistEntry struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	HotelID  primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	RoomType RoomType           `bson:"roomType" json:"roomType"`
+	UserID   primitive.ObjectID `bson:"userID" json:"userID"`
+	DateFrom civil.Date         `bson:"dateFrom" json:"dateFrom"`
+	DateTo   civil.Date         `bson:"dateTo" json:"dateTo"`
+	Guests   int                `bson:"guests" json:"guests"`
+	// Rate plan offer is booked with
+	RatePlanID primitive.ObjectID `bson:"ratePlanID,omitempty" json:"ratePlanID,omitempty"`
+	Status     WaitlistStatus     `bson:"status" json:"status"`
+	// Set once room is offered
+	HoldID         primitive.ObjectID `bson:"holdID,omitempty" json:"holdID,omitempty"`
+	OfferExpiresAt *time.Time         `bson:"offerExpiresAt,omitempty" json:"offerExpiresAt,omitempty"`
+	// Set once offer is booked
+	BookingID primitive.ObjectID `bson:"bookingID,omitempty" json:"bookingID,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+type CreateWaitlistEntryParams struct {
+	HotelID  primitive.ObjectID `json:"hotelID" validate:"required,exists=Hotel"`
+	RoomType RoomType           `json:"roomType" validate:"required,enum"`
+	DateFrom civil.Date         `json:"dateFrom" validate:"required"`
+	DateTo   civil.Date         `json:"dateTo" validate:"required,gtfield=dateFrom"`
+	// Defaults to 1
+	Guests int `json:"guests"`
+	// One of hotel's rate plans, if it has any
+	RatePlanID primitive.ObjectID `json:"ratePlanID" validate:"exists=RatePlan"`
+}
+
+func NewWaitlistEntryFromCreateParams(params CreateWaitlistEntryParams) (*WaitlistEntry, error) {
+	return &WaitlistEntry{
+		HotelID:    params.HotelID,
+		RoomType:   params.RoomType,
+		DateFrom:   params.DateFrom,
+		DateTo:     params.DateTo,
+		Guests:     defaultGuests(params.Guests),
+		RatePlanID: params.RatePlanID,
+	}, nil
+}
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Partner's endpoint receiving events of a hotel. Deliveries are
+// signed with Secret, see webhooks.Sign
+type Webhook struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Version    int64              `bson:"version" json:"version"`
+	HotelID    primitive.ObjectID `bson:"hotelID" json:"hotelID"`
+	URL        string             `bson:"url" json:"url"`
+	EventTypes []EventType        `bson:"eventTypes" json:"eventTypes"`
+	IsActive   bool               `bson:"isActive" json:"isActive"`
+	Secret     string             `bson:"secret" json:"-"`
+	CreatedBy  primitive.ObjectID `bson:"createdBy" json:"createdBy"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+}
+
+func (self *Webhook) HasEventType(eventType EventType) bool {
+	for _, subscribed := range self.EventTypes {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Returned only once, right after creation
+type WebhookWithSecret struct {
+	*Webhook
+	Secret string `bson:"-" json:"secret"`
+}
+
+type BaseWebhookParams struct {
+	URL        string      `json:"url" validate:"required,url"`
+	EventTypes []EventType `json:"eventTypes" validate:"required,enum"`
+}
+
+type CreateWebhookParams struct {
+	BaseWebhookParams
+	HotelID primitive.ObjectID `json:"hotelID" validate:"required,exists=Hotel"`
+}
+
+type UpdateWebhookParams struct {
+	BaseWebhookParams
+	IsActive bool `json:"isActive"`
+}
+
+func NewWebhookFromCreateParams(params CreateWebhookParams) (*Webhook, error) {
+	return &Webhook{
+		HotelID:    params.HotelID,
+		URL:        params.URL,
+		EventTypes: params.EventTypes,
+		IsActive:   true,
+	}, nil
+}
+
+func NewWebhookFromUpdateParams(params UpdateWebhookParams) (*Webhook, error) {
+	return &Webhook{
+		URL:        params.URL,
+		EventTypes: params.EventTypes,
+		IsActive:   params.IsActive,
+	}, nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	PendingWebhookDeliveryStatus   WebhookDeliveryStatus = "pending"
+	SucceededWebhookDeliveryStatus WebhookDeliveryStatus = "succeeded"
+	// Failed deliveries are retried, until they become dead
+	FailedWebhookDeliveryStatus WebhookDeliveryStatus = "failed"
+	DeadWebhookDeliveryStatus   WebhookDeliveryStatus = "dead"
+)
+
+func (self WebhookDeliveryStatus) IsValid() bool {
+	switch self {
+	case
+		PendingWebhookDeliveryStatus, SucceededWebhookDeliveryStatus,
+		FailedWebhookDeliveryStatus, DeadWebhookDeliveryStatus:
+		return true
+	}
+	return false
+}
+
+type WebhookAttempt struct {
+	At time.Time `bson:"at" json:"at"`
+	// Zero if request failed before response, e.g. on timeout
+	StatusCode int    `bson:"statusCode" json:"statusCode"`
+	Error      string `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Single event sent to single webhook, along with log of attempts
+type WebhookDelivery struct {
+	ID        primitive.ObjectID    `bson:"_id,omitempty" json:"id,omitempty"`
+	WebhookID primitive.ObjectID    `bson:"webhookID" json:"webhookID"`
+	HotelID   primitive.ObjectID    `bson:"hotelID" json:"hotelID"`
+	EventID   primitive.ObjectID    `bson:"eventID" json:"eventID"`
+	EventType EventType             `bson:"eventType" json:"eventType"`
+	Status    WebhookDeliveryStatus `bson:"status" json:"status"`
+	// Exactly what is sent, so redelivery doesn't depend on outbox
+	Body     string           `bson:"body" json:"body"`
+	Attempts []WebhookAttempt `bson:"attempts" json:"attempts"`
+	// Failures since creation or last manual redelivery
+	Failures      int        `bson:"failures" json:"failures"`
+	NextAttemptAt time.Time  `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	CreatedAt     time.Time  `bson:"createdAt" json:"createdAt"`
+	DeliveredAt   *time.Time `bson:"deliveredAt" json:"deliveredAt"`
+}
@@ -0,0 +1,396 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"cloud.google.com/go/civil"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Rules are declared in `validate` struct tag and checked in order,
+// only the first failed rule of a field is reported:
+//   - required: value is not zero
+//   - min=N, max=N: length of string or slice
+//   - email: string is valid email
+//   - url: string is absolute http or https URL
+//   - enum: value implements Enum and is valid
+//   - gtfield=name, gtefield=name: value is greater (or equal) than
+//     other field of the same struct, referenced by its JSON name
+//   - exists=Entity: ObjectID points to existing Entity
+//   - dive: every element of slice of structs is validated by its own
+//     tags, null elements are rejected
+//
+// email, url, enum and exists are applied to every element of a slice.
+// Errors are keyed by JSON field names, those of elements like "lines[0].kind"
+const tagName = "validate"
+
+type Enum interface {
+	IsValid() bool
+}
+
+// Reports whether document of entity (e.g. "Hotel") with given id exists
+type ExistsFunc func(ctx context.Context, entity string, id primitive.ObjectID) (bool, error)
+
+type Validator struct {
+	Exists ExistsFunc
+}
+
+// Sourced from https://stackoverflow.com/a/67686133
+var emailRegex = regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
+
+func IsEmailValid(email string) bool {
+	return emailRegex.MatchString(email)
+}
+
+type field struct {
+	name  string
+	rules string
+	value reflect.Value
+}
+
+// Collects fields the same way encoding/json does: embedded structs
+// without json tag are flattened
+func collectFields(value reflect.Value, fields []*field) []*field {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		tag := structField.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if structField.Anonymous && len(name) == 0 &&
+			structField.Type.Kind() == reflect.Struct {
+			fields = collectFields(value.Field(i), fields)
+			continue
+		}
+		if !structField.IsExported() {
+			continue
+		}
+		if len(name) == 0 {
+			name = structField.Name
+		}
+		fields = append(fields, &field{
+			name:  name,
+			rules: structField.Tag.Get(tagName),
+			value: value.Field(i),
+		})
+	}
+	return fields
+}
+
+// Validates struct (or pointer to struct) and returns errors by field
+func (self *Validator) Struct(ctx context.Context, s interface{}) (map[string]string, error) {
+	value := reflect.Indirect(reflect.ValueOf(s))
+	if value.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Can't validate %s, struct expected", value.Kind())
+	}
+	fields := collectFields(value, nil)
+	byName := map[string]*field{}
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+
+	errors := map[string]string{}
+	for _, f := range fields {
+		if len(f.rules) == 0 {
+			continue
+		}
+		msg, err := self.field(ctx, f, byName)
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) != 0 {
+			errors[f.name] = msg
+			continue
+		}
+		if hasRule(f.rules, "dive") {
+			err = self.dive(ctx, f, errors)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return errors, nil
+}
+
+func hasRule(rules string, name string) bool {
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validates elements of slice, adding their errors under element's name
+func (self *Validator) dive(ctx context.Context, f *field, errors map[string]string) error {
+	if f.value.Kind() != reflect.Slice {
+		return fmt.Errorf("Rule dive isn't applicable to %s", f.name)
+	}
+	for i := 0; i < f.value.Len(); i++ {
+		name := fmt.Sprintf("%s[%d]", f.name, i)
+		elem := f.value.Index(i)
+		if elem.Kind() == reflect.Pointer && elem.IsNil() {
+			errors[name] = "Item can't be null"
+			continue
+		}
+		elemErrors, err := self.Struct(ctx, elem.Interface())
+		if err != nil {
+			return err
+		}
+		for elemName, msg := range elemErrors {
+			errors[name+"."+elemName] = msg
+		}
+	}
+	return nil
+}
+
+func (self *Validator) field(
+	ctx context.Context, f *field, byName map[string]*field,
+) (string, error) {
+	for _, rule := range strings.Split(f.rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name != "required" && f.value.IsZero() {
+			// Optional fields are checked only when provided
+			continue
+		}
+		var msg string
+		var err error
+		switch name {
+		case "required":
+			msg = required(f)
+		case "min", "max":
+			msg, err = length(f, name, arg)
+		case "email":
+			msg = eachElem(f, email)
+		case "url":
+			msg = eachElem(f, absoluteURL)
+		case "enum":
+			msg = eachElem(f, enum)
+		case "gtfield", "gtefield":
+			msg, err = compareFields(f, name, byName[arg])
+		case "exists":
+			msg, err = self.exists(ctx, f, arg)
+		case "dive":
+			// Elements are validated once the slice itself is valid
+		default:
+			err = fmt.Errorf("Unknown validation rule %s", name)
+		}
+		if err != nil || len(msg) != 0 {
+			return msg, err
+		}
+	}
+	return "", nil
+}
+
+var acronyms = map[string]bool{"id": true, "ip": true, "url": true}
+
+// "hotelID" becomes "Hotel ID", "dateFrom" becomes "Date from"
+func label(name string) string {
+	words := []string{}
+	runes := []rune(name)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsUpper(runes[i]) && !unicode.IsUpper(runes[i-1]) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	for i, word := range words {
+		// Acronyms like "ID" or "IDs" are kept as is
+		if acronyms[strings.ToLower(word)] {
+			words[i] = strings.ToUpper(word)
+		} else if len(word) < 2 || !unicode.IsUpper(rune(word[1])) {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+	return strings.Join(words, " ")
+}
+
+func lowerLabel(name string) string {
+	l := label(name)
+	if len(l) > 1 && unicode.IsUpper(rune(l[1])) {
+		return l
+	}
+	return strings.ToLower(l[:1]) + l[1:]
+}
+
+func required(f *field) string {
+	empty := f.value.IsZero()
+	if f.value.Kind() == reflect.Slice || f.value.Kind() == reflect.Map {
+		empty = f.value.Len() == 0
+	}
+	if empty {
+		return fmt.Sprintf("%s is required", label(f.name))
+	}
+	return ""
+}
+
+func length(f *field, rule string, arg string) (string, error) {
+	limit, err := strconv.Atoi(arg)
+	if err != nil {
+		return "", fmt.Errorf("Invalid %s argument for %s: %s", rule, f.name, arg)
+	}
+
+	var actual int
+	var unit string
+	switch f.value.Kind() {
+	case reflect.String:
+		actual = len([]rune(f.value.String()))
+		unit = "characters"
+	case reflect.Slice, reflect.Map:
+		actual = f.value.Len()
+		unit = "items"
+	default:
+		return "", fmt.Errorf("Rule %s isn't applicable to %s", rule, f.name)
+	}
+
+	if rule == "min" && actual < limit {
+		return fmt.Sprintf(
+			"%s length should be at least %d %s", label(f.name), limit, unit,
+		), nil
+	}
+	if rule == "max" && actual > limit {
+		return fmt.Sprintf(
+			"%s length should be at most %d %s", label(f.name), limit, unit,
+		), nil
+	}
+	return "", nil
+}
+
+func eachElem(f *field, check func(name string, value reflect.Value) string) string {
+	if f.value.Kind() != reflect.Slice {
+		return check(f.name, f.value)
+	}
+	for i := 0; i < f.value.Len(); i++ {
+		if msg := check(f.name, f.value.Index(i)); len(msg) != 0 {
+			return msg
+		}
+	}
+	return ""
+}
+
+func email(name string, value reflect.Value) string {
+	if !IsEmailValid(value.String()) {
+		return fmt.Sprintf("Email \"%s\" is invalid", value.String())
+	}
+	return ""
+}
+
+func absoluteURL(name string, value reflect.Value) string {
+	parsed, err := url.Parse(value.String())
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") ||
+		len(parsed.Host) == 0 {
+		return fmt.Sprintf("%s should be absolute http or https URL", label(name))
+	}
+	return ""
+}
+
+func enum(name string, value reflect.Value) string {
+	e, ok := value.Interface().(Enum)
+	if !ok || !e.IsValid() {
+		return fmt.Sprintf("Invalid %s \"%v\"", lowerLabel(name), value.Interface())
+	}
+	return ""
+}
+
+// Returns -1, 0 or 1 like strings.Compare
+func compare(a reflect.Value, b reflect.Value) (int, error) {
+	switch x := a.Interface().(type) {
+	case civil.Date:
+		y := b.Interface().(civil.Date)
+		return compareTime(x.In(time.UTC), y.In(time.UTC)), nil
+	case time.Time:
+		return compareTime(x, b.Interface().(time.Time)), nil
+	}
+	switch {
+	case a.CanInt():
+		return compareOrdered(a.Int(), b.Int()), nil
+	case a.CanUint():
+		return compareOrdered(a.Uint(), b.Uint()), nil
+	case a.CanFloat():
+		return compareOrdered(a.Float(), b.Float()), nil
+	}
+	return 0, fmt.Errorf("Can't compare values of type %s", a.Type())
+}
+
+func compareTime(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareOrdered[T int64 | uint64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFields(f *field, rule string, other *field) (string, error) {
+	if other == nil || other.value.Type() != f.value.Type() {
+		return "", fmt.Errorf("Invalid %s reference for %s", rule, f.name)
+	}
+	if other.value.IsZero() {
+		return "", nil
+	}
+	cmp, err := compare(f.value, other.value)
+	if err != nil {
+		return "", err
+	}
+	if rule == "gtefield" && cmp < 0 {
+		return fmt.Sprintf(
+			"%s can't be less than %s", label(f.name), lowerLabel(other.name),
+		), nil
+	}
+	if rule == "gtfield" && cmp <= 0 {
+		return fmt.Sprintf(
+			"%s should be greater than %s", label(f.name), lowerLabel(other.name),
+		), nil
+	}
+	return "", nil
+}
+
+func (self *Validator) exists(ctx context.Context, f *field, entity string) (string, error) {
+	ids := []primitive.ObjectID{}
+	switch value := f.value.Interface().(type) {
+	case primitive.ObjectID:
+		ids = append(ids, value)
+	case []primitive.ObjectID:
+		ids = value
+	default:
+		return "", fmt.Errorf("Rule exists isn't applicable to %s", f.name)
+	}
+
+	for _, id := range ids {
+		ok, err := self.Exists(ctx, entity, id)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			if f.value.Kind() == reflect.Slice {
+				return fmt.Sprintf("%s %s not found", entity, id.Hex()), nil
+			}
+			return fmt.Sprintf("%s not found", entity), nil
+		}
+	}
+	return "", nil
+}
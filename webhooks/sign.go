@@ -0,0 +1,61 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader  = "X-Webhook-Signature"
+	DeliveryIDHeader = "X-Webhook-Delivery"
+	EventIDHeader    = "X-Event-ID"
+	EventTypeHeader  = "X-Event-Type"
+)
+
+func signature(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Returns value of SignatureHeader in form "t=<unix time>,v1=<hex HMAC>".
+// HMAC-SHA256 covers timestamp and body, so delivery can't be replayed
+// later with a fresh timestamp
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := at.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, signature(secret, timestamp, body))
+}
+
+// Checks signature produced by Sign. Deliveries signed more than
+// tolerance ago are rejected. Meant for receivers, e.g. partners' code
+func Verify(
+	secret string, header string, body []byte, now time.Time, tolerance time.Duration,
+) bool {
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp, _ = strconv.ParseInt(value, 10, 64)
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || now.Sub(time.Unix(timestamp, 0)).Abs() > tolerance {
+		return false
+	}
+	expected := signature(secret, timestamp, body)
+	for _, actual := range signatures {
+		if hmac.Equal([]byte(expected), []byte(actual)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"hotel/db"
+	"hotel/types"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Events sink, which schedules delivery of event to every active
+// webhook of its hotel subscribed to its type. Delivery itself is
+// done by Worker, so slow partners don't hold up other sinks
+type Sink struct {
+	Webhooks   *db.MongoStore
+	Deliveries *db.MongoStore
+}
+
+func NewSink(dbStore *db.DB) *Sink {
+	return &Sink{
+		Webhooks:   dbStore.Webhooks,
+		Deliveries: dbStore.WebhookDeliveries,
+	}
+}
+
+func (self *Sink) Name() string {
+	return "webhooks"
+}
+
+func (self *Sink) Deliver(ctx context.Context, event *types.Event) error {
+	result, err := self.Webhooks.Get(ctx, bson.M{
+		"hotelID":    event.HotelID,
+		"eventTypes": event.Type,
+		"isActive":   true,
+	}, []*types.Webhook{})
+	if err != nil {
+		return err
+	}
+	webhooks, _ := result.([]*types.Webhook)
+	if len(webhooks) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, webhook := range webhooks {
+		// Event may be delivered to sink again, but webhook gets it once
+		err = self.Deliveries.Upsert(
+			ctx,
+			bson.M{"webhookID": webhook.ID, "eventID": event.ID},
+			bson.M{"$setOnInsert": &types.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				HotelID:       event.HotelID,
+				EventID:       event.ID,
+				EventType:     event.Type,
+				Status:        types.PendingWebhookDeliveryStatus,
+				Body:          string(body),
+				Attempts:      []types.WebhookAttempt{},
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
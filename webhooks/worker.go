@@ -0,0 +1,173 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hotel/db"
+	"hotel/types"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Sends scheduled deliveries to webhooks. Failed ones are retried with
+// exponential backoff, after MaxFailures they're dead until redelivered
+// manually. Several workers may run at once, each delivery is claimed by one
+type Worker struct {
+	Webhooks   *db.MongoStore
+	Deliveries *db.MongoStore
+	Client     *http.Client
+	// How often deliveries are polled once there are no due ones
+	PollInterval time.Duration
+	// Claimed delivery is skipped by others for this long
+	ClaimTimeout time.Duration
+	// Delay before the first retry, doubles with each next one
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	MaxFailures int
+}
+
+func NewWorker(dbStore *db.DB) *Worker {
+	return &Worker{
+		Webhooks:     dbStore.Webhooks,
+		Deliveries:   dbStore.WebhookDeliveries,
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		PollInterval: time.Second,
+		ClaimTimeout: time.Minute,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+		MaxFailures:  10,
+	}
+}
+
+func (self *Worker) Run(ctx context.Context) {
+	for {
+		_, err := self.DeliverPending(ctx)
+		if err != nil {
+			log.Printf("Failed to deliver webhooks: %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(self.PollInterval):
+		}
+	}
+}
+
+// Sends due deliveries until there are none left. Returns number of attempts
+func (self *Worker) DeliverPending(ctx context.Context) (int, error) {
+	attempted := 0
+	for ctx.Err() == nil {
+		delivery, err := self.claim(ctx, time.Now())
+		if err != nil {
+			return attempted, err
+		}
+		if delivery == nil {
+			break
+		}
+		err = self.deliver(ctx, delivery)
+		if err != nil {
+			return attempted, err
+		}
+		attempted++
+	}
+	return attempted, nil
+}
+
+func (self *Worker) claim(
+	ctx context.Context, now time.Time,
+) (*types.WebhookDelivery, error) {
+	result, err := self.Deliveries.GetOneAndUpdate(
+		ctx,
+		bson.M{
+			"status": bson.M{"$in": []types.WebhookDeliveryStatus{
+				types.PendingWebhookDeliveryStatus, types.FailedWebhookDeliveryStatus,
+			}},
+			"nextAttemptAt": bson.M{"$lte": now},
+		},
+		bson.M{"$set": bson.M{"nextAttemptAt": now.Add(self.ClaimTimeout)}},
+		&types.WebhookDelivery{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery, _ := result.(*types.WebhookDelivery)
+	return delivery, nil
+}
+
+func (self *Worker) backoff(failures int) time.Duration {
+	delay := self.BaseBackoff
+	for i := 1; i < failures && delay < self.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > self.MaxBackoff {
+		delay = self.MaxBackoff
+	}
+	return delay
+}
+
+// Posts body to webhook. Returns status code, if response was received
+func (self *Worker) send(
+	ctx context.Context, webhook *types.Webhook, delivery *types.WebhookDelivery,
+) (int, error) {
+	body := []byte(delivery.Body)
+	req, err := http.NewRequestWithContext(ctx, "POST", webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now(), body))
+	req.Header.Set(DeliveryIDHeader, delivery.ID.Hex())
+	req.Header.Set(EventIDHeader, delivery.EventID.Hex())
+	req.Header.Set(EventTypeHeader, string(delivery.EventType))
+	resp, err := self.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("Webhook responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (self *Worker) deliver(ctx context.Context, delivery *types.WebhookDelivery) error {
+	result, err := self.Webhooks.GetOneByID(ctx, delivery.WebhookID, &types.Webhook{})
+	if err != nil {
+		return err
+	}
+	webhook, _ := result.(*types.Webhook)
+
+	now := time.Now()
+	attempt := types.WebhookAttempt{At: now}
+	if webhook == nil || !webhook.IsActive {
+		// Nothing to retry, but delivery can be redelivered once webhook is back
+		err = fmt.Errorf("Webhook is deleted or inactive")
+	} else {
+		attempt.StatusCode, err = self.send(ctx, webhook, delivery)
+	}
+
+	set := bson.M{}
+	update := bson.M{"$set": set, "$push": bson.M{"attempts": &attempt}}
+	if err == nil {
+		set["status"] = types.SucceededWebhookDeliveryStatus
+		set["deliveredAt"] = now
+	} else {
+		attempt.Error = err.Error()
+		failures := delivery.Failures + 1
+		update["$inc"] = bson.M{"failures": 1}
+		if webhook == nil || !webhook.IsActive || failures >= self.MaxFailures {
+			set["status"] = types.DeadWebhookDeliveryStatus
+		} else {
+			set["status"] = types.FailedWebhookDeliveryStatus
+			set["nextAttemptAt"] = now.Add(self.backoff(failures))
+		}
+	}
+	_, err = self.Deliveries.Update(ctx, bson.M{"_id": delivery.ID}, update)
+	return err
+}